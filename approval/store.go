@@ -0,0 +1,231 @@
+// Package approval persists pending two-person approval requests.
+package approval
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	approvalStoreEnvVar = "PREY_APPROVAL_STORE"
+	approvalTTLEnvVar   = "PREY_APPROVAL_TTL"
+
+	defaultTTL = time.Hour
+)
+
+// Request states.
+const (
+	StatusPending  = "pending"
+	StatusApproved = "approved"
+)
+
+var (
+	ErrNotFound     = errors.New("approval request not found")
+	ErrExpired      = errors.New("approval request has expired")
+	ErrNotPending   = errors.New("approval request is no longer pending")
+	ErrSelfApproval = errors.New("approval requests must be approved by a different caller")
+)
+
+// Request is a tool call waiting for a second caller's approval.
+type Request struct {
+	ID        string         `json:"id"`
+	Tool      string         `json:"tool"`
+	Arguments map[string]any `json:"arguments"`
+	Requester string         `json:"requester"`
+	Summary   string         `json:"summary,omitempty"`
+	// Profile and Upstream are the requester's Prey profile and base URL, which the
+	// approved call runs against.
+	Profile   string    `json:"profile,omitempty"`
+	Upstream  string    `json:"upstream,omitempty"`
+	Status    string    `json:"status"`
+	Approver  string    `json:"approver,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// Store keeps approval requests in a JSON file so they survive restarts.
+type Store struct {
+	mu       sync.Mutex
+	path     string
+	ttl      time.Duration
+	now      func() time.Time
+	requests map[string]Request
+}
+
+// Open loads the store at path, creating it on first write. Expired requests are dropped.
+func Open(path string, ttl time.Duration) (*Store, error) {
+	if ttl <= 0 {
+		ttl = defaultTTL
+	}
+	s := &Store{path: path, ttl: ttl, now: time.Now, requests: map[string]Request{}}
+	b, err := os.ReadFile(path)
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+	case err != nil:
+		return nil, fmt.Errorf("read approval store: %w", err)
+	case len(b) > 0:
+		var reqs []Request
+		if err := json.Unmarshal(b, &reqs); err != nil {
+			return nil, fmt.Errorf("parse approval store: %w", err)
+		}
+		for _, r := range reqs {
+			s.requests[r.ID] = r
+		}
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.pruneLocked()
+	return s, nil
+}
+
+// FromEnv opens the store named by PREY_APPROVAL_STORE, with requests expiring after
+// PREY_APPROVAL_TTL (a Go duration, default 1h). It returns nil when approvals are off.
+func FromEnv() (*Store, error) {
+	path := strings.TrimSpace(os.Getenv(approvalStoreEnvVar))
+	if path == "" {
+		return nil, nil
+	}
+	ttl := defaultTTL
+	if val := strings.TrimSpace(os.Getenv(approvalTTLEnvVar)); val != "" {
+		d, err := time.ParseDuration(val)
+		if err != nil || d <= 0 {
+			return nil, fmt.Errorf("invalid %s: %q", approvalTTLEnvVar, val)
+		}
+		ttl = d
+	}
+	return Open(path, ttl)
+}
+
+// Create records r as a pending request. The caller sets the call and requester
+// fields; the store sets the ID, status and times.
+func (s *Store) Create(r Request) (Request, error) {
+	id, err := newID()
+	if err != nil {
+		return Request{}, err
+	}
+	now := s.now().UTC()
+	r.ID = id
+	r.Status = StatusPending
+	r.Approver = ""
+	r.CreatedAt = now
+	r.ExpiresAt = now.Add(s.ttl)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.pruneLocked()
+	s.requests[id] = r
+	return r, s.saveLocked()
+}
+
+// Approve marks a pending request as approved by approver. The caller runs the
+// approved call and calls Release if it failed; a request can be approved only once
+// until it is released.
+func (s *Store) Approve(id, approver string) (Request, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	r, ok := s.requests[id]
+	if !ok {
+		return Request{}, ErrNotFound
+	}
+	if !s.now().Before(r.ExpiresAt) {
+		return r, ErrExpired
+	}
+	if r.Status != StatusPending {
+		return r, ErrNotPending
+	}
+	if approver == "" || approver == r.Requester {
+		return r, ErrSelfApproval
+	}
+	r.Status = StatusApproved
+	r.Approver = approver
+	s.requests[id] = r
+	return r, s.saveLocked()
+}
+
+// Release returns an approved request whose call failed to pending, so it can be
+// approved again.
+func (s *Store) Release(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	r, ok := s.requests[id]
+	if !ok {
+		return ErrNotFound
+	}
+	if r.Status != StatusApproved {
+		return ErrNotPending
+	}
+	r.Status = StatusPending
+	r.Approver = ""
+	s.requests[id] = r
+	return s.saveLocked()
+}
+
+// Pending lists unexpired pending requests, oldest first.
+func (s *Store) Pending() []Request {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.now()
+	var out []Request
+	for _, r := range s.requests {
+		if r.Status == StatusPending && now.Before(r.ExpiresAt) {
+			out = append(out, r)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].CreatedAt.Before(out[j].CreatedAt) })
+	return out
+}
+
+// pruneLocked drops requests that expired or were approved more than a TTL ago.
+func (s *Store) pruneLocked() {
+	cutoff := s.now().Add(-s.ttl)
+	for id, r := range s.requests {
+		if r.ExpiresAt.Before(cutoff) || (r.Status != StatusPending && r.ExpiresAt.Before(s.now())) {
+			delete(s.requests, id)
+		}
+	}
+}
+
+// saveLocked writes the store atomically through a temporary file.
+func (s *Store) saveLocked() error {
+	reqs := make([]Request, 0, len(s.requests))
+	for _, r := range s.requests {
+		reqs = append(reqs, r)
+	}
+	sort.Slice(reqs, func(i, j int) bool { return reqs[i].CreatedAt.Before(reqs[j].CreatedAt) })
+	b, err := json.MarshalIndent(reqs, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), ".approvals-*")
+	if err != nil {
+		return fmt.Errorf("write approval store: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(b); err != nil {
+		_ = tmp.Close()
+		return fmt.Errorf("write approval store: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("write approval store: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), s.path); err != nil {
+		return fmt.Errorf("write approval store: %w", err)
+	}
+	return nil
+}
+
+func newID() (string, error) {
+	b := make([]byte, 12)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,77 @@
+package approval
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestStoreApprove(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "approvals.json")
+	s, err := Open(path, time.Hour)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	req, err := s.Create(Request{Tool: "prey.devices.delete", Arguments: map[string]any{"deviceId": "42"}, Requester: "alice", Summary: "Delete device 42", Profile: "acme", Upstream: "https://api.preyproject.com/v1"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Pending requests survive a restart.
+	s, err = Open(path, time.Hour)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if pending := s.Pending(); len(pending) != 1 || pending[0].ID != req.ID || pending[0].Profile != "acme" || pending[0].Upstream == "" {
+		t.Fatalf("expected persisted pending request, got %+v", pending)
+	}
+
+	if _, err := s.Approve(req.ID, "alice"); !errors.Is(err, ErrSelfApproval) {
+		t.Fatalf("expected self-approval to be refused, got %v", err)
+	}
+	approved, err := s.Approve(req.ID, "bob")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if approved.Status != StatusApproved || approved.Approver != "bob" {
+		t.Fatalf("unexpected approved request: %+v", approved)
+	}
+	if _, err := s.Approve(req.ID, "carol"); !errors.Is(err, ErrNotPending) {
+		t.Fatalf("expected second approval to be refused, got %v", err)
+	}
+
+	// A released request can be approved again.
+	if err := s.Release(req.ID); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if pending := s.Pending(); len(pending) != 1 || pending[0].Approver != "" {
+		t.Fatalf("expected released request to be pending, got %+v", pending)
+	}
+	if _, err := s.Approve(req.ID, "carol"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := s.Approve("missing", "bob"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestStoreExpiry(t *testing.T) {
+	s, err := Open(filepath.Join(t.TempDir(), "approvals.json"), time.Minute)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	now := time.Now()
+	s.now = func() time.Time { return now }
+	req, err := s.Create(Request{Tool: "prey.devices.delete", Requester: "alice"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	s.now = func() time.Time { return now.Add(2 * time.Minute) }
+	if _, err := s.Approve(req.ID, "bob"); !errors.Is(err, ErrExpired) {
+		t.Fatalf("expected ErrExpired, got %v", err)
+	}
+	if len(s.Pending()) != 0 {
+		t.Fatalf("expected expired request not to be pending")
+	}
+}
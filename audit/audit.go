@@ -0,0 +1,191 @@
+// Package audit writes a tamper-evident log of write operations.
+//
+// Each entry is a JSON line carrying the hash of the previous entry and its own
+// hash, so editing, removing or reordering lines breaks the chain. With a key, the
+// hashes are HMACs, so the chain cannot be rewritten without the key either.
+package audit
+
+import (
+	"bufio"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const (
+	auditLogEnvVar        = "PREY_AUDIT_LOG"
+	auditFailClosedEnvVar = "PREY_AUDIT_FAIL_CLOSED"
+)
+
+// Entry is one audited tool invocation.
+type Entry struct {
+	Seq            int64           `json:"seq"`
+	Time           string          `json:"time"`
+	Tool           string          `json:"tool"`
+	Subject        string          `json:"subject,omitempty"`
+	AuthMethod     string          `json:"auth_method,omitempty"`
+	Session        string          `json:"session,omitempty"`
+	Arguments      json.RawMessage `json:"arguments,omitempty"`
+	Targets        []string        `json:"targets,omitempty"`
+	UpstreamStatus int             `json:"upstream_status,omitempty"`
+	DurationMs     int64           `json:"duration_ms"`
+	// DryRun marks calls that were validated but not sent to Prey.
+	DryRun bool `json:"dry_run,omitempty"`
+	// ApprovalID names the approval request the call was queued as or, once approved,
+	// executed for.
+	ApprovalID string `json:"approval_id,omitempty"`
+	Result     string `json:"result"`
+	Error      string `json:"error,omitempty"`
+	PrevHash   string `json:"prev_hash"`
+	Hash       string `json:"hash"`
+}
+
+// Results recorded in Entry.Result.
+const (
+	ResultOK    = "ok"
+	ResultError = "error"
+	// ResultStarted marks a call about to run, recorded first in fail-closed mode.
+	ResultStarted = "started"
+	// ResultPending marks a call queued for approval and not run yet.
+	ResultPending = "pending"
+)
+
+// hash returns the chain hash of e, computed over its JSON without the hash field:
+// an HMAC-SHA256 with key, or a plain SHA-256 without one.
+func (e Entry) hash(key []byte) (string, error) {
+	e.Hash = ""
+	b, err := json.Marshal(e)
+	if err != nil {
+		return "", err
+	}
+	if len(key) == 0 {
+		sum := sha256.Sum256(b)
+		return hex.EncodeToString(sum[:]), nil
+	}
+	mac := hmac.New(sha256.New, key)
+	mac.Write(b)
+	return hex.EncodeToString(mac.Sum(nil)), nil
+}
+
+// Logger appends entries to an audit file.
+type Logger struct {
+	mu         sync.Mutex
+	f          *os.File
+	key        []byte
+	failClosed bool
+	seq        int64
+	prev       string
+}
+
+// Open opens or creates the audit file at path and continues its chain, keyed with
+// key when it is not empty. It refuses to append to a file whose chain does not
+// verify, including one written with another key.
+func Open(path string, key []byte) (*Logger, error) {
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("open audit log: %w", err)
+	}
+	last, err := Verify(f, key)
+	if err != nil {
+		_ = f.Close()
+		return nil, fmt.Errorf("audit log %s: %w", path, err)
+	}
+	return &Logger{f: f, key: key, seq: last.Seq, prev: last.Hash}, nil
+}
+
+// FromEnv opens the audit file named by PREY_AUDIT_LOG with key. It returns nil when
+// unset. PREY_AUDIT_FAIL_CLOSED=true makes the logger fail closed.
+func FromEnv(key []byte) (*Logger, error) {
+	path := strings.TrimSpace(os.Getenv(auditLogEnvVar))
+	if path == "" {
+		return nil, nil
+	}
+	var failClosed bool
+	if val := strings.TrimSpace(os.Getenv(auditFailClosedEnvVar)); val != "" {
+		v, err := strconv.ParseBool(val)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s: %q", auditFailClosedEnvVar, val)
+		}
+		failClosed = v
+	}
+	l, err := Open(path, key)
+	if err != nil {
+		return nil, err
+	}
+	l.failClosed = failClosed
+	return l, nil
+}
+
+// FailClosed reports whether calls must not run unless their start was recorded.
+func (l *Logger) FailClosed() bool {
+	return l.failClosed
+}
+
+// Write chains e to the previous entry and appends it.
+func (l *Logger) Write(e Entry) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	e.Seq = l.seq + 1
+	e.PrevHash = l.prev
+	h, err := e.hash(l.key)
+	if err != nil {
+		return err
+	}
+	e.Hash = h
+	b, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	if _, err := l.f.Write(append(b, '\n')); err != nil {
+		return fmt.Errorf("write audit log: %w", err)
+	}
+	if err := l.f.Sync(); err != nil {
+		return fmt.Errorf("sync audit log: %w", err)
+	}
+	l.seq, l.prev = e.Seq, e.Hash
+	return nil
+}
+
+func (l *Logger) Close() error {
+	return l.f.Close()
+}
+
+// ErrChainBroken reports an entry whose hash or link does not match.
+var ErrChainBroken = errors.New("audit chain broken")
+
+// Verify reads an audit log and checks every entry's hash, keyed with key as the log
+// was written, and link to its predecessor. It returns the last entry, which is zero
+// for an empty log.
+func Verify(r io.Reader, key []byte) (Entry, error) {
+	var last Entry
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	line := 0
+	for scanner.Scan() {
+		line++
+		var e Entry
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			return last, fmt.Errorf("line %d: %w: %v", line, ErrChainBroken, err)
+		}
+		if e.Seq != last.Seq+1 || e.PrevHash != last.Hash {
+			return last, fmt.Errorf("line %d: %w: entry does not follow seq %d", line, ErrChainBroken, last.Seq)
+		}
+		h, err := e.hash(key)
+		if err != nil {
+			return last, err
+		}
+		if !hmac.Equal([]byte(h), []byte(e.Hash)) {
+			return last, fmt.Errorf("line %d: %w: hash mismatch", line, ErrChainBroken)
+		}
+		last = e
+	}
+	return last, scanner.Err()
+}
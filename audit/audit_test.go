@@ -0,0 +1,115 @@
+package audit
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoggerChain(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	l, err := Open(path, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := l.Write(Entry{Tool: "prey.devices.delete", Subject: "alice", Result: ResultOK}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	_ = l.Close()
+
+	// Reopening continues the chain.
+	l, err = Open(path, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := l.Write(Entry{Tool: "prey.labels.create", Arguments: []byte(`{"name":"<EMEA>"}`), Result: ResultOK}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	_ = l.Close()
+
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	last, err := Verify(bytes.NewReader(b), nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if last.Seq != 2 {
+		t.Fatalf("expected 2 entries, got %d", last.Seq)
+	}
+
+	tampered := strings.Replace(string(b), "alice", "bob", 1)
+	if _, err := Verify(strings.NewReader(tampered), nil); !errors.Is(err, ErrChainBroken) {
+		t.Fatalf("expected edited entry to break the chain, got %v", err)
+	}
+	lines := strings.SplitAfter(string(b), "\n")
+	if _, err := Verify(strings.NewReader(lines[1]), nil); !errors.Is(err, ErrChainBroken) {
+		t.Fatalf("expected removed entry to break the chain, got %v", err)
+	}
+	if err := os.WriteFile(path, []byte(tampered), 0o600); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := Open(path, nil); err == nil {
+		t.Fatalf("expected Open to refuse a broken chain")
+	}
+}
+
+func TestLoggerKeyedChain(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	key := []byte("audit-key")
+	l, err := Open(path, key)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, subject := range []string{"alice", "bob"} {
+		if err := l.Write(Entry{Tool: "prey.devices.delete", Subject: subject, Result: ResultOK}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	_ = l.Close()
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if last, err := Verify(bytes.NewReader(b), key); err != nil || last.Seq != 2 {
+		t.Fatalf("expected the keyed chain to verify, got %+v %v", last, err)
+	}
+	if _, err := Verify(bytes.NewReader(b), []byte("other-key")); !errors.Is(err, ErrChainBroken) {
+		t.Fatalf("expected another key to fail, got %v", err)
+	}
+
+	// A log rewritten and re-hashed without the key does not verify.
+	forged := filepath.Join(t.TempDir(), "forged.jsonl")
+	fl, err := Open(forged, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := fl.Write(Entry{Tool: "prey.devices.delete", Subject: "mallory", Result: ResultOK}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	_ = fl.Close()
+	if _, err := Open(forged, key); err == nil {
+		t.Fatalf("expected a log hashed without the key to be refused")
+	}
+}
+
+func TestFromEnvFailClosed(t *testing.T) {
+	t.Setenv(auditLogEnvVar, filepath.Join(t.TempDir(), "audit.jsonl"))
+	t.Setenv(auditFailClosedEnvVar, "true")
+	l, err := FromEnv(nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer l.Close()
+	if !l.FailClosed() {
+		t.Fatalf("expected the logger to fail closed")
+	}
+	t.Setenv(auditFailClosedEnvVar, "maybe")
+	if _, err := FromEnv(nil); err == nil {
+		t.Fatalf("expected error for an invalid %s", auditFailClosedEnvVar)
+	}
+}
@@ -0,0 +1,146 @@
+package auth
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+	"sync"
+
+	"mcp-prey/internal"
+)
+
+const (
+	authTokensEnvVar     = "PREY_AUTH_TOKENS"
+	authTokensFileEnvVar = "PREY_AUTH_TOKENS_FILE"
+)
+
+type tokenEntry struct {
+	subject string
+	hash    [sha256.Size]byte
+}
+
+// StaticTokens authenticates callers against a fixed set of bearer tokens.
+// Only SHA-256 hashes of the tokens are stored, never the tokens themselves.
+type StaticTokens struct {
+	entries []tokenEntry
+	// file holds the entries read from PREY_AUTH_TOKENS_FILE, if set.
+	file *tokensFile
+}
+
+// tokensFile follows a token file, so tokens can be rotated without a restart.
+type tokensFile struct {
+	secret *internal.Secret
+
+	mu       sync.Mutex
+	contents string
+	entries  []tokenEntry
+}
+
+// current returns the file's entries, parsing it again when it changed. Invalid
+// contents are logged and the previous entries kept.
+func (f *tokensFile) current() []tokenEntry {
+	val := f.secret.Value()
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if val != f.contents {
+		f.contents = val
+		t, err := ParseStaticTokens(val)
+		switch {
+		case err != nil:
+			slog.Warn("Keeping previous auth tokens: invalid token file", "file", f.secret.Path(), "error", err)
+		case len(t.entries) == 0:
+			slog.Warn("Keeping previous auth tokens: token file contains no tokens", "file", f.secret.Path())
+		default:
+			f.entries = t.entries
+		}
+	}
+	return f.entries
+}
+
+// HashToken returns the hex-encoded SHA-256 hash of a token, as stored in token lists.
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// ParseStaticTokens parses "subject:sha256hex" entries separated by commas or newlines.
+// Blank lines and lines starting with # are ignored.
+func ParseStaticTokens(val string) (*StaticTokens, error) {
+	t := &StaticTokens{}
+	scanner := bufio.NewScanner(strings.NewReader(strings.ReplaceAll(val, ",", "\n")))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		subject, hash, ok := strings.Cut(line, ":")
+		subject = strings.TrimSpace(subject)
+		hash = strings.TrimPrefix(strings.TrimSpace(hash), "sha256:")
+		if !ok || subject == "" {
+			return nil, fmt.Errorf("invalid token entry %q: expected subject:sha256hex", line)
+		}
+		b, err := hex.DecodeString(hash)
+		if err != nil || len(b) != sha256.Size {
+			return nil, fmt.Errorf("invalid token hash for %s: expected 64 hex characters", subject)
+		}
+		entry := tokenEntry{subject: subject}
+		copy(entry.hash[:], b)
+		t.entries = append(t.entries, entry)
+	}
+	return t, scanner.Err()
+}
+
+// StaticTokensFromEnv loads tokens from PREY_AUTH_TOKENS and PREY_AUTH_TOKENS_FILE.
+// It returns nil when neither is set. The file is re-read when it changes.
+func StaticTokensFromEnv() (*StaticTokens, error) {
+	t, err := ParseStaticTokens(os.Getenv(authTokensEnvVar))
+	if err != nil {
+		return nil, err
+	}
+	if path := strings.TrimSpace(os.Getenv(authTokensFileEnvVar)); path != "" {
+		secret, err := internal.OpenSecret(path)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", authTokensFileEnvVar, err)
+		}
+		parsed, err := ParseStaticTokens(secret.Value())
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", authTokensFileEnvVar, err)
+		}
+		t.file = &tokensFile{secret: secret, contents: secret.Value(), entries: parsed.entries}
+	} else if strings.TrimSpace(os.Getenv(authTokensEnvVar)) == "" {
+		return nil, nil
+	}
+	if len(t.current()) == 0 {
+		return nil, fmt.Errorf("%s and %s contain no tokens", authTokensEnvVar, authTokensFileEnvVar)
+	}
+	return t, nil
+}
+
+// current returns the configured entries, including the token file's current ones.
+func (t *StaticTokens) current() []tokenEntry {
+	if t.file == nil {
+		return t.entries
+	}
+	return append(append([]tokenEntry(nil), t.entries...), t.file.current()...)
+}
+
+// Authenticate returns the identity bound to token.
+func (t *StaticTokens) Authenticate(_ context.Context, token string) (Identity, error) {
+	sum := sha256.Sum256([]byte(token))
+	var subject string
+	for _, e := range t.current() {
+		if subtle.ConstantTimeCompare(sum[:], e.hash[:]) == 1 {
+			subject = e.subject
+		}
+	}
+	if subject == "" {
+		return Identity{}, ErrUnauthenticated
+	}
+	return Identity{Subject: subject, Method: MethodBearer}, nil
+}
@@ -0,0 +1,93 @@
+package auth
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestParseStaticTokens(t *testing.T) {
+	tokens, err := ParseStaticTokens("# comment\nalice:" + HashToken("secret-a") + ",bob:sha256:" + HashToken("secret-b"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	id, err := tokens.Authenticate(context.Background(), "secret-b")
+	if err != nil || id.Subject != "bob" {
+		t.Fatalf("expected bob, got %+v err=%v", id, err)
+	}
+	if _, err := tokens.Authenticate(context.Background(), "nope"); err == nil {
+		t.Fatalf("expected error for unknown token")
+	}
+	if _, err := ParseStaticTokens("alice:not-a-hash"); err == nil {
+		t.Fatalf("expected error for invalid hash")
+	}
+}
+
+func TestStaticTokensMiddleware(t *testing.T) {
+	tokens, err := ParseStaticTokens("alice:" + HashToken("secret"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var subject string
+	h := Middleware("", tokens)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id, _ := IdentityFromContext(r.Context())
+		subject = id.Subject
+	}))
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/mcp", nil))
+	if rec.Code != http.StatusUnauthorized || rec.Header().Get("WWW-Authenticate") == "" {
+		t.Fatalf("expected 401 with challenge, got %d", rec.Code)
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/mcp", nil)
+	req.Header.Set("Authorization", "Bearer secret")
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK || subject != "alice" {
+		t.Fatalf("expected authenticated request, got %d subject=%q", rec.Code, subject)
+	}
+}
+
+func TestStaticTokensFileFollowsChanges(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tokens")
+	if err := os.WriteFile(path, []byte("alice:"+HashToken("old")+"\n"), 0o600); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	t.Setenv(authTokensEnvVar, "")
+	t.Setenv(authTokensFileEnvVar, path)
+	tokens, err := StaticTokensFromEnv()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := tokens.Authenticate(context.Background(), "old"); err != nil {
+		t.Fatalf("expected old token to authenticate: %v", err)
+	}
+
+	if err := os.WriteFile(path, []byte("alice:"+HashToken("new")+"\n"), 0o600); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := tokens.file.secret.Reload(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := tokens.Authenticate(context.Background(), "old"); err == nil {
+		t.Fatalf("expected rotated-out token to be rejected")
+	}
+	if _, err := tokens.Authenticate(context.Background(), "new"); err != nil {
+		t.Fatalf("expected new token to authenticate: %v", err)
+	}
+
+	// Invalid contents keep the previous tokens.
+	if err := os.WriteFile(path, []byte("alice:not-a-hash\n"), 0o600); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := tokens.file.secret.Reload(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := tokens.Authenticate(context.Background(), "new"); err != nil {
+		t.Fatalf("expected previous tokens to be kept: %v", err)
+	}
+}
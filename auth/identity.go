@@ -0,0 +1,73 @@
+package auth
+
+import "context"
+
+// Authentication methods recorded in Identity.Method.
+const (
+	MethodBearer = "bearer"
+	MethodOAuth  = "oauth"
+	MethodStdio  = "stdio"
+	MethodHeader = "header"
+)
+
+// Identity is the authenticated caller of a request.
+type Identity struct {
+	// Subject identifies the caller, e.g. the name bound to a static token.
+	Subject string
+	// Method is how the caller was authenticated, one of the Method* constants.
+	Method string
+	// Scopes granted to the caller, if the authentication method carries any.
+	Scopes []string
+}
+
+// PermissionLocationPrecise lets a caller see exact device coordinates. It is granted
+// as an OAuth scope or through an RBAC role.
+const PermissionLocationPrecise = "location:precise"
+
+type identityKey struct{}
+
+type permissionsKey struct{}
+
+func WithIdentity(ctx context.Context, id Identity) context.Context {
+	return context.WithValue(ctx, identityKey{}, id)
+}
+
+// IdentityFromContext returns the caller identity and whether one was set.
+func IdentityFromContext(ctx context.Context) (Identity, bool) {
+	id, ok := ctx.Value(identityKey{}).(Identity)
+	return id, ok
+}
+
+// HasScope reports whether the identity was granted scope.
+func (i Identity) HasScope(scope string) bool {
+	for _, s := range i.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// ExtractStdioIdentity marks stdio sessions so policies can map them to their stdio identity.
+func ExtractStdioIdentity(ctx context.Context) context.Context {
+	return WithIdentity(ctx, Identity{Method: MethodStdio})
+}
+
+// WithPermissions records permissions granted to the caller by its roles.
+func WithPermissions(ctx context.Context, perms []string) context.Context {
+	return context.WithValue(ctx, permissionsKey{}, perms)
+}
+
+// HasPermission reports whether the caller holds perm, as a scope or a role permission.
+func HasPermission(ctx context.Context, perm string) bool {
+	if id, ok := IdentityFromContext(ctx); ok && id.HasScope(perm) {
+		return true
+	}
+	perms, _ := ctx.Value(permissionsKey{}).([]string)
+	for _, p := range perms {
+		if p == perm {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,64 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+)
+
+const defaultChallenge = `Bearer realm="mcp-prey"`
+
+var ErrUnauthenticated = errors.New("missing or invalid bearer token")
+
+// Authenticator resolves a bearer token to a caller identity.
+type Authenticator interface {
+	Authenticate(ctx context.Context, token string) (Identity, error)
+}
+
+// Middleware rejects requests whose bearer token none of the authenticators accept and
+// stores the caller identity in the request context for the MCP context funcs.
+// challenge is the WWW-Authenticate value sent with 401 responses; empty uses a plain Bearer realm.
+func Middleware(challenge string, authenticators ...Authenticator) func(http.Handler) http.Handler {
+	if challenge == "" {
+		challenge = defaultChallenge
+	}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			token, ok := BearerToken(r)
+			if !ok {
+				unauthorized(w, r, challenge, "")
+				return
+			}
+			for _, a := range authenticators {
+				id, err := a.Authenticate(r.Context(), token)
+				if err == nil {
+					next.ServeHTTP(w, r.WithContext(WithIdentity(r.Context(), id)))
+					return
+				}
+			}
+			unauthorized(w, r, challenge, "invalid_token")
+		})
+	}
+}
+
+// BearerToken extracts the token from an "Authorization: Bearer" header.
+func BearerToken(r *http.Request) (string, bool) {
+	scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return "", false
+	}
+	token = strings.TrimSpace(token)
+	return token, token != ""
+}
+
+func unauthorized(w http.ResponseWriter, r *http.Request, challenge, errCode string) {
+	slog.Warn("rejected unauthenticated request", "path", r.URL.Path, "remote", r.RemoteAddr, "error", errCode)
+	if errCode != "" {
+		challenge += fmt.Sprintf(`, error=%q`, errCode)
+	}
+	w.Header().Set("WWW-Authenticate", challenge)
+	http.Error(w, ErrUnauthenticated.Error(), http.StatusUnauthorized)
+}
@@ -0,0 +1,274 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-jose/go-jose/v4"
+	"github.com/go-jose/go-jose/v4/jwt"
+)
+
+const (
+	oauthResourceEnvVar             = "PREY_OAUTH_RESOURCE"
+	oauthIssuerEnvVar               = "PREY_OAUTH_ISSUER"
+	oauthAudienceEnvVar             = "PREY_OAUTH_AUDIENCE"
+	oauthJWKSEnvVar                 = "PREY_OAUTH_JWKS"
+	oauthAuthorizationServersEnvVar = "PREY_OAUTH_AUTHORIZATION_SERVERS"
+
+	// ScopeRead grants read tools, ScopeWrite grants write tools.
+	ScopeRead  = "prey:read"
+	ScopeWrite = "prey:write"
+
+	protectedResourcePath = "/.well-known/oauth-protected-resource"
+	jwksRefreshInterval   = time.Minute
+	jwksCacheTTL          = 10 * time.Minute
+	clockLeeway           = 30 * time.Second
+)
+
+var supportedAlgorithms = []jose.SignatureAlgorithm{
+	jose.RS256, jose.RS384, jose.RS512,
+	jose.PS256, jose.PS384, jose.PS512,
+	jose.ES256, jose.ES384, jose.ES512,
+	jose.EdDSA,
+}
+
+// OAuthConfig describes the server as an OAuth 2.1 protected resource.
+type OAuthConfig struct {
+	// Resource is the canonical URL of the MCP endpoint, e.g. https://mcp.example.com/mcp.
+	Resource string
+	Issuer   string
+	// Audience expected in access tokens; defaults to Resource.
+	Audience string
+	// JWKS is a local file path or an http(s) URL.
+	JWKS                 string
+	AuthorizationServers []string
+}
+
+// OAuth validates JWT access tokens and serves protected resource metadata (RFC 9728).
+type OAuth struct {
+	cfg  OAuthConfig
+	jwks *jwksSource
+}
+
+// OAuthConfigFromEnv reads the PREY_OAUTH_* variables. It returns nil when OAuth is not configured.
+func OAuthConfigFromEnv() *OAuthConfig {
+	resource := strings.TrimSpace(os.Getenv(oauthResourceEnvVar))
+	issuer := strings.TrimSpace(os.Getenv(oauthIssuerEnvVar))
+	if resource == "" && issuer == "" {
+		return nil
+	}
+	cfg := &OAuthConfig{
+		Resource: resource,
+		Issuer:   issuer,
+		Audience: strings.TrimSpace(os.Getenv(oauthAudienceEnvVar)),
+		JWKS:     strings.TrimSpace(os.Getenv(oauthJWKSEnvVar)),
+	}
+	for _, s := range strings.Split(os.Getenv(oauthAuthorizationServersEnvVar), ",") {
+		if s = strings.TrimSpace(s); s != "" {
+			cfg.AuthorizationServers = append(cfg.AuthorizationServers, s)
+		}
+	}
+	return cfg
+}
+
+// NewOAuth validates the configuration and loads the JWKS once so misconfiguration fails at startup.
+func NewOAuth(ctx context.Context, cfg OAuthConfig) (*OAuth, error) {
+	if cfg.Resource == "" || cfg.Issuer == "" || cfg.JWKS == "" {
+		return nil, fmt.Errorf("%s, %s and %s are required for OAuth", oauthResourceEnvVar, oauthIssuerEnvVar, oauthJWKSEnvVar)
+	}
+	if u, err := url.Parse(cfg.Resource); err != nil || u.Scheme == "" || u.Host == "" {
+		return nil, fmt.Errorf("%s must be an absolute URL", oauthResourceEnvVar)
+	}
+	if cfg.Audience == "" {
+		cfg.Audience = cfg.Resource
+	}
+	if len(cfg.AuthorizationServers) == 0 {
+		cfg.AuthorizationServers = []string{cfg.Issuer}
+	}
+	o := &OAuth{cfg: cfg, jwks: &jwksSource{location: cfg.JWKS, client: &http.Client{Timeout: 10 * time.Second}}}
+	if _, err := o.jwks.keySet(ctx, true); err != nil {
+		return nil, err
+	}
+	return o, nil
+}
+
+type scopeClaims struct {
+	Scope string   `json:"scope"`
+	Scp   []string `json:"scp"`
+}
+
+func (c scopeClaims) scopes() []string {
+	scopes := strings.Fields(c.Scope)
+	return append(scopes, c.Scp...)
+}
+
+// Authenticate validates a JWT access token: signature, issuer, audience and expiry.
+func (o *OAuth) Authenticate(ctx context.Context, raw string) (Identity, error) {
+	tok, err := jwt.ParseSigned(raw, supportedAlgorithms)
+	if err != nil {
+		return Identity{}, ErrUnauthenticated
+	}
+	kid := ""
+	if len(tok.Headers) > 0 {
+		kid = tok.Headers[0].KeyID
+	}
+	key, err := o.jwks.key(ctx, kid)
+	if err != nil {
+		return Identity{}, err
+	}
+	var claims jwt.Claims
+	var extra scopeClaims
+	if err := tok.Claims(key, &claims, &extra); err != nil {
+		return Identity{}, ErrUnauthenticated
+	}
+	if claims.Expiry == nil {
+		return Identity{}, fmt.Errorf("%w: token has no expiry", ErrUnauthenticated)
+	}
+	expected := jwt.Expected{
+		Issuer:      o.cfg.Issuer,
+		AnyAudience: jwt.Audience{o.cfg.Audience},
+		Time:        time.Now(),
+	}
+	if err := claims.ValidateWithLeeway(expected, clockLeeway); err != nil {
+		return Identity{}, fmt.Errorf("%w: %v", ErrUnauthenticated, err)
+	}
+	return Identity{Subject: claims.Subject, Method: MethodOAuth, Scopes: extra.scopes()}, nil
+}
+
+// MetadataURL is the protected resource metadata document advertised in challenges.
+func (o *OAuth) MetadataURL() string {
+	u, _ := url.Parse(o.cfg.Resource)
+	return u.Scheme + "://" + u.Host + protectedResourcePath
+}
+
+// Challenge is the WWW-Authenticate value pointing clients to the metadata document.
+func (o *OAuth) Challenge() string {
+	return fmt.Sprintf(`Bearer resource_metadata=%q, scope="%s %s"`, o.MetadataURL(), ScopeRead, ScopeWrite)
+}
+
+// MetadataPaths returns the paths the metadata document is served on: the root
+// well-known path and the path-suffixed variant for resources with a path.
+func (o *OAuth) MetadataPaths() []string {
+	paths := []string{protectedResourcePath}
+	if u, _ := url.Parse(o.cfg.Resource); strings.Trim(u.Path, "/") != "" {
+		paths = append(paths, protectedResourcePath+"/"+strings.Trim(u.Path, "/"))
+	}
+	return paths
+}
+
+// MetadataHandler serves the protected resource metadata document.
+func (o *OAuth) MetadataHandler(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]any{
+		"resource":                 o.cfg.Resource,
+		"authorization_servers":    o.cfg.AuthorizationServers,
+		"scopes_supported":         []string{ScopeRead, ScopeWrite},
+		"bearer_methods_supported": []string{"header"},
+	})
+}
+
+// jwksSource loads a JSON Web Key Set from a file or URL. Remote sets are cached
+// and refetched when a token references an unknown key ID.
+type jwksSource struct {
+	location string
+	client   *http.Client
+
+	mu      sync.Mutex
+	keys    *jose.JSONWebKeySet
+	fetched time.Time
+}
+
+func (s *jwksSource) remote() bool {
+	return strings.HasPrefix(s.location, "https://") || strings.HasPrefix(s.location, "http://")
+}
+
+func (s *jwksSource) keySet(ctx context.Context, refresh bool) (*jose.JSONWebKeySet, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.keys != nil {
+		age := time.Since(s.fetched)
+		stale := s.remote() && (age > jwksCacheTTL || (refresh && age > jwksRefreshInterval))
+		if !stale {
+			return s.keys, nil
+		}
+	}
+	b, err := s.load(ctx)
+	if err != nil {
+		if s.keys != nil {
+			return s.keys, nil
+		}
+		return nil, err
+	}
+	var set jose.JSONWebKeySet
+	if err := json.Unmarshal(b, &set); err != nil {
+		return nil, fmt.Errorf("parse JWKS: %w", err)
+	}
+	if len(set.Keys) == 0 {
+		return nil, errors.New("JWKS contains no keys")
+	}
+	s.keys, s.fetched = &set, time.Now()
+	return s.keys, nil
+}
+
+func (s *jwksSource) load(ctx context.Context) ([]byte, error) {
+	if !s.remote() {
+		b, err := os.ReadFile(s.location)
+		if err != nil {
+			return nil, fmt.Errorf("read JWKS: %w", err)
+		}
+		return b, nil
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.location, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("fetch JWKS: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("fetch JWKS: %s", resp.Status)
+	}
+	return io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+}
+
+func (s *jwksSource) key(ctx context.Context, kid string) (jose.JSONWebKey, error) {
+	set, err := s.keySet(ctx, false)
+	if err != nil {
+		return jose.JSONWebKey{}, err
+	}
+	if k, ok := lookupKey(set, kid); ok {
+		return k, nil
+	}
+	// The issuer may have rotated keys since the last fetch.
+	if set, err = s.keySet(ctx, true); err == nil {
+		if k, ok := lookupKey(set, kid); ok {
+			return k, nil
+		}
+	}
+	return jose.JSONWebKey{}, fmt.Errorf("%w: unknown signing key %q", ErrUnauthenticated, kid)
+}
+
+func lookupKey(set *jose.JSONWebKeySet, kid string) (jose.JSONWebKey, bool) {
+	if kid == "" {
+		if len(set.Keys) == 1 {
+			return set.Keys[0], true
+		}
+		return jose.JSONWebKey{}, false
+	}
+	keys := set.Key(kid)
+	if len(keys) == 0 {
+		return jose.JSONWebKey{}, false
+	}
+	return keys[0], true
+}
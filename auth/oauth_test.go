@@ -0,0 +1,111 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-jose/go-jose/v4"
+	"github.com/go-jose/go-jose/v4/jwt"
+)
+
+func newTestOAuth(t *testing.T) (*OAuth, func(jwt.Claims, string) string) {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	set := jose.JSONWebKeySet{Keys: []jose.JSONWebKey{{Key: &key.PublicKey, KeyID: "k1", Algorithm: string(jose.RS256), Use: "sig"}}}
+	b, err := json.Marshal(set)
+	if err != nil {
+		t.Fatalf("marshal jwks: %v", err)
+	}
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	if err := os.WriteFile(path, b, 0o600); err != nil {
+		t.Fatalf("write jwks: %v", err)
+	}
+	o, err := NewOAuth(context.Background(), OAuthConfig{
+		Resource: "https://mcp.example.com/mcp",
+		Issuer:   "https://issuer.example.com",
+		JWKS:     path,
+	})
+	if err != nil {
+		t.Fatalf("new oauth: %v", err)
+	}
+	signer, err := jose.NewSigner(jose.SigningKey{Algorithm: jose.RS256, Key: key}, (&jose.SignerOptions{}).WithHeader("kid", "k1"))
+	if err != nil {
+		t.Fatalf("new signer: %v", err)
+	}
+	sign := func(claims jwt.Claims, scope string) string {
+		tok, err := jwt.Signed(signer).Claims(claims).Claims(map[string]any{"scope": scope}).Serialize()
+		if err != nil {
+			t.Fatalf("sign: %v", err)
+		}
+		return tok
+	}
+	return o, sign
+}
+
+func TestOAuthAuthenticate(t *testing.T) {
+	o, sign := newTestOAuth(t)
+	valid := jwt.Claims{
+		Subject:  "alice",
+		Issuer:   "https://issuer.example.com",
+		Audience: jwt.Audience{"https://mcp.example.com/mcp"},
+		Expiry:   jwt.NewNumericDate(time.Now().Add(time.Hour)),
+	}
+	id, err := o.Authenticate(context.Background(), sign(valid, "prey:read prey:write"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if id.Subject != "alice" || id.Method != MethodOAuth || !id.HasScope(ScopeWrite) {
+		t.Fatalf("unexpected identity: %+v", id)
+	}
+
+	wrongAudience := valid
+	wrongAudience.Audience = jwt.Audience{"https://other.example.com"}
+	if _, err := o.Authenticate(context.Background(), sign(wrongAudience, "prey:read")); err == nil {
+		t.Fatalf("expected error for wrong audience")
+	}
+	expired := valid
+	expired.Expiry = jwt.NewNumericDate(time.Now().Add(-time.Hour))
+	if _, err := o.Authenticate(context.Background(), sign(expired, "prey:read")); err == nil {
+		t.Fatalf("expected error for expired token")
+	}
+	noExpiry := valid
+	noExpiry.Expiry = nil
+	if _, err := o.Authenticate(context.Background(), sign(noExpiry, "prey:read")); err == nil {
+		t.Fatalf("expected error for token without expiry")
+	}
+}
+
+func TestOAuthMetadataAndChallenge(t *testing.T) {
+	o, _ := newTestOAuth(t)
+	if got := o.MetadataPaths(); len(got) != 2 || got[1] != "/.well-known/oauth-protected-resource/mcp" {
+		t.Fatalf("unexpected metadata paths: %v", got)
+	}
+	rec := httptest.NewRecorder()
+	o.MetadataHandler(rec, httptest.NewRequest(http.MethodGet, "/.well-known/oauth-protected-resource", nil))
+	var doc map[string]any
+	if err := json.Unmarshal(rec.Body.Bytes(), &doc); err != nil {
+		t.Fatalf("decode metadata: %v", err)
+	}
+	if doc["resource"] != "https://mcp.example.com/mcp" {
+		t.Fatalf("unexpected metadata: %v", doc)
+	}
+
+	h := Middleware(o.Challenge(), o)(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/mcp", nil))
+	if rec.Code != http.StatusUnauthorized || !strings.Contains(rec.Header().Get("WWW-Authenticate"), "resource_metadata=") {
+		t.Fatalf("expected challenge with resource metadata, got %d %q", rec.Code, rec.Header().Get("WWW-Authenticate"))
+	}
+}
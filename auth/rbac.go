@@ -0,0 +1,179 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"path"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	rbacPolicyFileEnvVar = "PREY_RBAC_POLICY_FILE"
+
+	defaultStdioIdentity = "stdio"
+)
+
+// Role grants access to a set of tools, optionally limited to devices or labels.
+type Role struct {
+	// Tools are tool names or glob patterns (prey.zones.*). Empty allows every tool.
+	Tools []string `yaml:"tools" json:"tools"`
+	// Write allows write tools.
+	Write bool `yaml:"write" json:"write"`
+	// Devices limits device-targeting calls to these device IDs.
+	Devices []string `yaml:"devices" json:"devices"`
+	// Labels limits device-targeting calls to devices carrying one of these labels (name or ID).
+	Labels []string `yaml:"labels" json:"labels"`
+	// Permissions are extra grants such as location:precise.
+	Permissions []string `yaml:"permissions" json:"permissions"`
+}
+
+// Policy maps caller identities to roles.
+type Policy struct {
+	// IdentityHeader is a trusted header (set by a proxy) naming the caller of HTTP
+	// requests that were not authenticated with a token.
+	IdentityHeader string `yaml:"identity_header" json:"identity_header"`
+	// StdioIdentity is the identity used for stdio sessions.
+	StdioIdentity string `yaml:"stdio_identity" json:"stdio_identity"`
+	// DefaultRoles apply to identities without an explicit entry. Empty denies them.
+	DefaultRoles []string            `yaml:"default_roles" json:"default_roles"`
+	Roles        map[string]Role     `yaml:"roles" json:"roles"`
+	Identities   map[string][]string `yaml:"identities" json:"identities"`
+}
+
+// ParsePolicy parses a YAML (or JSON) RBAC policy and checks role references.
+func ParsePolicy(b []byte) (*Policy, error) {
+	var p Policy
+	if err := yaml.Unmarshal(b, &p); err != nil {
+		return nil, fmt.Errorf("parse RBAC policy: %w", err)
+	}
+	if p.StdioIdentity == "" {
+		p.StdioIdentity = defaultStdioIdentity
+	}
+	check := func(owner string, roles []string) error {
+		for _, r := range roles {
+			if _, ok := p.Roles[r]; !ok {
+				return fmt.Errorf("RBAC policy: %s references unknown role %q", owner, r)
+			}
+		}
+		return nil
+	}
+	if err := check("default_roles", p.DefaultRoles); err != nil {
+		return nil, err
+	}
+	for id, roles := range p.Identities {
+		if err := check("identity "+id, roles); err != nil {
+			return nil, err
+		}
+	}
+	for name, role := range p.Roles {
+		for _, pattern := range role.Tools {
+			if _, err := path.Match(pattern, ""); err != nil {
+				return nil, fmt.Errorf("RBAC policy: role %s has invalid tool pattern %q", name, pattern)
+			}
+		}
+	}
+	return &p, nil
+}
+
+// PolicyFromEnv loads the policy file named by PREY_RBAC_POLICY_FILE. It returns nil when unset.
+func PolicyFromEnv() (*Policy, error) {
+	file := strings.TrimSpace(os.Getenv(rbacPolicyFileEnvVar))
+	if file == "" {
+		return nil, nil
+	}
+	b, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("read %s: %w", rbacPolicyFileEnvVar, err)
+	}
+	return ParsePolicy(b)
+}
+
+// ExtractHeaderIdentity records the caller named by the policy's identity header
+// for requests that were not authenticated with a token.
+func (p *Policy) ExtractHeaderIdentity(ctx context.Context, req *http.Request) context.Context {
+	if p.IdentityHeader == "" {
+		return ctx
+	}
+	if id, ok := IdentityFromContext(ctx); ok && id.Subject != "" {
+		return ctx
+	}
+	subject := strings.TrimSpace(req.Header.Get(p.IdentityHeader))
+	if subject == "" {
+		return ctx
+	}
+	return WithIdentity(ctx, Identity{Subject: subject, Method: MethodHeader})
+}
+
+// Subject resolves the caller identity: the token or header subject, or the stdio
+// identity for stdio sessions.
+func (p *Policy) Subject(ctx context.Context) string {
+	id, ok := IdentityFromContext(ctx)
+	if !ok {
+		return ""
+	}
+	if id.Subject != "" {
+		return id.Subject
+	}
+	if id.Method == MethodStdio {
+		return p.StdioIdentity
+	}
+	return ""
+}
+
+// RolesFor returns the roles granted to subject.
+func (p *Policy) RolesFor(subject string) []Role {
+	names, ok := p.Identities[subject]
+	if !ok || subject == "" {
+		names = p.DefaultRoles
+	}
+	roles := make([]Role, 0, len(names))
+	for _, n := range names {
+		roles = append(roles, p.Roles[n])
+	}
+	return roles
+}
+
+// AllowsTool reports whether the role may call toolName, ignoring device scopes.
+func (r Role) AllowsTool(toolName string, write bool) bool {
+	if write && !r.Write {
+		return false
+	}
+	if len(r.Tools) == 0 {
+		return true
+	}
+	for _, pattern := range r.Tools {
+		if ok, _ := path.Match(pattern, toolName); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// Scoped reports whether the role is limited to specific devices or labels.
+func (r Role) Scoped() bool {
+	return len(r.Devices) > 0 || len(r.Labels) > 0
+}
+
+// AllowsDevice reports whether the role may act on a device with the given ID and labels.
+func (r Role) AllowsDevice(deviceID string, labels []string) bool {
+	if !r.Scoped() {
+		return true
+	}
+	for _, d := range r.Devices {
+		if d == deviceID {
+			return true
+		}
+	}
+	for _, want := range r.Labels {
+		for _, have := range labels {
+			if strings.EqualFold(want, have) {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,92 @@
+package auth
+
+import (
+	"context"
+	"net/http"
+	"testing"
+)
+
+const testPolicy = `
+identity_header: X-Forwarded-User
+default_roles: [viewer]
+roles:
+  viewer:
+    tools: ["prey.devices.*", "prey.account.get"]
+  helpdesk:
+    write: true
+    labels: [EMEA]
+  admin:
+    write: true
+identities:
+  alice: [admin]
+  bob: [helpdesk]
+  stdio: [admin]
+`
+
+func TestParsePolicy(t *testing.T) {
+	p, err := ParsePolicy([]byte(testPolicy))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if p.StdioIdentity != defaultStdioIdentity {
+		t.Fatalf("expected default stdio identity, got %q", p.StdioIdentity)
+	}
+	if _, err := ParsePolicy([]byte("identities:\n  alice: [missing]\n")); err == nil {
+		t.Fatalf("expected error for unknown role")
+	}
+	if _, err := ParsePolicy([]byte("roles:\n  bad:\n    tools: [\"prey.[\"]\n")); err == nil {
+		t.Fatalf("expected error for invalid pattern")
+	}
+}
+
+func TestPolicySubject(t *testing.T) {
+	p, err := ParsePolicy([]byte(testPolicy))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	ctx := context.Background()
+	if got := p.Subject(ctx); got != "" {
+		t.Fatalf("expected no subject, got %q", got)
+	}
+	if got := p.Subject(ExtractStdioIdentity(ctx)); got != "stdio" {
+		t.Fatalf("expected stdio subject, got %q", got)
+	}
+
+	req, _ := http.NewRequest(http.MethodPost, "/mcp", nil)
+	req.Header.Set("X-Forwarded-User", "bob")
+	if got := p.Subject(p.ExtractHeaderIdentity(ctx, req)); got != "bob" {
+		t.Fatalf("expected header subject, got %q", got)
+	}
+	tokenCtx := WithIdentity(ctx, Identity{Subject: "alice", Method: MethodBearer})
+	if got := p.Subject(p.ExtractHeaderIdentity(tokenCtx, req)); got != "alice" {
+		t.Fatalf("expected token subject to win over header, got %q", got)
+	}
+}
+
+func TestRoleAllows(t *testing.T) {
+	p, err := ParsePolicy([]byte(testPolicy))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	viewer := p.RolesFor("unknown")
+	if len(viewer) != 1 || !viewer[0].AllowsTool("prey.devices.list", false) {
+		t.Fatalf("expected default viewer role to allow device reads")
+	}
+	if viewer[0].AllowsTool("prey.zones.list", false) {
+		t.Fatalf("expected viewer to be limited to its tool patterns")
+	}
+	if viewer[0].AllowsTool("prey.devices.delete", true) {
+		t.Fatalf("expected viewer to be denied write tools")
+	}
+
+	helpdesk := p.Roles["helpdesk"]
+	if !helpdesk.AllowsDevice("1", []string{"emea"}) {
+		t.Fatalf("expected label match to be case-insensitive")
+	}
+	if helpdesk.AllowsDevice("1", []string{"APAC"}) {
+		t.Fatalf("expected device outside label scope to be denied")
+	}
+	if !p.Roles["admin"].AllowsDevice("1", nil) {
+		t.Fatalf("expected unscoped role to allow any device")
+	}
+}
@@ -0,0 +1,114 @@
+package mcprey
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+const cancelledNotificationMethod = "notifications/cancelled"
+
+// CancellationTracker cancels in-flight tool calls when the client sends notifications/cancelled.
+//
+// Tool handlers never see the JSON-RPC request ID, so a before-call hook remembers it
+// keyed by the request's _meta pointer, which survives the copy into the handler.
+type CancellationTracker struct {
+	mu      sync.Mutex
+	ids     map[*mcp.Meta]any
+	cancels map[string]context.CancelFunc
+}
+
+func NewCancellationTracker() *CancellationTracker {
+	return &CancellationTracker{
+		ids:     make(map[*mcp.Meta]any),
+		cancels: make(map[string]context.CancelFunc),
+	}
+}
+
+// AddHooks registers the hooks needed to correlate tool calls with request IDs.
+func (t *CancellationTracker) AddHooks(hooks *server.Hooks) {
+	hooks.AddBeforeCallTool(func(_ context.Context, id any, request *mcp.CallToolRequest) {
+		if request.Params.Meta == nil {
+			request.Params.Meta = &mcp.Meta{}
+		}
+		t.mu.Lock()
+		t.ids[request.Params.Meta] = id
+		t.mu.Unlock()
+	})
+	hooks.AddOnError(func(_ context.Context, _ any, method mcp.MCPMethod, message any, _ error) {
+		request, ok := message.(*mcp.CallToolRequest)
+		if method != mcp.MethodToolsCall || !ok || request.Params.Meta == nil {
+			return
+		}
+		t.mu.Lock()
+		delete(t.ids, request.Params.Meta)
+		t.mu.Unlock()
+	})
+}
+
+// Middleware makes the tool handler context cancellable by a matching notifications/cancelled.
+func (t *CancellationTracker) Middleware(next server.ToolHandlerFunc) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		t.mu.Lock()
+		id, ok := t.ids[request.Params.Meta]
+		delete(t.ids, request.Params.Meta)
+		t.mu.Unlock()
+		if !ok {
+			return next(ctx, request)
+		}
+
+		ctx = context.WithValue(ctx, requestIDKey{}, id)
+		ctx, cancel := context.WithCancel(ctx)
+		key := cancellationKey(ctx, id)
+		t.mu.Lock()
+		t.cancels[key] = cancel
+		t.mu.Unlock()
+		defer func() {
+			t.mu.Lock()
+			delete(t.cancels, key)
+			t.mu.Unlock()
+			cancel()
+		}()
+		return next(ctx, request)
+	}
+}
+
+type requestIDKey struct{}
+
+// RequestIDFromContext returns the JSON-RPC ID of the tool call being handled. It is
+// set by CancellationTracker.Middleware.
+func RequestIDFromContext(ctx context.Context) (any, bool) {
+	id := ctx.Value(requestIDKey{})
+	return id, id != nil
+}
+
+// HandleCancelled is the notification handler for notifications/cancelled.
+func (t *CancellationTracker) HandleCancelled(ctx context.Context, notification mcp.JSONRPCNotification) {
+	id, ok := notification.Params.AdditionalFields["requestId"]
+	if !ok {
+		return
+	}
+	key := cancellationKey(ctx, id)
+	t.mu.Lock()
+	cancel, ok := t.cancels[key]
+	t.mu.Unlock()
+	if ok {
+		cancel()
+	}
+}
+
+// Register installs the notifications/cancelled handler on the server.
+func (t *CancellationTracker) Register(s *server.MCPServer) {
+	s.AddNotificationHandler(cancelledNotificationMethod, t.HandleCancelled)
+}
+
+func cancellationKey(ctx context.Context, id any) string {
+	sessionID := ""
+	if session := server.ClientSessionFromContext(ctx); session != nil {
+		sessionID = session.SessionID()
+	}
+	return fmt.Sprintf("%s/%v", sessionID, id)
+}
@@ -0,0 +1,88 @@
+package mcprey
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+type testSession struct {
+	id            string
+	notifications chan mcp.JSONRPCNotification
+}
+
+func newTestSession(id string) *testSession {
+	return &testSession{id: id, notifications: make(chan mcp.JSONRPCNotification, 10)}
+}
+
+func (s *testSession) SessionID() string                                   { return s.id }
+func (s *testSession) NotificationChannel() chan<- mcp.JSONRPCNotification { return s.notifications }
+func (s *testSession) Initialize()                                         {}
+func (s *testSession) Initialized() bool                                   { return true }
+
+// handle sends a JSON-RPC message to the server as the session.
+func handle(ctx context.Context, srv *server.MCPServer, msg map[string]any) mcp.JSONRPCMessage {
+	b, _ := json.Marshal(msg)
+	return srv.HandleMessage(ctx, b)
+}
+
+func TestCancellationThroughServer(t *testing.T) {
+	tracker := NewCancellationTracker()
+	hooks := &server.Hooks{}
+	tracker.AddHooks(hooks)
+	srv := server.NewMCPServer("test", "0.0.0", server.WithHooks(hooks), server.WithToolHandlerMiddleware(tracker.Middleware))
+	tracker.Register(srv)
+
+	started := make(chan struct{})
+	srv.AddTool(mcp.NewTool("test.block"), func(ctx context.Context, _ mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		if id, ok := RequestIDFromContext(ctx); !ok || id == nil {
+			return nil, errors.New("expected the request ID in the context")
+		}
+		close(started)
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(5 * time.Second):
+			return mcp.NewToolResultText("not cancelled"), nil
+		}
+	})
+
+	session := newTestSession("s1")
+	ctx := srv.WithContext(context.Background(), session)
+	done := make(chan mcp.JSONRPCMessage, 1)
+	go func() {
+		done <- handle(ctx, srv, map[string]any{
+			"jsonrpc": "2.0", "id": 7, "method": "tools/call",
+			"params": map[string]any{"name": "test.block"},
+		})
+	}()
+
+	select {
+	case <-started:
+	case <-time.After(2 * time.Second):
+		t.Fatalf("tool call did not start")
+	}
+	// A cancellation from another session must not cancel the call.
+	other := srv.WithContext(context.Background(), newTestSession("s2"))
+	handle(other, srv, map[string]any{"jsonrpc": "2.0", "method": "notifications/cancelled", "params": map[string]any{"requestId": 7}})
+	select {
+	case <-done:
+		t.Fatalf("call was cancelled by another session")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	handle(ctx, srv, map[string]any{"jsonrpc": "2.0", "method": "notifications/cancelled", "params": map[string]any{"requestId": 7}})
+	select {
+	case resp := <-done:
+		if _, ok := resp.(mcp.JSONRPCError); !ok {
+			t.Fatalf("expected the cancelled call to fail, got %#v", resp)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatalf("call was not cancelled")
+	}
+}
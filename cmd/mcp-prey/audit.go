@@ -0,0 +1,47 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"mcp-prey/audit"
+	"mcp-prey/prey"
+)
+
+// runAudit implements `mcp-prey audit verify [--file path]`. A keyed log is verified
+// with PREY_AUDIT_KEY or PREY_AUDIT_KEY_FILE.
+func runAudit(args []string, stdout, stderr io.Writer) int {
+	if len(args) == 0 || args[0] != "verify" {
+		fmt.Fprintln(stderr, "usage: mcp-prey audit verify [--file path]")
+		return 2
+	}
+	fs := flag.NewFlagSet("audit verify", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	file := fs.String("file", os.Getenv("PREY_AUDIT_LOG"), "Audit log to verify (default: PREY_AUDIT_LOG)")
+	if err := fs.Parse(args[1:]); err != nil {
+		return 2
+	}
+	if *file == "" {
+		fmt.Fprintln(stderr, "no audit log given; use --file or PREY_AUDIT_LOG")
+		return 2
+	}
+	f, err := os.Open(*file)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+	defer f.Close()
+	if err := prey.LoadSecretFiles(); err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+	last, err := audit.Verify(f, prey.AuditKeyFromEnv())
+	if err != nil {
+		fmt.Fprintf(stderr, "FAIL: %v (last valid seq %d)\n", err, last.Seq)
+		return 1
+	}
+	fmt.Fprintf(stdout, "OK: %d entries, last hash %s\n", last.Seq, last.Hash)
+	return 0
+}
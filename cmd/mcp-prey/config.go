@@ -0,0 +1,101 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+
+	"mcp-prey/auth"
+	"mcp-prey/prey"
+	"mcp-prey/tools"
+)
+
+const configUsage = "usage: mcp-prey config validate|print [--config path] [--profile name]"
+
+// runConfig implements `mcp-prey config validate` and `mcp-prey config print`, which
+// load the configuration the server would use from flags, environment and file.
+func runConfig(args []string, stdout, stderr io.Writer) int {
+	if len(args) == 0 || (args[0] != "validate" && args[0] != "print") {
+		fmt.Fprintln(stderr, configUsage)
+		return 2
+	}
+	fs := flag.NewFlagSet("config "+args[0], flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	configPath := fs.String("config", envOrDefault("PREY_CONFIG_FILE", ""), "YAML or TOML config file with Prey profiles")
+	profile := fs.String("profile", envOrDefault("PREY_PROFILE", ""), "Config file profile to use")
+	if err := fs.Parse(args[1:]); err != nil {
+		return 2
+	}
+	if err := prey.NewConfigManager(*configPath, *profile).Load(); err != nil {
+		fmt.Fprintf(stderr, "FAIL: %v\n", err)
+		return 1
+	}
+
+	if args[0] == "print" {
+		enc := json.NewEncoder(stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(prey.DescribeConfig()); err != nil {
+			fmt.Fprintln(stderr, err)
+			return 1
+		}
+		return 0
+	}
+
+	problems := flattenErrors(validateConfig())
+	if len(problems) > 0 {
+		for _, p := range problems {
+			fmt.Fprintf(stderr, "- %s\n", p)
+		}
+		fmt.Fprintf(stderr, "FAIL: %d problem(s)\n", len(problems))
+		return 1
+	}
+	active := prey.ActiveProfile()
+	if active == "" {
+		active = "environment"
+	}
+	fmt.Fprintf(stdout, "OK: configuration is valid (profile %s)\n", active)
+	return 0
+}
+
+// validateConfig runs the Prey settings checks and the parsers of the optional
+// components, without opening their stores.
+func validateConfig() error {
+	errs := []error{prey.ValidateSettings(tools.KnownTools())}
+	if _, err := prey.MaskPolicyFromEnv(); err != nil {
+		errs = append(errs, err)
+	}
+	if _, err := auth.PolicyFromEnv(); err != nil {
+		errs = append(errs, err)
+	}
+	if _, err := auth.StaticTokensFromEnv(); err != nil {
+		errs = append(errs, err)
+	}
+	if _, err := tools.NewIdempotencyFromEnv(); err != nil {
+		errs = append(errs, err)
+	}
+	if _, err := prey.ReadinessFromEnv(); err != nil {
+		errs = append(errs, err)
+	}
+	if _, err := tools.ParseToolsets(envOrDefault("PREY_TOOLSETS", "all")); err != nil {
+		errs = append(errs, fmt.Errorf("PREY_TOOLSETS: %w", err))
+	}
+	return errors.Join(errs...)
+}
+
+// flattenErrors lists the leaves of joined errors, one per line of output.
+func flattenErrors(err error) []string {
+	if err == nil {
+		return nil
+	}
+	if joined, ok := err.(interface{ Unwrap() []error }); ok {
+		var out []string
+		for _, e := range joined.Unwrap() {
+			out = append(out, flattenErrors(e)...)
+		}
+		return out
+	}
+	return strings.Split(err.Error(), "\n")
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"mcp-prey/prey"
+)
+
+// setConfigEnv clears the settings runConfig reads and sets env on top.
+func setConfigEnv(t *testing.T, env map[string]string) {
+	t.Helper()
+	for _, key := range []string{"PREY_CONFIG_FILE", "PREY_PROFILE", "PREY_API_KEY", "PREY_API_KEY_FILE", "PREY_API_BASE", "PREY_TIMEOUT_MS", "PREY_ALLOWED_TOOLS", "PREY_DENIED_TOOLS", "PREY_WRITE_TOOLS", "PREY_RATE_LIMIT_TIERS", "PREY_RATE_LIMIT_WRITE_RESERVE", "PREY_AUTH_TOKENS", "PREY_AUTH_TOKENS_FILE", "PREY_MASK_MODE", "PREY_MASK_SALT", "PREY_MASK_SALT_FILE", "PREY_TOOLSETS", "PREY_LOCATION_PRECISION", "PREY_CONFIRM_POLICY"} {
+		t.Setenv(key, "")
+	}
+	for key, val := range env {
+		t.Setenv(key, val)
+	}
+	t.Cleanup(func() { _ = prey.SetConfigFile(nil, "") })
+}
+
+func TestRunConfigExitCodes(t *testing.T) {
+	cases := []struct {
+		name string
+		args []string
+		env  map[string]string
+		want int
+	}{
+		{name: "no subcommand", want: 2},
+		{name: "unknown subcommand", args: []string{"check"}, want: 2},
+		{name: "unknown flag", args: []string{"validate", "--bogus"}, want: 2},
+		{name: "valid", args: []string{"validate"}, env: map[string]string{"PREY_API_KEY": "key"}, want: 0},
+		{name: "missing API key", args: []string{"validate"}, want: 1},
+		{name: "invalid timeout", args: []string{"validate"}, env: map[string]string{"PREY_API_KEY": "key", "PREY_TIMEOUT_MS": "soon"}, want: 1},
+		{name: "invalid location precision", args: []string{"validate"}, env: map[string]string{"PREY_API_KEY": "key", "PREY_LOCATION_PRECISION": "geohash:99"}, want: 1},
+		{name: "unknown confirmation policy", args: []string{"validate"}, env: map[string]string{"PREY_API_KEY": "key", "PREY_CONFIRM_POLICY": "prey.devices.delete=alwyas"}, want: 1},
+		{name: "missing config file", args: []string{"print", "--config", filepath.Join(t.TempDir(), "missing.yaml")}, want: 1},
+		{name: "print", args: []string{"print"}, env: map[string]string{"PREY_API_KEY": "key"}, want: 0},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			setConfigEnv(t, tc.env)
+			var stdout, stderr bytes.Buffer
+			if got := runConfig(tc.args, &stdout, &stderr); got != tc.want {
+				t.Fatalf("expected exit code %d, got %d (stdout %q, stderr %q)", tc.want, got, stdout.String(), stderr.String())
+			}
+		})
+	}
+}
+
+func TestRunConfigValidateOutput(t *testing.T) {
+	setConfigEnv(t, map[string]string{"PREY_API_KEY": "key", "PREY_TIMEOUT_MS": "soon", "PREY_TOOLSETS": "nope"})
+	var stdout, stderr bytes.Buffer
+	if got := runConfig([]string{"validate"}, &stdout, &stderr); got != 1 {
+		t.Fatalf("expected exit code 1, got %d", got)
+	}
+	out := stderr.String()
+	if !strings.Contains(out, "PREY_TIMEOUT_MS") || !strings.Contains(out, "PREY_TOOLSETS") || !strings.Contains(out, "FAIL: 2 problem(s)") {
+		t.Fatalf("expected both problems to be listed, got %q", out)
+	}
+	if stdout.Len() != 0 {
+		t.Fatalf("expected no output on stdout, got %q", stdout.String())
+	}
+}
+
+func TestRunConfigPrintRedactsSecrets(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "acme.key"), []byte("acme-file-secret\n"), 0o600); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	config := filepath.Join(dir, "config.yaml")
+	yaml := `
+default_profile: acme
+profiles:
+  acme:
+    base_url: https://api.preyproject.com/v1/
+    api_key_file: acme.key
+  globex:
+    base_url: https://globex.example.com/v1
+    api_key: globex-inline-secret
+`
+	if err := os.WriteFile(config, []byte(yaml), 0o600); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	setConfigEnv(t, nil)
+
+	var stdout, stderr bytes.Buffer
+	if got := runConfig([]string{"print", "--config", config}, &stdout, &stderr); got != 0 {
+		t.Fatalf("expected exit code 0, got %d (stderr %q)", got, stderr.String())
+	}
+	out := stdout.String()
+	for _, secret := range []string{"acme-file-secret", "globex-inline-secret"} {
+		if strings.Contains(out, secret) {
+			t.Fatalf("print output leaks %q: %s", secret, out)
+		}
+	}
+	if !strings.Contains(out, "***") || !strings.Contains(out, "globex") {
+		t.Fatalf("expected redacted keys for every profile, got %s", out)
+	}
+}
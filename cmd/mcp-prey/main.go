@@ -9,20 +9,84 @@ import (
 	"net/http"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
 	"time"
 
+	"github.com/mark3labs/mcp-go/mcp"
 	"github.com/mark3labs/mcp-go/server"
 
+	mcprey "mcp-prey"
+	"mcp-prey/approval"
+	"mcp-prey/audit"
+	"mcp-prey/auth"
+	"mcp-prey/internal"
+	"mcp-prey/metrics"
 	"mcp-prey/prey"
+	"mcp-prey/telemetry"
 	"mcp-prey/tools"
 )
 
+// approvalsPath is where the admin approval endpoints are served on HTTP transports.
+const approvalsPath = "/approvals/"
+
+// metricsPath is where Prometheus metrics are served.
+const metricsPath = "/metrics"
+
 func handleHealthz(w http.ResponseWriter, _ *http.Request) {
 	w.WriteHeader(http.StatusOK)
 	_, _ = w.Write([]byte("ok"))
 }
 
+// handleProbes registers the health and readiness probes, which orchestrators call
+// without credentials, and the metrics, which carry tool names and call volumes and so
+// require the same authentication as MCP requests.
+func handleProbes(mux *http.ServeMux, authenticate func(http.Handler) http.Handler, readiness http.Handler, m *metrics.Metrics) {
+	mux.HandleFunc("/healthz", handleHealthz)
+	mux.Handle("/readyz", readiness)
+	mux.Handle(metricsPath, authenticate(m.Handler()))
+}
+
+// setupHTTPAuth returns the middleware guarding the MCP endpoints and registers the
+// OAuth protected resource metadata on mux when OAuth is configured. Without static
+// tokens or OAuth, requests pass through unauthenticated, as before.
+func setupHTTPAuth(mux *http.ServeMux) (func(http.Handler) http.Handler, error) {
+	var authenticators []auth.Authenticator
+	challenge := ""
+	tokens, err := auth.StaticTokensFromEnv()
+	if err != nil {
+		return nil, err
+	}
+	if tokens != nil {
+		authenticators = append(authenticators, tokens)
+	}
+	if cfg := auth.OAuthConfigFromEnv(); cfg != nil {
+		oauth, err := auth.NewOAuth(context.Background(), *cfg)
+		if err != nil {
+			return nil, err
+		}
+		authenticators = append(authenticators, oauth)
+		challenge = oauth.Challenge()
+		for _, path := range oauth.MetadataPaths() {
+			mux.HandleFunc(path, oauth.MetadataHandler)
+		}
+	}
+	if len(authenticators) == 0 {
+		slog.Warn("HTTP transport is running without authentication; set PREY_AUTH_TOKENS or PREY_OAUTH_* to require it")
+		return func(h http.Handler) http.Handler { return h }, nil
+	}
+	return auth.Middleware(challenge, authenticators...), nil
+}
+
+// headerIdentity returns the context func recording the caller named by the RBAC
+// policy's identity header, or a no-op without a policy.
+func headerIdentity(policy *auth.Policy) func(context.Context, *http.Request) context.Context {
+	if policy == nil {
+		return func(ctx context.Context, _ *http.Request) context.Context { return ctx }
+	}
+	return policy.ExtractHeaderIdentity
+}
+
 type httpServer interface {
 	Start(addr string) error
 	Shutdown(ctx context.Context) error
@@ -59,10 +123,35 @@ func runHTTPServer(ctx context.Context, srv httpServer, addr, transportName stri
 	return nil
 }
 
-func newServer() *server.MCPServer {
-	s := server.NewMCPServer(
-		"mcp-prey",
-		"0.1.0",
+// serverDeps are the components newServer wires into the MCP server. toolsets and
+// idempotency are required; the others are enabled by their environment settings.
+// stdio resolves the configuration per call, as the stdio context is built once.
+type serverDeps struct {
+	stdio       bool
+	toolsets    *tools.ToolsetManager
+	metrics     *metrics.Metrics
+	policy      *auth.Policy
+	auditLog    *audit.Logger
+	approvals   *tools.Approvals
+	idempotency *tools.Idempotency
+}
+
+func newServer(deps serverDeps) *server.MCPServer {
+	toolsets := deps.toolsets
+	hooks := &server.Hooks{}
+	cancellations := mcprey.NewCancellationTracker()
+	cancellations.AddHooks(hooks)
+	visibility := tools.NewToolVisibility()
+	if deps.stdio {
+		visibility.SetContextFunc(prey.ExtractInfoFromEnv)
+	}
+	visibility.AddHooks(hooks)
+	toolsets.AddHooks(hooks)
+	if deps.metrics != nil {
+		deps.metrics.AddHooks(hooks)
+	}
+
+	opts := []server.ServerOption{
 		server.WithInstructions(`
 This server provides access to the Prey API.
 
@@ -74,15 +163,190 @@ Capabilities (v1):
 
 Note: Write tools are disabled unless PREY_ALLOW_WRITE=true.
 `),
+		server.WithElicitation(),
+		server.WithToolCapabilities(true),
+		server.WithToolFilter(visibility.Filter),
+		server.WithToolFilter(toolsets.Filter),
+		server.WithHooks(hooks),
+	}
+	if deps.stdio {
+		opts = append(opts, server.WithToolHandlerMiddleware(prey.EnvConfigMiddleware))
+	}
+	opts = append(opts,
+		server.WithToolHandlerMiddleware(cancellations.Middleware),
+		server.WithToolHandlerMiddleware(tools.LoggingMiddleware),
+	)
+	// Middleware runs in the order added: logs, metrics and audit see every attempt,
+	// dry runs are still checked against roles, and approvals only queue calls the
+	// requester is allowed to make.
+	if deps.metrics != nil {
+		opts = append(opts, server.WithToolHandlerMiddleware(deps.metrics.Middleware))
+	}
+	if deps.auditLog != nil {
+		opts = append(opts, server.WithToolHandlerMiddleware(tools.NewAudit(deps.auditLog).Middleware))
+	}
+	opts = append(opts, server.WithToolHandlerMiddleware(toolsets.Middleware))
+	if deps.policy != nil {
+		rbac := tools.NewRBAC(deps.policy)
+		opts = append(opts,
+			server.WithToolFilter(rbac.Filter),
+			server.WithToolHandlerMiddleware(rbac.Middleware),
+		)
+	}
+	opts = append(opts,
+		server.WithToolHandlerMiddleware(tools.DryRunMiddleware),
+		server.WithToolHandlerMiddleware(deps.idempotency.Middleware),
 	)
+	if deps.approvals != nil {
+		opts = append(opts, server.WithToolHandlerMiddleware(deps.approvals.Middleware))
+	}
 
-	tools.AddAllTools(s)
+	s := server.NewMCPServer("mcp-prey", "0.1.0", opts...)
+	cancellations.Register(s)
+	toolsets.Register(s)
+	if deps.approvals != nil {
+		deps.approvals.Register(s)
+	}
 	return s
 }
 
-func run(transport, addr, basePath, endpointPath string, logLevel slog.Level) error {
-	slog.SetDefault(slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: logLevel})))
-	s := newServer()
+// serveMetrics serves metrics on a separate listener until ctx is done.
+func serveMetrics(ctx context.Context, addr string, m *metrics.Metrics) {
+	mux := http.NewServeMux()
+	mux.Handle(metricsPath, m.Handler())
+	srv := &http.Server{Addr: addr, Handler: mux}
+	go func() {
+		<-ctx.Done()
+		_ = srv.Close()
+	}()
+	slog.Info("Serving metrics", "address", addr, "path", metricsPath)
+	if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		slog.Error("metrics server failed", "error", err)
+	}
+}
+
+// newLogHandler returns the stderr log handler for format "text" or "json".
+func newLogHandler(format string, level slog.Level) (slog.Handler, error) {
+	opts := &slog.HandlerOptions{Level: level}
+	switch format {
+	case "text":
+		return slog.NewTextHandler(os.Stderr, opts), nil
+	case "json":
+		return slog.NewJSONHandler(os.Stderr, opts), nil
+	default:
+		return nil, fmt.Errorf("invalid log format %q: must be 'text' or 'json'", format)
+	}
+}
+
+// watchConfig reloads the configuration on SIGHUP and when the config file changes,
+// telling every session to re-list tools when the allowed tools changed.
+func watchConfig(ctx context.Context, configs *prey.ConfigManager, s *server.MCPServer) {
+	configs.OnReload(func(toolsChanged bool) {
+		if toolsChanged {
+			s.SendNotificationToAllClients(mcp.MethodNotificationToolsListChanged, nil)
+		}
+	})
+	go configs.Watch(ctx)
+
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	go func() {
+		defer signal.Stop(hup)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-hup:
+				slog.Info("Received SIGHUP, reloading configuration")
+				if err := configs.Reload(); err != nil {
+					slog.Error("failed to reload configuration, keeping the current one", "error", err)
+				}
+			}
+		}
+	}()
+}
+
+func run(configs *prey.ConfigManager, transport, addr, basePath, endpointPath, metricsAddr, logFormat string, logLevel slog.Level, toolsets []string, dynamicToolsets bool) error {
+	logHandler, err := newLogHandler(logFormat, logLevel)
+	if err != nil {
+		return err
+	}
+	slog.SetDefault(slog.New(logHandler))
+	shutdownTracing, err := telemetry.Setup(context.Background())
+	if err != nil {
+		return err
+	}
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := shutdownTracing(ctx); err != nil {
+			slog.Warn("failed to flush traces", "error", err)
+		}
+	}()
+	if err := prey.LoadSecretFiles(); err != nil {
+		return err
+	}
+	if err := prey.ValidateRateLimit(); err != nil {
+		return err
+	}
+	maskPolicy, err := prey.MaskPolicyFromEnv()
+	if err != nil {
+		return err
+	}
+	internal.SetMaskPolicy(maskPolicy)
+	if _, err := prey.LocationPrecisionFromEnv(); err != nil {
+		return fmt.Errorf("PREY_LOCATION_PRECISION: %w", err)
+	}
+	if _, err := prey.ConfirmConfigFromEnv(); err != nil {
+		return err
+	}
+	policy, err := auth.PolicyFromEnv()
+	if err != nil {
+		return err
+	}
+	auditLog, err := audit.FromEnv(prey.AuditKeyFromEnv())
+	if err != nil {
+		return err
+	}
+	if auditLog != nil {
+		defer auditLog.Close()
+	}
+	approvalStore, err := approval.FromEnv()
+	if err != nil {
+		return err
+	}
+	if approvalStore != nil && transport == "stdio" {
+		// The stdio transport has a single caller, who cannot approve their own requests.
+		return fmt.Errorf("PREY_APPROVAL_STORE requires an HTTP transport: on stdio no second caller can approve requests")
+	}
+	var approvals *tools.Approvals
+	if approvalStore != nil {
+		approvals = tools.NewApprovals(approvalStore)
+	}
+	idempotency, err := tools.NewIdempotencyFromEnv()
+	if err != nil {
+		return err
+	}
+	readiness, err := prey.ReadinessFromEnv()
+	if err != nil {
+		return err
+	}
+	m := metrics.New()
+	s := newServer(serverDeps{
+		stdio:       transport == "stdio",
+		toolsets:    tools.NewToolsetManager(toolsets, dynamicToolsets),
+		metrics:     m,
+		policy:      policy,
+		auditLog:    auditLog,
+		approvals:   approvals,
+		idempotency: idempotency,
+	})
+	requestContext := prey.ComposeHTTPContextFuncs(prey.ExtractTraceContext, prey.ExtractCorrelationID, headerIdentity(policy), prey.ExtractInfoFromHeaders, prey.ExtractClientFromHeaders)
+	handleApprovals := func(mux *http.ServeMux, authenticate func(http.Handler) http.Handler) {
+		if approvals != nil {
+			mux.Handle(approvalsPath, authenticate(approvals.HTTPHandler(s, approvalsPath, requestContext)))
+		}
+	}
 
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
@@ -91,6 +355,8 @@ func run(transport, addr, basePath, endpointPath string, logLevel slog.Level) er
 	signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
 	defer signal.Stop(sigChan)
 
+	watchConfig(ctx, configs, s)
+
 	go func() {
 		<-sigChan
 		slog.Info("Received shutdown signal")
@@ -100,10 +366,14 @@ func run(transport, addr, basePath, endpointPath string, logLevel slog.Level) er
 		}
 	}()
 
+	if metricsAddr != "" {
+		go serveMetrics(ctx, metricsAddr, m)
+	}
+
 	switch transport {
 	case "stdio":
 		srv := server.NewStdioServer(s)
-		srv.SetContextFunc(prey.ComposedStdioContextFunc())
+		srv.SetContextFunc(prey.ComposeStdioContextFuncs(auth.ExtractStdioIdentity, prey.ComposedStdioContextFunc()))
 		slog.Info("Starting Prey MCP server using stdio transport")
 		err := srv.Listen(ctx, os.Stdin, os.Stdout)
 		if err != nil && err != context.Canceled {
@@ -113,29 +383,39 @@ func run(transport, addr, basePath, endpointPath string, logLevel slog.Level) er
 	case "sse":
 		httpSrv := &http.Server{Addr: addr}
 		srv := server.NewSSEServer(s,
-			server.WithSSEContextFunc(prey.ComposedSSEContextFunc()),
+			server.WithSSEContextFunc(server.SSEContextFunc(requestContext)),
 			server.WithStaticBasePath(basePath),
 			server.WithHTTPServer(httpSrv),
 		)
 		mux := http.NewServeMux()
+		authenticate, err := setupHTTPAuth(mux)
+		if err != nil {
+			return err
+		}
 		if basePath == "" {
 			basePath = "/"
 		}
-		mux.Handle(basePath, srv)
-		mux.HandleFunc("/healthz", handleHealthz)
+		mux.Handle(basePath, authenticate(srv))
+		handleApprovals(mux, authenticate)
+		handleProbes(mux, authenticate, readiness, m)
 		httpSrv.Handler = mux
 		slog.Info("Starting Prey MCP server using SSE transport", "address", addr, "basePath", basePath)
 		return runHTTPServer(ctx, srv, addr, "SSE")
 	case "streamable-http":
 		httpSrv := &http.Server{Addr: addr}
 		srv := server.NewStreamableHTTPServer(s,
-			server.WithHTTPContextFunc(prey.ComposedHTTPContextFunc()),
+			server.WithHTTPContextFunc(requestContext),
 			server.WithEndpointPath(endpointPath),
 			server.WithStreamableHTTPServer(httpSrv),
 		)
 		mux := http.NewServeMux()
-		mux.Handle(endpointPath, srv)
-		mux.HandleFunc("/healthz", handleHealthz)
+		authenticate, err := setupHTTPAuth(mux)
+		if err != nil {
+			return err
+		}
+		mux.Handle(endpointPath, authenticate(srv))
+		handleApprovals(mux, authenticate)
+		handleProbes(mux, authenticate, readiness, m)
 		httpSrv.Handler = mux
 		slog.Info("Starting Prey MCP server using StreamableHTTP transport", "address", addr, "endpointPath", endpointPath)
 		return runHTTPServer(ctx, srv, addr, "StreamableHTTP")
@@ -145,6 +425,13 @@ func run(transport, addr, basePath, endpointPath string, logLevel slog.Level) er
 }
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "audit" {
+		os.Exit(runAudit(os.Args[2:], os.Stdout, os.Stderr))
+	}
+	if len(os.Args) > 1 && os.Args[1] == "config" {
+		os.Exit(runConfig(os.Args[2:], os.Stdout, os.Stderr))
+	}
+
 	var transport string
 	flag.StringVar(&transport, "t", "stdio", "Transport type (stdio, sse or streamable-http)")
 	flag.StringVar(&transport, "transport", "stdio", "Transport type (stdio, sse or streamable-http)")
@@ -152,11 +439,32 @@ func main() {
 	basePath := flag.String("base-path", "", "Base path for the sse server")
 	endpointPath := flag.String("endpoint-path", "/mcp", "Endpoint path for the streamable-http server")
 	logLevel := flag.String("log-level", "info", "Log level (debug, info, warn, error)")
+	logFormat := flag.String("log-format", envOrDefault("PREY_LOG_FORMAT", "text"), "Log format (text or json)")
+	metricsAddr := flag.String("metrics-address", envOrDefault("PREY_METRICS_ADDRESS", ""), "Serve Prometheus metrics on a separate listener, e.g. for stdio")
+	toolsets := flag.String("toolsets", envOrDefault("PREY_TOOLSETS", "all"), "Comma-separated toolsets to enable, or 'all'")
+	dynamicToolsets := flag.Bool("dynamic-toolsets", prey.EnvBool("PREY_DYNAMIC_TOOLSETS"), "Let the model enable toolsets on demand with prey.toolsets.enable")
+	configPath := flag.String("config", envOrDefault("PREY_CONFIG_FILE", ""), "YAML or TOML config file with Prey profiles")
+	profile := flag.String("profile", envOrDefault("PREY_PROFILE", ""), "Config file profile to use")
 	flag.Parse()
 
-	if err := run(transport, *addr, *basePath, *endpointPath, parseLevel(*logLevel)); err != nil {
+	configs := prey.NewConfigManager(*configPath, *profile)
+	if err := configs.Load(); err != nil {
+		panic(err)
+	}
+	enabledToolsets, err := tools.ParseToolsets(*toolsets)
+	if err != nil {
 		panic(err)
 	}
+	if err := run(configs, transport, *addr, *basePath, *endpointPath, *metricsAddr, *logFormat, parseLevel(*logLevel), enabledToolsets, *dynamicToolsets); err != nil {
+		panic(err)
+	}
+}
+
+func envOrDefault(key, def string) string {
+	if val, ok := os.LookupEnv(key); ok {
+		return strings.TrimSpace(val)
+	}
+	return def
 }
 
 func parseLevel(level string) slog.Level {
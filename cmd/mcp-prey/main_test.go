@@ -0,0 +1,45 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"mcp-prey/metrics"
+)
+
+func TestMetricsRequireAuthentication(t *testing.T) {
+	sum := sha256.Sum256([]byte("secret-token"))
+	t.Setenv("PREY_AUTH_TOKENS", "scraper:"+hex.EncodeToString(sum[:]))
+	t.Setenv("PREY_AUTH_TOKENS_FILE", "")
+	mux := http.NewServeMux()
+	authenticate, err := setupHTTPAuth(mux)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	ready := http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) { w.WriteHeader(http.StatusOK) })
+	handleProbes(mux, authenticate, ready, metrics.New())
+
+	for _, tc := range []struct {
+		path, token string
+		want        int
+	}{
+		{path: "/healthz", want: http.StatusOK},
+		{path: "/readyz", want: http.StatusOK},
+		{path: metricsPath, want: http.StatusUnauthorized},
+		{path: metricsPath, token: "wrong-token", want: http.StatusUnauthorized},
+		{path: metricsPath, token: "secret-token", want: http.StatusOK},
+	} {
+		req := httptest.NewRequest(http.MethodGet, tc.path, nil)
+		if tc.token != "" {
+			req.Header.Set("Authorization", "Bearer "+tc.token)
+		}
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, req)
+		if rec.Code != tc.want {
+			t.Fatalf("%s with token %q: expected status %d, got %d", tc.path, tc.token, tc.want, rec.Code)
+		}
+	}
+}
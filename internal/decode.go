@@ -0,0 +1,160 @@
+package internal
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// Decode converts a generic JSON payload (as produced by DoJSON into an any) into a typed value.
+func Decode(src any, dst any) error {
+	b, err := json.Marshal(src)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(b, dst); err != nil {
+		return fmt.Errorf("decode response: %w", err)
+	}
+	return nil
+}
+
+// listKeys are the fields some Prey endpoints wrap list responses in.
+var listKeys = []string{"data", "items", "results"}
+
+// ListItems returns the records of a list response. Besides a JSON array it accepts
+// null, and an object wrapping the records under one of listKeys or as its only
+// array field.
+func ListItems(payload any) ([]any, error) {
+	switch v := payload.(type) {
+	case nil:
+		return []any{}, nil
+	case []any:
+		return v, nil
+	case map[string]any:
+		for _, key := range listKeys {
+			if items, ok := v[key].([]any); ok {
+				return items, nil
+			}
+		}
+		var items []any
+		found := 0
+		for _, field := range v {
+			if arr, ok := field.([]any); ok {
+				items = arr
+				found++
+			}
+		}
+		if found == 1 {
+			return items, nil
+		}
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		return nil, fmt.Errorf("decode response: expected a list, got an object with fields %s", strings.Join(keys, ", "))
+	default:
+		return nil, fmt.Errorf("decode response: expected a list, got %T", payload)
+	}
+}
+
+// DecodeList is Decode for list responses, accepting the shapes ListItems does.
+func DecodeList(src any, dst any) error {
+	items, err := ListItems(src)
+	if err != nil {
+		return err
+	}
+	return Decode(items, dst)
+}
+
+// FlexString is a string that also accepts JSON numbers and booleans, since the
+// Prey API is not consistent about the types it uses for identifiers.
+type FlexString string
+
+func (s *FlexString) UnmarshalJSON(b []byte) error {
+	b = bytes.TrimSpace(b)
+	if bytes.Equal(b, []byte("null")) {
+		return nil
+	}
+	if len(b) > 0 && b[0] == '"' {
+		var v string
+		if err := json.Unmarshal(b, &v); err != nil {
+			return err
+		}
+		*s = FlexString(v)
+		return nil
+	}
+	var v any
+	if err := json.Unmarshal(b, &v); err != nil {
+		return err
+	}
+	switch v.(type) {
+	case float64, bool:
+		*s = FlexString(b)
+		return nil
+	default:
+		return fmt.Errorf("cannot decode %s into a string", b)
+	}
+}
+
+// UnmarshalWithExtra decodes data into dst and stores every field that dst does
+// not declare into extra, so typed responses don't drop unknown API fields.
+// dst is expected to be a pointer to an alias type without an UnmarshalJSON method.
+func UnmarshalWithExtra(data []byte, dst any, extra *map[string]any) error {
+	if err := json.Unmarshal(data, dst); err != nil {
+		return err
+	}
+	var all map[string]any
+	if err := json.Unmarshal(data, &all); err != nil {
+		return err
+	}
+	for _, name := range jsonFieldNames(reflect.TypeOf(dst).Elem()) {
+		delete(all, name)
+	}
+	if len(all) > 0 {
+		*extra = all
+	}
+	return nil
+}
+
+// MarshalWithExtra encodes src and merges extra into the resulting object.
+// Declared fields win over extra fields with the same name.
+func MarshalWithExtra(src any, extra map[string]any) ([]byte, error) {
+	b, err := json.Marshal(src)
+	if err != nil || len(extra) == 0 {
+		return b, err
+	}
+	var out map[string]any
+	if err := json.Unmarshal(b, &out); err != nil {
+		return nil, err
+	}
+	for k, v := range extra {
+		if _, ok := out[k]; !ok {
+			out[k] = v
+		}
+	}
+	return json.Marshal(out)
+}
+
+func jsonFieldNames(t reflect.Type) []string {
+	names := make([]string, 0, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, _, _ := strings.Cut(tag, ",")
+		if name == "" {
+			name = field.Name
+		}
+		names = append(names, name)
+	}
+	return names
+}
@@ -0,0 +1,103 @@
+package internal
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+type extraRecord struct {
+	ID    FlexString     `json:"id"`
+	Name  string         `json:"name,omitempty"`
+	Extra map[string]any `json:"-"`
+}
+
+func (r *extraRecord) UnmarshalJSON(b []byte) error {
+	type record extraRecord
+	return UnmarshalWithExtra(b, (*record)(r), &r.Extra)
+}
+
+func (r extraRecord) MarshalJSON() ([]byte, error) {
+	type record extraRecord
+	return MarshalWithExtra(record(r), r.Extra)
+}
+
+func TestFlexString(t *testing.T) {
+	var values []FlexString
+	if err := json.Unmarshal([]byte(`["abc", 42, true, null]`), &values); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if values[0] != "abc" || values[1] != "42" || values[2] != "true" || values[3] != "" {
+		t.Fatalf("unexpected values: %v", values)
+	}
+	var v FlexString
+	if err := json.Unmarshal([]byte(`{"a":1}`), &v); err == nil {
+		t.Fatalf("expected error for object")
+	}
+}
+
+func TestDecodeKeepsExtraFields(t *testing.T) {
+	payload := map[string]any{"id": float64(7), "name": "laptop", "location": map[string]any{"lat": 1.5}}
+	var rec extraRecord
+	if err := Decode(payload, &rec); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if rec.ID != "7" || rec.Name != "laptop" {
+		t.Fatalf("unexpected record: %+v", rec)
+	}
+	if _, ok := rec.Extra["location"]; !ok {
+		t.Fatalf("expected location kept in extra")
+	}
+	if _, ok := rec.Extra["name"]; ok {
+		t.Fatalf("declared field should not be in extra")
+	}
+	b, err := json.Marshal(rec)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var out map[string]any
+	if err := json.Unmarshal(b, &out); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if out["id"] != "7" || out["location"] == nil {
+		t.Fatalf("unexpected output: %s", b)
+	}
+}
+
+func TestDecodeTypeMismatch(t *testing.T) {
+	var rec extraRecord
+	if err := Decode([]any{1, 2}, &rec); err == nil {
+		t.Fatalf("expected error decoding array into record")
+	}
+}
+
+func TestDecodeList(t *testing.T) {
+	cases := map[string]int{
+		`[{"id": 1}, {"id": 2}]`:              2,
+		`null`:                                0,
+		`{"data": [{"id": 1}], "total": 1}`:   1,
+		`{"devices": [{"id": 1}], "page": 1}`: 1,
+	}
+	for payload, want := range cases {
+		var src any
+		if err := json.Unmarshal([]byte(payload), &src); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		var records []extraRecord
+		if err := DecodeList(src, &records); err != nil {
+			t.Fatalf("unexpected error for %s: %v", payload, err)
+		}
+		if len(records) != want || records == nil {
+			t.Fatalf("expected %d records for %s, got %v", want, payload, records)
+		}
+	}
+	for _, payload := range []string{`{"id": 1, "name": "x"}`, `{"a": [], "b": []}`, `"text"`} {
+		var src any
+		if err := json.Unmarshal([]byte(payload), &src); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		var records []extraRecord
+		if err := DecodeList(src, &records); err == nil {
+			t.Fatalf("expected error for %s", payload)
+		}
+	}
+}
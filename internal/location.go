@@ -0,0 +1,243 @@
+package internal
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+var (
+	latitudeKeys  = []string{"lat", "latitude"}
+	longitudeKeys = []string{"lng", "lon", "long", "longitude"}
+)
+
+// Location precision modes.
+const (
+	LocationDecimals = "decimals"
+	LocationGeohash  = "geohash"
+)
+
+// LocationPrecision coarsens coordinates, either by rounding them to Digits decimal
+// places or by snapping them to the centre of the geohash cell with Digits characters.
+// The zero value leaves coordinates unchanged.
+type LocationPrecision struct {
+	Mode   string
+	Digits int
+}
+
+// ParseLocationPrecision parses "N" or "decimals:N" (0-6 places) and "geohash:N"
+// (1-12 characters). An empty value disables the reduction.
+func ParseLocationPrecision(val string) (LocationPrecision, error) {
+	val = strings.ToLower(strings.TrimSpace(val))
+	if val == "" {
+		return LocationPrecision{}, nil
+	}
+	kind, num, found := strings.Cut(val, ":")
+	if !found {
+		kind, num = LocationDecimals, val
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(num))
+	switch {
+	case err != nil:
+	case kind == LocationDecimals && n >= 0 && n <= 6:
+		return LocationPrecision{Mode: kind, Digits: n}, nil
+	case kind == LocationGeohash && n >= 1 && n <= 12:
+		return LocationPrecision{Mode: kind, Digits: n}, nil
+	}
+	return LocationPrecision{}, fmt.Errorf("invalid location precision %q: want decimals:0-6 or geohash:1-12", val)
+}
+
+// Enabled reports whether coordinates are coarsened.
+func (p LocationPrecision) Enabled() bool {
+	return p.Mode != ""
+}
+
+func (p LocationPrecision) String() string {
+	if !p.Enabled() {
+		return ""
+	}
+	return fmt.Sprintf("%s:%d", p.Mode, p.Digits)
+}
+
+// Reduce coarsens one coordinate pair.
+func (p LocationPrecision) Reduce(lat, lng float64) (float64, float64) {
+	switch p.Mode {
+	case LocationGeohash:
+		return geohashCenter(encodeGeohash(lat, lng, p.Digits))
+	case LocationDecimals:
+		scale := math.Pow(10, float64(p.Digits))
+		return math.Round(lat*scale) / scale, math.Round(lng*scale) / scale
+	}
+	return lat, lng
+}
+
+// Apply coarsens every object in v that has both a latitude and a longitude field.
+func (p LocationPrecision) Apply(v any) any {
+	if !p.Enabled() {
+		return v
+	}
+	switch t := v.(type) {
+	case map[string]any:
+		out := make(map[string]any, len(t))
+		for k, v2 := range t {
+			out[k] = p.Apply(v2)
+		}
+		latKey, lngKey := findKey(out, latitudeKeys), findKey(out, longitudeKeys)
+		if latKey == "" || lngKey == "" {
+			return out
+		}
+		lat, latOK := toFloat(out[latKey])
+		lng, lngOK := toFloat(out[lngKey])
+		if !latOK || !lngOK {
+			return out
+		}
+		lat, lng = p.Reduce(lat, lng)
+		out[latKey] = sameType(out[latKey], lat)
+		out[lngKey] = sameType(out[lngKey], lng)
+		return out
+	case []any:
+		out := make([]any, len(t))
+		for i, v2 := range t {
+			out[i] = p.Apply(v2)
+		}
+		return out
+	default:
+		return v
+	}
+}
+
+// ApplyCSV coarsens the latitude and longitude columns of a CSV document with a header row.
+func (p LocationPrecision) ApplyCSV(b []byte) ([]byte, error) {
+	if !p.Enabled() {
+		return b, nil
+	}
+	records, err := csv.NewReader(bytes.NewReader(b)).ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("parse location CSV: %w", err)
+	}
+	if len(records) == 0 {
+		return b, nil
+	}
+	latCol, lngCol := -1, -1
+	for i, name := range records[0] {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if containsString(latitudeKeys, name) {
+			latCol = i
+		}
+		if containsString(longitudeKeys, name) {
+			lngCol = i
+		}
+	}
+	if latCol < 0 || lngCol < 0 {
+		return b, nil
+	}
+	for _, row := range records[1:] {
+		if latCol >= len(row) || lngCol >= len(row) {
+			continue
+		}
+		lat, err1 := strconv.ParseFloat(strings.TrimSpace(row[latCol]), 64)
+		lng, err2 := strconv.ParseFloat(strings.TrimSpace(row[lngCol]), 64)
+		if err1 != nil || err2 != nil {
+			continue
+		}
+		lat, lng = p.Reduce(lat, lng)
+		row[latCol] = strconv.FormatFloat(lat, 'f', -1, 64)
+		row[lngCol] = strconv.FormatFloat(lng, 'f', -1, 64)
+	}
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	if err := w.WriteAll(records); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func findKey(m map[string]any, candidates []string) string {
+	for k := range m {
+		if containsString(candidates, strings.ToLower(k)) {
+			return k
+		}
+	}
+	return ""
+}
+
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+func toFloat(v any) (float64, bool) {
+	switch t := v.(type) {
+	case float64:
+		return t, true
+	case string:
+		f, err := strconv.ParseFloat(strings.TrimSpace(t), 64)
+		return f, err == nil
+	}
+	return 0, false
+}
+
+// sameType returns f as a string when the original value was a string.
+func sameType(original any, f float64) any {
+	if _, ok := original.(string); ok {
+		return strconv.FormatFloat(f, 'f', -1, 64)
+	}
+	return f
+}
+
+const geohashAlphabet = "0123456789bcdefghjkmnpqrstuvwxyz"
+
+func encodeGeohash(lat, lng float64, precision int) string {
+	latRange, lngRange := [2]float64{-90, 90}, [2]float64{-180, 180}
+	var out strings.Builder
+	bit, ch, even := 0, 0, true
+	for out.Len() < precision {
+		r, v := &latRange, lat
+		if even {
+			r, v = &lngRange, lng
+		}
+		mid := (r[0] + r[1]) / 2
+		ch <<= 1
+		if v >= mid {
+			ch |= 1
+			r[0] = mid
+		} else {
+			r[1] = mid
+		}
+		even = !even
+		if bit++; bit == 5 {
+			out.WriteByte(geohashAlphabet[ch])
+			bit, ch = 0, 0
+		}
+	}
+	return out.String()
+}
+
+func geohashCenter(hash string) (float64, float64) {
+	latRange, lngRange := [2]float64{-90, 90}, [2]float64{-180, 180}
+	even := true
+	for i := 0; i < len(hash); i++ {
+		ch := strings.IndexByte(geohashAlphabet, hash[i])
+		for b := 4; b >= 0; b-- {
+			r := &latRange
+			if even {
+				r = &lngRange
+			}
+			mid := (r[0] + r[1]) / 2
+			if ch&(1<<b) != 0 {
+				r[0] = mid
+			} else {
+				r[1] = mid
+			}
+			even = !even
+		}
+	}
+	return (latRange[0] + latRange[1]) / 2, (lngRange[0] + lngRange[1]) / 2
+}
@@ -0,0 +1,67 @@
+package internal
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseLocationPrecision(t *testing.T) {
+	cases := map[string]LocationPrecision{
+		"":           {},
+		"2":          {Mode: LocationDecimals, Digits: 2},
+		"decimals:0": {Mode: LocationDecimals, Digits: 0},
+		"geohash:6":  {Mode: LocationGeohash, Digits: 6},
+	}
+	for in, want := range cases {
+		got, err := ParseLocationPrecision(in)
+		if err != nil || got != want {
+			t.Fatalf("ParseLocationPrecision(%q) = %+v, %v; want %+v", in, got, err, want)
+		}
+	}
+	for _, in := range []string{"7", "geohash:0", "grid:3", "x"} {
+		if _, err := ParseLocationPrecision(in); err == nil {
+			t.Fatalf("expected error for %q", in)
+		}
+	}
+}
+
+func TestLocationPrecisionApply(t *testing.T) {
+	p := LocationPrecision{Mode: LocationDecimals, Digits: 2}
+	out := p.Apply([]any{
+		map[string]any{"lat": -33.44889, "lng": -70.669265, "accuracy": 12.0},
+		map[string]any{"location": map[string]any{"latitude": "48.858370", "longitude": "2.294481"}},
+		map[string]any{"lat": 1.23456},
+	}).([]any)
+	first := out[0].(map[string]any)
+	if first["lat"] != -33.45 || first["lng"] != -70.67 || first["accuracy"] != 12.0 {
+		t.Fatalf("unexpected rounded location: %v", first)
+	}
+	nested := out[1].(map[string]any)["location"].(map[string]any)
+	if nested["latitude"] != "48.86" || nested["longitude"] != "2.29" {
+		t.Fatalf("expected string coordinates to stay strings: %v", nested)
+	}
+	if out[2].(map[string]any)["lat"] != 1.23456 {
+		t.Fatalf("expected a lone latitude to be left alone")
+	}
+
+	g := LocationPrecision{Mode: LocationGeohash, Digits: 5}
+	if encodeGeohash(57.64911, 10.40744, 11) != "u4pruydqqvj" {
+		t.Fatalf("unexpected geohash %s", encodeGeohash(57.64911, 10.40744, 11))
+	}
+	lat1, lng1 := g.Reduce(57.64911, 10.40744)
+	lat2, lng2 := g.Reduce(57.64921, 10.40754)
+	if lat1 != lat2 || lng1 != lng2 || lat1 == 57.64911 {
+		t.Fatalf("expected nearby points to share a cell centre: %v,%v %v,%v", lat1, lng1, lat2, lng2)
+	}
+}
+
+func TestLocationPrecisionApplyCSV(t *testing.T) {
+	p := LocationPrecision{Mode: LocationDecimals, Digits: 1}
+	out, err := p.ApplyCSV([]byte("date,lat,lng\n2024-01-01,-33.44889,-70.669265\n"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(string(out), "2024-01-01,-33.4,-70.7") {
+		t.Fatalf("unexpected CSV: %s", out)
+	}
+}
@@ -1,32 +1,210 @@
 package internal
 
-import "strings"
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"regexp"
+	"strings"
+	"sync/atomic"
+)
 
 var sensitiveKeys = []string{"token", "secret", "password", "apikey", "api_key"}
 
+const redacted = "***"
+
+// Masking modes for personal data matched by extra keys and value patterns.
+// Secrets matched by the built-in sensitive keys are redacted in either mode.
+const (
+	MaskModeRedact       = "redact"
+	MaskModePseudonymize = "pseudonymize"
+)
+
+// MaskRule masks personal data of one kind, by key name and/or by value.
+type MaskRule struct {
+	Name string
+	// Keys are key substrings whose values are masked.
+	Keys []string
+	// Pattern matches values, or parts of string values, to mask.
+	Pattern *regexp.Regexp
+	// Valid, when set, must accept a pattern match for it to be masked.
+	Valid func(string) bool
+}
+
+// BuiltinMaskRules are the rules selectable by name.
+var BuiltinMaskRules = map[string]MaskRule{
+	"email": {
+		Name:    "email",
+		Keys:    []string{"email"},
+		Pattern: regexp.MustCompile(`[A-Za-z0-9._%+\-]+@[A-Za-z0-9.\-]+\.[A-Za-z]{2,}`),
+	},
+	"phone": {
+		Name:    "phone",
+		Keys:    []string{"phone", "mobile"},
+		Pattern: regexp.MustCompile(`\+\d[\d\s().\-]{6,}\d|\(\d{3}\)\s?\d{3}[\s.\-]\d{4}`),
+	},
+	"mac": {
+		Name:    "mac",
+		Keys:    []string{"mac_address"},
+		Pattern: regexp.MustCompile(`\b[0-9A-Fa-f]{2}(?:[:\-][0-9A-Fa-f]{2}){5}\b`),
+	},
+	// Serial numbers have no recognisable shape, so they are matched by key only.
+	"serial": {
+		Name: "serial",
+		Keys: []string{"serial"},
+	},
+	"ip": {
+		Name:    "ip",
+		Keys:    []string{"ip_address"},
+		Pattern: regexp.MustCompile(`\b\d{1,3}(?:\.\d{1,3}){3}\b|[0-9A-Fa-f]{0,4}(?::[0-9A-Fa-f]{0,4}){2,7}`),
+		Valid:   func(s string) bool { return net.ParseIP(s) != nil },
+	},
+}
+
+// MaskPolicy configures MaskSensitive.
+type MaskPolicy struct {
+	// ExtraKeys are additional key substrings whose values are masked.
+	ExtraKeys []string
+	// AllowKeys are keys (exact, case-insensitive) whose values are never masked,
+	// e.g. to keep a "token_count" field that the secret key list would match.
+	AllowKeys []string
+	Rules     []MaskRule
+	Mode      string
+	// Salt keys pseudonyms so they cannot be reversed by hashing guesses.
+	Salt []byte
+	// SaltFunc, when set, returns the current salt instead of Salt, so a salt read
+	// from a file can change without a restart.
+	SaltFunc func() []byte
+}
+
+var maskPolicy atomic.Pointer[MaskPolicy]
+
+// SetMaskPolicy replaces the policy used by MaskSensitive. A nil policy restores
+// the default, which only redacts secrets.
+func SetMaskPolicy(p *MaskPolicy) {
+	if p != nil && p.Mode == MaskModePseudonymize && len(p.Salt) == 0 && p.SaltFunc == nil {
+		copied := *p
+		copied.Salt = make([]byte, 32)
+		_, _ = rand.Read(copied.Salt)
+		p = &copied
+	}
+	maskPolicy.Store(p)
+}
+
+// MaskSensitive masks secrets and, as configured with SetMaskPolicy, personal data.
 func MaskSensitive(v any) any {
+	p := maskPolicy.Load()
+	if p == nil {
+		p = &MaskPolicy{}
+	}
+	return p.Mask(v)
+}
+
+// Mask returns a copy of v with sensitive values masked.
+func (p *MaskPolicy) Mask(v any) any {
 	switch t := v.(type) {
 	case map[string]any:
 		out := make(map[string]any, len(t))
 		for k, v2 := range t {
-			if isSensitiveKey(k) {
-				out[k] = "***"
-				continue
-			}
-			out[k] = MaskSensitive(v2)
+			out[k] = p.maskField(k, v2)
 		}
 		return out
 	case []any:
 		out := make([]any, len(t))
 		for i, v2 := range t {
-			out[i] = MaskSensitive(v2)
+			out[i] = p.Mask(v2)
 		}
 		return out
+	case string:
+		return p.maskString(t)
 	default:
 		return v
 	}
 }
 
+func (p *MaskPolicy) maskField(key string, v any) any {
+	if p.allowed(key) {
+		switch v.(type) {
+		case map[string]any, []any:
+			return p.Mask(v)
+		}
+		return v
+	}
+	if isSensitiveKey(key) {
+		return redacted
+	}
+	if kind, ok := p.personalKey(key); ok {
+		switch v.(type) {
+		case map[string]any, []any:
+			return p.Mask(v)
+		case nil:
+			return nil
+		}
+		return p.replace(kind, fmt.Sprint(v))
+	}
+	return p.Mask(v)
+}
+
+func (p *MaskPolicy) maskString(s string) string {
+	for _, rule := range p.Rules {
+		if rule.Pattern == nil {
+			continue
+		}
+		s = rule.Pattern.ReplaceAllStringFunc(s, func(m string) string {
+			if rule.Valid != nil && !rule.Valid(m) {
+				return m
+			}
+			return p.replace(rule.Name, m)
+		})
+	}
+	return s
+}
+
+// replace redacts value or, in pseudonymize mode, replaces it with a stable token
+// so the same value always maps to the same pseudonym.
+func (p *MaskPolicy) replace(kind, value string) string {
+	if p.Mode != MaskModePseudonymize {
+		return redacted
+	}
+	salt := p.Salt
+	if p.SaltFunc != nil {
+		salt = p.SaltFunc()
+	}
+	mac := hmac.New(sha256.New, salt)
+	mac.Write([]byte(value))
+	return kind + "_" + hex.EncodeToString(mac.Sum(nil))[:12]
+}
+
+func (p *MaskPolicy) allowed(key string) bool {
+	for _, a := range p.AllowKeys {
+		if strings.EqualFold(a, key) {
+			return true
+		}
+	}
+	return false
+}
+
+// personalKey reports whether key holds personal data and the kind used in pseudonyms.
+func (p *MaskPolicy) personalKey(key string) (string, bool) {
+	k := strings.ToLower(key)
+	for _, rule := range p.Rules {
+		for _, s := range rule.Keys {
+			if strings.Contains(k, s) {
+				return rule.Name, true
+			}
+		}
+	}
+	for _, s := range p.ExtraKeys {
+		if strings.Contains(k, strings.ToLower(s)) {
+			return "anon", true
+		}
+	}
+	return "", false
+}
+
 func isSensitiveKey(key string) bool {
 	k := strings.ToLower(key)
 	for _, s := range sensitiveKeys {
@@ -26,3 +26,50 @@ func TestMaskSensitive(t *testing.T) {
 		t.Fatalf("expected api_key masked")
 	}
 }
+
+func TestMaskPolicy(t *testing.T) {
+	p := &MaskPolicy{
+		ExtraKeys: []string{"owner"},
+		AllowKeys: []string{"token_count"},
+		Rules: []MaskRule{
+			BuiltinMaskRules["email"], BuiltinMaskRules["mac"], BuiltinMaskRules["serial"], BuiltinMaskRules["ip"],
+		},
+		Mode: MaskModeRedact,
+	}
+	masked := p.Mask(map[string]any{
+		"token_count":   float64(3),
+		"owner":         "Jane Doe",
+		"serial_number": "C02XK1ABJGH5",
+		"note":          "Contact jane@example.com from 10.0.0.12 (aa:bb:cc:dd:ee:ff) at 12:30:45",
+		"version":       "10.15.7",
+	}).(map[string]any)
+	if masked["token_count"] != float64(3) {
+		t.Fatalf("expected allowlisted key to be kept, got %v", masked["token_count"])
+	}
+	if masked["owner"] != "***" || masked["serial_number"] != "***" {
+		t.Fatalf("expected extra and serial keys masked, got %v", masked)
+	}
+	if got := masked["note"]; got != "Contact *** from *** (***) at 12:30:45" {
+		t.Fatalf("unexpected masked note: %v", got)
+	}
+	if masked["version"] != "10.15.7" {
+		t.Fatalf("expected version to be kept, got %v", masked["version"])
+	}
+}
+
+func TestMaskPseudonymize(t *testing.T) {
+	p := &MaskPolicy{Rules: []MaskRule{BuiltinMaskRules["email"]}, Mode: MaskModePseudonymize, Salt: []byte("salt")}
+	a := p.Mask(map[string]any{"email": "jane@example.com", "password": "x"}).(map[string]any)
+	b := p.Mask("reply to jane@example.com").(string)
+	pseudonym, _ := a["email"].(string)
+	if pseudonym == "" || pseudonym == "jane@example.com" || b != "reply to "+pseudonym {
+		t.Fatalf("expected a stable pseudonym, got %q and %q", pseudonym, b)
+	}
+	if a["password"] != "***" {
+		t.Fatalf("expected secrets to be redacted, got %v", a["password"])
+	}
+	other := &MaskPolicy{Rules: p.Rules, Mode: MaskModePseudonymize, Salt: []byte("other")}
+	if other.Mask("jane@example.com") == pseudonym {
+		t.Fatalf("expected pseudonyms to depend on the salt")
+	}
+}
@@ -42,18 +42,33 @@ func AddPagination(q url.Values, page, pageSize int) (url.Values, error) {
 	return q, nil
 }
 
+// PageMeta describes the page returned by a paginated list tool.
+type PageMeta struct {
+	Page     int `json:"page" jsonschema:"description=Page number"`
+	PageSize int `json:"page_size" jsonschema:"description=Number of records per page"`
+	Pages    int `json:"pages,omitempty" jsonschema:"description=Number of pages fetched when all_pages is set"`
+}
+
 func Meta(page, pageSize int) (map[string]any, error) {
-	var err error
-	pageSize, err = NormalizePageSize(pageSize)
+	meta, err := NewPageMeta(page, pageSize)
 	if err != nil {
 		return nil, err
 	}
 	return map[string]any{
-		"page":      NormalizePage(page),
-		"page_size": pageSize,
+		"page":      meta.Page,
+		"page_size": meta.PageSize,
 	}, nil
 }
 
+func NewPageMeta(page, pageSize int) (*PageMeta, error) {
+	var err error
+	pageSize, err = NormalizePageSize(pageSize)
+	if err != nil {
+		return nil, err
+	}
+	return &PageMeta{Page: NormalizePage(page), PageSize: pageSize}, nil
+}
+
 func itoa(v int) string {
 	if v == 0 {
 		return "0"
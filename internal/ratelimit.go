@@ -2,32 +2,207 @@ package internal
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
 	"time"
 
 	"golang.org/x/time/rate"
 )
 
+// RateTier allows Limit requests per Period, with bursts of up to Burst requests.
+type RateTier struct {
+	Limit  int
+	Period time.Duration
+	Burst  int
+}
+
+// String formats the tier as ParseRateTiers accepts it.
+func (t RateTier) String() string {
+	return fmt.Sprintf("%d/%s:%d", t.Limit, formatPeriod(t.Period), t.Burst)
+}
+
+// formatPeriod drops the zero units time.Duration.String adds, so 1m prints as 1m.
+func formatPeriod(d time.Duration) string {
+	s := d.String()
+	if strings.HasSuffix(s, "m0s") {
+		s = strings.TrimSuffix(s, "0s")
+	}
+	if strings.HasSuffix(s, "h0m") {
+		s = strings.TrimSuffix(s, "0m")
+	}
+	return s
+}
+
+// DefaultPreyTiers are Prey's limits per API key: 2 req/s, 60 req/min and 10k req/hour.
+var DefaultPreyTiers = []RateTier{
+	{Limit: 2, Period: time.Second, Burst: 2},
+	{Limit: 60, Period: time.Minute, Burst: 60},
+	{Limit: 10000, Period: time.Hour, Burst: 100},
+}
+
+// DefaultWriteReserve is the share of every tier kept for write calls. Every tier of
+// DefaultPreyTiers has the burst to keep it.
+const DefaultWriteReserve = 0.2
+
+// ParseRateTiers parses a comma-separated list of LIMIT/PERIOD[:BURST] tiers, such as
+// "2/1s,60/1m,10000/1h:100". PERIOD is a Go duration; BURST defaults to LIMIT.
+func ParseRateTiers(val string) ([]RateTier, error) {
+	var tiers []RateTier
+	for _, entry := range strings.Split(val, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		spec, burst, hasBurst := strings.Cut(entry, ":")
+		limit, period, ok := strings.Cut(spec, "/")
+		if !ok {
+			return nil, fmt.Errorf("rate limit tier %q: expected LIMIT/PERIOD[:BURST]", entry)
+		}
+		var t RateTier
+		var err error
+		if t.Limit, err = strconv.Atoi(strings.TrimSpace(limit)); err != nil || t.Limit <= 0 {
+			return nil, fmt.Errorf("rate limit tier %q: limit must be a positive integer", entry)
+		}
+		if t.Period, err = time.ParseDuration(strings.TrimSpace(period)); err != nil || t.Period <= 0 {
+			return nil, fmt.Errorf("rate limit tier %q: period must be a positive duration", entry)
+		}
+		t.Burst = t.Limit
+		if hasBurst {
+			if t.Burst, err = strconv.Atoi(strings.TrimSpace(burst)); err != nil || t.Burst <= 0 {
+				return nil, fmt.Errorf("rate limit tier %q: burst must be a positive integer", entry)
+			}
+		}
+		tiers = append(tiers, t)
+	}
+	if len(tiers) == 0 {
+		return nil, errors.New("no rate limit tiers")
+	}
+	return tiers, nil
+}
+
+// tierLimiter enforces one tier. When a write reserve is configured the tier is split
+// into a shared bucket, used by every call, and a reserved bucket only writes may use.
+type tierLimiter struct {
+	tier     RateTier
+	shared   *rate.Limiter
+	reserved *rate.Limiter
+}
+
+// MultiLimiter enforces several rate tiers at once.
 type MultiLimiter struct {
-	limiters []*rate.Limiter
+	tiers []tierLimiter
 }
 
-func NewMultiLimiter(limiters ...*rate.Limiter) *MultiLimiter {
-	return &MultiLimiter{limiters: limiters}
+// NewTieredLimiter enforces every tier and keeps writeReserve (0 to 1) of each tier's
+// rate and burst for writes, so reads cannot use up the whole budget.
+func NewTieredLimiter(tiers []RateTier, writeReserve float64) (*MultiLimiter, error) {
+	if writeReserve < 0 || writeReserve >= 1 {
+		return nil, fmt.Errorf("write reserve %v must be at least 0 and below 1", writeReserve)
+	}
+	m := &MultiLimiter{}
+	for _, t := range tiers {
+		every := float64(t.Limit) / t.Period.Seconds()
+		reservedBurst := int(math.Ceil(float64(t.Burst) * writeReserve))
+		if reservedBurst >= t.Burst {
+			return nil, fmt.Errorf("rate limit tier %s: burst is too small for a write reserve of %v", t, writeReserve)
+		}
+		tl := tierLimiter{
+			tier:   t,
+			shared: rate.NewLimiter(rate.Limit(every*(1-writeReserve)), t.Burst-reservedBurst),
+		}
+		if reservedBurst > 0 {
+			tl.reserved = rate.NewLimiter(rate.Limit(every*writeReserve), reservedBurst)
+		}
+		m.tiers = append(m.tiers, tl)
+	}
+	return m, nil
 }
 
+// Wait blocks until every tier allows a read.
 func (m *MultiLimiter) Wait(ctx context.Context) error {
-	for _, limiter := range m.limiters {
-		if err := limiter.Wait(ctx); err != nil {
+	for _, t := range m.tiers {
+		if err := t.shared.Wait(ctx); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WaitWrite blocks until every tier allows a write. Writes take whichever of the
+// shared and reserved buckets frees up first.
+func (m *MultiLimiter) WaitWrite(ctx context.Context) error {
+	for _, t := range m.tiers {
+		if err := t.waitWrite(ctx); err != nil {
 			return err
 		}
 	}
 	return nil
 }
 
-func NewPreyLimiter() *MultiLimiter {
-	// Per spec: 2 req/s, 60 req/min, 10k req/hour per API key
-	perSecond := rate.NewLimiter(rate.Every(500*time.Millisecond), 2)
-	perMinute := rate.NewLimiter(rate.Every(time.Minute/60), 60)
-	perHour := rate.NewLimiter(rate.Every(time.Hour/10000), 100)
-	return NewMultiLimiter(perSecond, perMinute, perHour)
+func (t tierLimiter) waitWrite(ctx context.Context) error {
+	if t.reserved == nil {
+		return t.shared.Wait(ctx)
+	}
+	now := time.Now()
+	r := t.shared.ReserveN(now, 1)
+	if other := t.reserved.ReserveN(now, 1); r.DelayFrom(now) > other.DelayFrom(now) {
+		r.CancelAt(now)
+		r = other
+	} else {
+		other.CancelAt(now)
+	}
+	delay := r.DelayFrom(now)
+	if delay == 0 {
+		return nil
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		r.Cancel()
+		return ctx.Err()
+	}
+}
+
+// TierStatus is the remaining budget of one tier. Remaining counts requests any call
+// can make right now; ReservedRemaining those only writes can make.
+type TierStatus struct {
+	Tier              RateTier
+	Remaining         int
+	ReservedRemaining int
+}
+
+// Status reports the remaining budget of every tier.
+func (m *MultiLimiter) Status() []TierStatus {
+	status := make([]TierStatus, 0, len(m.tiers))
+	for _, t := range m.tiers {
+		s := TierStatus{Tier: t.tier, Remaining: tokens(t.shared)}
+		if t.reserved != nil {
+			s.ReservedRemaining = tokens(t.reserved)
+		}
+		status = append(status, s)
+	}
+	return status
+}
+
+// tokens rounds down; a limiter in debt reports zero.
+func tokens(l *rate.Limiter) int {
+	return max(0, int(math.Floor(l.Tokens())))
+}
+
+// RefillTime is how long an unused limiter takes to refill every tier, after which it
+// is indistinguishable from a new one.
+func (m *MultiLimiter) RefillTime() time.Duration {
+	var longest time.Duration
+	for _, t := range m.tiers {
+		if t.tier.Limit > 0 {
+			longest = max(longest, t.tier.Period*time.Duration(t.tier.Burst)/time.Duration(t.tier.Limit))
+		}
+	}
+	return longest
 }
@@ -0,0 +1,72 @@
+package internal
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestParseRateTiers(t *testing.T) {
+	tiers, err := ParseRateTiers("2/1s, 60/1m,10000/1h:100")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(tiers) != 3 || tiers[1].Burst != 60 || tiers[2].Burst != 100 || tiers[2].Period != time.Hour {
+		t.Fatalf("unexpected tiers: %v", tiers)
+	}
+	if got := tiers[2].String(); got != "10000/1h:100" {
+		t.Fatalf("expected 10000/1h:100, got %s", got)
+	}
+	for _, val := range []string{"", "2", "x/1s", "2/soon", "2/1s:0", "0/1s"} {
+		if _, err := ParseRateTiers(val); err == nil {
+			t.Fatalf("expected error for %q", val)
+		}
+	}
+}
+
+func TestTieredLimiterWriteReserve(t *testing.T) {
+	m, err := NewTieredLimiter([]RateTier{{Limit: 10, Period: time.Hour, Burst: 10}}, 0.2)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	for i := 0; i < 8; i++ {
+		if err := m.Wait(ctx); err != nil {
+			t.Fatalf("unexpected error on read %d: %v", i, err)
+		}
+	}
+	if err := m.Wait(ctx); err == nil {
+		t.Fatalf("expected reads to stop at the write reserve")
+	}
+	for i := 0; i < 2; i++ {
+		if err := m.WaitWrite(ctx); err != nil {
+			t.Fatalf("unexpected error on write %d: %v", i, err)
+		}
+	}
+	status := m.Status()
+	if len(status) != 1 || status[0].Remaining != 0 || status[0].ReservedRemaining != 0 {
+		t.Fatalf("unexpected status: %+v", status)
+	}
+}
+
+func TestTieredLimiterRejectsSmallBurst(t *testing.T) {
+	if _, err := NewTieredLimiter([]RateTier{{Limit: 1, Period: time.Second, Burst: 1}}, 0.2); err == nil {
+		t.Fatalf("expected error when the reserve leaves no burst for reads")
+	}
+	if _, err := NewTieredLimiter(DefaultPreyTiers, 1); err == nil {
+		t.Fatalf("expected error for a reserve of 1")
+	}
+}
+
+func TestDefaultTiersKeepDefaultReserve(t *testing.T) {
+	m, err := NewTieredLimiter(DefaultPreyTiers, DefaultWriteReserve)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, s := range m.Status() {
+		if s.ReservedRemaining == 0 {
+			t.Fatalf("expected tier %s to keep a write reserve, got %+v", s.Tier, s)
+		}
+	}
+}
@@ -8,3 +8,14 @@ func Wrap(data any, meta any) map[string]any {
 	}
 	return resp
 }
+
+// Envelope is the typed counterpart of Wrap, used by tools that expose an output schema.
+type Envelope[T any] struct {
+	Data T         `json:"data"`
+	Meta *PageMeta `json:"meta,omitempty"`
+}
+
+// NewEnvelope returns a typed response envelope with data and optional meta.
+func NewEnvelope[T any](data T, meta *PageMeta) *Envelope[T] {
+	return &Envelope[T]{Data: data, Meta: meta}
+}
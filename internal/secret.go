@@ -0,0 +1,85 @@
+package internal
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// secretCheckInterval bounds how often a secret file is checked for changes.
+const secretCheckInterval = time.Second
+
+// Secret is a value read from a file, such as a mounted Kubernetes or Docker secret.
+// It is read once when opened and re-read when the file changes. Errors name the
+// file but never include its contents.
+type Secret struct {
+	path string
+	now  func() time.Time
+
+	mu      sync.Mutex
+	value   string
+	info    os.FileInfo
+	checked time.Time
+}
+
+// OpenSecret reads the secret at path, which must be readable and non-empty.
+// Surrounding whitespace, such as a trailing newline, is trimmed.
+func OpenSecret(path string) (*Secret, error) {
+	s := &Secret{path: path, now: time.Now}
+	if err := s.Reload(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *Secret) Path() string {
+	return s.path
+}
+
+// Value returns the secret, re-reading the file if it changed since the last read.
+// When the new contents cannot be read or are empty, the last good value is kept.
+func (s *Secret) Value() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if now := s.now(); now.Sub(s.checked) >= secretCheckInterval {
+		s.checked = now
+		// Stat follows symlinks, so the atomic symlink swap used for Kubernetes
+		// secret volumes shows up as a different file.
+		if info, err := os.Stat(s.path); err == nil && FileChanged(s.info, info) {
+			_ = s.reloadLocked()
+		}
+	}
+	return s.value
+}
+
+// Reload re-reads the file now. On error the previous value is kept.
+func (s *Secret) Reload() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.reloadLocked()
+}
+
+func (s *Secret) reloadLocked() error {
+	info, err := os.Stat(s.path)
+	if err != nil {
+		return fmt.Errorf("secret file %s: %w", s.path, err)
+	}
+	b, err := os.ReadFile(s.path)
+	if err != nil {
+		return fmt.Errorf("secret file %s: %w", s.path, err)
+	}
+	value := strings.TrimSpace(string(b))
+	if value == "" {
+		return fmt.Errorf("secret file %s is empty", s.path)
+	}
+	s.value, s.info, s.checked = value, info, s.now()
+	return nil
+}
+
+// FileChanged reports whether cur is a different file, or was modified, compared to
+// prev. A nil prev counts as changed.
+func FileChanged(prev, cur os.FileInfo) bool {
+	return prev == nil || !os.SameFile(prev, cur) || !prev.ModTime().Equal(cur.ModTime()) || prev.Size() != cur.Size()
+}
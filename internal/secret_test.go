@@ -0,0 +1,58 @@
+package internal
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestSecretReloadsOnChange(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "key")
+	if err := os.WriteFile(path, []byte("first\n"), 0o600); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	s, err := OpenSecret(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	now := time.Now()
+	s.now = func() time.Time { return now }
+	if got := s.Value(); got != "first" {
+		t.Fatalf("expected trimmed value, got %q", got)
+	}
+
+	if err := os.WriteFile(path, []byte("second-key"), 0o600); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := s.Value(); got != "first" {
+		t.Fatalf("expected no re-read within the check interval, got %q", got)
+	}
+	now = now.Add(secretCheckInterval)
+	if got := s.Value(); got != "second-key" {
+		t.Fatalf("expected the changed value, got %q", got)
+	}
+
+	// An emptied file keeps the last good value.
+	if err := os.WriteFile(path, nil, 0o600); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	now = now.Add(secretCheckInterval)
+	if got := s.Value(); got != "second-key" {
+		t.Fatalf("expected the last good value, got %q", got)
+	}
+}
+
+func TestOpenSecretErrors(t *testing.T) {
+	dir := t.TempDir()
+	if _, err := OpenSecret(filepath.Join(dir, "missing")); err == nil {
+		t.Fatalf("expected error for a missing file")
+	}
+	empty := filepath.Join(dir, "empty")
+	if err := os.WriteFile(empty, []byte(" \n"), 0o600); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := OpenSecret(empty); err == nil {
+		t.Fatalf("expected error for an empty file")
+	}
+}
@@ -0,0 +1,125 @@
+// Package metrics exposes Prometheus metrics for tool calls, upstream Prey
+// requests, rate limiting and sessions.
+package metrics
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+
+	"mcp-prey/prey"
+)
+
+const namespace = "mcp_prey"
+
+// Tool call outcomes.
+const (
+	outcomeOK        = "ok"
+	outcomeToolError = "tool_error"
+	outcomeError     = "error"
+)
+
+// Metrics holds the server's collectors in a dedicated registry.
+type Metrics struct {
+	registry *prometheus.Registry
+
+	toolCalls        *prometheus.CounterVec
+	toolDuration     *prometheus.HistogramVec
+	upstreamRequests *prometheus.CounterVec
+	upstreamDuration *prometheus.HistogramVec
+	rateLimitWait    prometheus.Histogram
+	activeSessions   prometheus.Gauge
+}
+
+func New() *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		toolCalls: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "tool_calls_total",
+			Help:      "Tool calls by tool and outcome (ok, tool_error, error).",
+		}, []string{"tool", "outcome"}),
+		toolDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "tool_call_duration_seconds",
+			Help:      "Tool call latency by tool.",
+			Buckets:   []float64{.05, .1, .25, .5, 1, 2.5, 5, 10, 30, 60, 120},
+		}, []string{"tool"}),
+		upstreamRequests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "upstream_requests_total",
+			Help:      "Prey API requests by method, route template and HTTP status (0 when no response).",
+		}, []string{"method", "endpoint", "status"}),
+		upstreamDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "upstream_request_duration_seconds",
+			Help:      "Prey API request latency by method and route template, excluding rate limiting.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"method", "endpoint"}),
+		rateLimitWait: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "ratelimit_wait_seconds",
+			Help:      "Time upstream requests waited for the Prey rate limiter.",
+			Buckets:   []float64{0, .01, .05, .1, .25, .5, 1, 2.5, 5, 10, 30, 60},
+		}),
+		activeSessions: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "active_sessions",
+			Help:      "MCP sessions currently registered.",
+		}),
+	}
+	m.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		m.toolCalls, m.toolDuration, m.upstreamRequests, m.upstreamDuration, m.rateLimitWait, m.activeSessions,
+	)
+	return m
+}
+
+// Handler serves the metrics in the Prometheus exposition format.
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{Registry: m.registry})
+}
+
+// Middleware records tool calls and the upstream requests they make.
+func (m *Metrics) Middleware(next server.ToolHandlerFunc) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		start := time.Now()
+		result, err := next(prey.WithRequestObserver(ctx, m.ObserveUpstream), request)
+		outcome := outcomeOK
+		switch {
+		case err != nil:
+			outcome = outcomeError
+		case result != nil && result.IsError:
+			outcome = outcomeToolError
+		}
+		m.toolCalls.WithLabelValues(request.Params.Name, outcome).Inc()
+		m.toolDuration.WithLabelValues(request.Params.Name).Observe(time.Since(start).Seconds())
+		return result, err
+	}
+}
+
+// ObserveUpstream is a prey.RequestObserver recording one upstream request.
+func (m *Metrics) ObserveUpstream(info prey.RequestInfo) {
+	method := info.Request.Method
+	m.upstreamRequests.WithLabelValues(method, info.Route, strconv.Itoa(info.Status)).Inc()
+	m.upstreamDuration.WithLabelValues(method, info.Route).Observe(info.Elapsed.Seconds())
+	m.rateLimitWait.Observe(info.RateLimitWait.Seconds())
+}
+
+// AddHooks tracks active sessions.
+func (m *Metrics) AddHooks(hooks *server.Hooks) {
+	hooks.AddOnRegisterSession(func(context.Context, server.ClientSession) {
+		m.activeSessions.Inc()
+	})
+	hooks.AddOnUnregisterSession(func(context.Context, server.ClientSession) {
+		m.activeSessions.Dec()
+	})
+}
@@ -0,0 +1,61 @@
+package metrics
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+
+	"mcp-prey/prey"
+)
+
+func TestMiddlewareRecordsToolAndUpstream(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer srv.Close()
+
+	client := prey.NewClient(prey.Config{URL: srv.URL, APIKey: "key", Timeout: time.Second, DisableRateLimit: true})
+	req := mcp.CallToolRequest{}
+	req.Params.Name = "prey.devices.get"
+
+	m := New()
+	handler := m.Middleware(func(ctx context.Context, _ mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		r, _ := client.NewRequest(http.MethodGet, "/devices/42", nil, nil)
+		if err := client.DoJSON(r.WithContext(ctx), nil); err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+		return mcp.NewToolResultText("ok"), nil
+	})
+	if _, err := handler(context.Background(), req); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	body := scrape(t, m)
+	for _, want := range []string{
+		`mcp_prey_tool_calls_total{outcome="tool_error",tool="prey.devices.get"} 1`,
+		`mcp_prey_upstream_requests_total{endpoint="/devices/{id}",method="GET",status="404"} 1`,
+		`mcp_prey_ratelimit_wait_seconds_count 1`,
+		`mcp_prey_tool_call_duration_seconds_count{tool="prey.devices.get"} 1`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Fatalf("expected %q in metrics output:\n%s", want, body)
+		}
+	}
+}
+
+func scrape(t *testing.T, m *Metrics) string {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	m.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	b, err := io.ReadAll(rec.Body)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return string(b)
+}
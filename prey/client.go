@@ -8,6 +8,9 @@ import (
 	"net/http"
 	"net/url"
 	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
 
 	"mcp-prey/internal"
 )
@@ -30,24 +33,60 @@ func NewClient(cfg Config) *Client {
 	}
 }
 
-func limiterFromConfig(cfg Config) *internal.MultiLimiter {
-	if cfg.DisableRateLimit {
-		return nil
-	}
-	return internal.NewPreyLimiter()
-}
-
 func (c *Client) do(req *http.Request) (*http.Response, error) {
+	if c.BaseURL == "" {
+		return nil, ErrUpstreamNotAllowed
+	}
+	// Dry runs capture writes without sending them, so they need no API key.
+	if captured, err := c.capture(req); captured || err != nil {
+		if err != nil {
+			return nil, err
+		}
+		return nil, ErrDryRun
+	}
 	if c.APIKey == "" {
 		return nil, fmt.Errorf("missing PREY_API_KEY")
 	}
+	route := RouteTemplate(c.relativePath(req.URL.Path))
+	req, span := startRequestSpan(req, route)
+	waitStart := time.Now()
 	if c.Limiter != nil {
-		if err := c.Limiter.Wait(req.Context()); err != nil {
+		wait := c.Limiter.Wait
+		if IsWriteMethod(req.Method) {
+			wait = c.Limiter.WaitWrite
+		}
+		if err := wait(req.Context()); err != nil {
+			endRequestSpan(span, 0, err)
 			return nil, err
 		}
 	}
+	wait := time.Since(waitStart)
+	span.SetAttributes(attribute.Int64("prey.ratelimit.wait_ms", wait.Milliseconds()))
 	req.Header.Set("apikey", c.APIKey)
-	return c.Client.Do(req)
+	if id := CorrelationIDFromContext(req.Context()); id != "" {
+		req.Header.Set(correlationHeader, id)
+	}
+	start := time.Now()
+	resp, err := c.Client.Do(req)
+	info := RequestInfo{
+		Request:       req,
+		Route:         route,
+		Err:           err,
+		Elapsed:       time.Since(start),
+		RateLimitWait: wait,
+	}
+	if resp != nil {
+		info.Status = resp.StatusCode
+	}
+	endRequestSpan(span, info.Status, err)
+	notifyObservers(info)
+	return resp, err
+}
+
+// IsWriteMethod reports whether a request changes Prey state and may use the rate
+// limiter's write reserve.
+func IsWriteMethod(method string) bool {
+	return method != http.MethodGet && method != http.MethodHead
 }
 
 func (c *Client) NewRequest(method, path string, q url.Values, body any) (*http.Request, error) {
@@ -103,3 +142,12 @@ func (c *Client) DoRaw(req *http.Request) ([]byte, string, error) {
 	}
 	return b, resp.Header.Get("Content-Type"), nil
 }
+
+// relativePath strips the base URL's path, giving the path passed to NewRequest.
+func (c *Client) relativePath(p string) string {
+	base, err := url.Parse(c.BaseURL)
+	if err != nil {
+		return p
+	}
+	return "/" + strings.TrimLeft(strings.TrimPrefix(p, strings.TrimRight(base.Path, "/")), "/")
+}
@@ -1,78 +1,207 @@
 package prey
 
 import (
+	"fmt"
+	"log/slog"
 	"os"
 	"strconv"
 	"strings"
 	"time"
+
+	"mcp-prey/internal"
 )
 
 const (
 	defaultPreyURL = "https://api.preyproject.com/v1"
+	defaultTimeout = 30 * time.Second
+
+	preyAPIKeyEnvVar         = "PREY_API_KEY"
+	preyAPIBaseEnvVar        = "PREY_API_BASE"
+	preyTimeoutMsEnvVar      = "PREY_TIMEOUT_MS"
+	preyAllowWriteEnvVar     = "PREY_ALLOW_WRITE"
+	preyAllowedToolsEnvVar   = "PREY_ALLOWED_TOOLS"
+	preyDeniedToolsEnvVar    = "PREY_DENIED_TOOLS"
+	preyWriteToolsEnvVar     = "PREY_WRITE_TOOLS"
+	preyDebugEnvVar          = "PREY_DEBUG"
+	preyDisableRateLimit     = "PREY_RATE_LIMIT_DISABLE"
+	preyRateLimitTiersEnvVar = "PREY_RATE_LIMIT_TIERS"
+	preyWriteReserveEnvVar   = "PREY_RATE_LIMIT_WRITE_RESERVE"
+	preyConfirmEnvVar        = "PREY_CONFIRM_POLICY"
+	preyDryRunEnvVar         = "PREY_DRY_RUN"
+	preyLocationEnvVar       = "PREY_LOCATION_PRECISION"
+	preyReadyCacheTTLEnvVar  = "PREY_READY_CACHE_TTL"
+
+	preyMaskKeysEnvVar      = "PREY_MASK_KEYS"
+	preyMaskAllowKeysEnvVar = "PREY_MASK_ALLOW_KEYS"
+	preyMaskPatternsEnvVar  = "PREY_MASK_PATTERNS"
+	preyMaskRegexEnvVar     = "PREY_MASK_REGEX"
+	preyMaskModeEnvVar      = "PREY_MASK_MODE"
+	preyMaskSaltEnvVar      = "PREY_MASK_SALT"
 
-	preyAPIKeyEnvVar       = "PREY_API_KEY"
-	preyAPIBaseEnvVar      = "PREY_API_BASE"
-	preyTimeoutMsEnvVar    = "PREY_TIMEOUT_MS"
-	preyAllowWriteEnvVar   = "PREY_ALLOW_WRITE"
-	preyAllowedToolsEnvVar = "PREY_ALLOWED_TOOLS"
-	preyDebugEnvVar        = "PREY_DEBUG"
-	preyDisableRateLimit   = "PREY_RATE_LIMIT_DISABLE"
+	preyAuditKeyEnvVar = "PREY_AUDIT_KEY"
 
-	preyURLHeader    = "X-Prey-URL"
-	preyAPIKeyHeader = "X-Prey-API-Key"
+	preyUpstreamAllowlistEnvVar = "PREY_UPSTREAM_ALLOWLIST"
+	preyUpstreamAllowHTTPEnvVar = "PREY_UPSTREAM_ALLOW_HTTP"
+
+	preyURLHeader          = "X-Prey-URL"
+	preyAPIKeyHeader       = "X-Prey-API-Key"
+	preyAllowWriteHeader   = "X-Prey-Allow-Write"
+	preyAllowedToolsHeader = "X-Prey-Allowed-Tools"
+	preyProfileHeader      = "X-Prey-Profile"
+	// correlationHeader carries the correlation ID, both from callers and to Prey.
+	correlationHeader = "X-Request-ID"
 )
 
 type Config struct {
+	// Profile is the config file profile the settings came from, if any.
+	Profile                 string
 	Debug                   bool
 	IncludeArgumentsInSpans bool
 	URL                     string
 	APIKey                  string
 	AllowWrite              bool
-	AllowedTools            map[string]struct{}
-	Timeout                 time.Duration
-	DisableRateLimit        bool
+	// AllowedTools is nil when every tool is allowed; an empty set allows none.
+	AllowedTools map[string]struct{}
+	// EnvAllowedTools is PREY_ALLOWED_TOOLS. It limits every profile, including those
+	// selected with X-Prey-Profile, in addition to AllowedTools.
+	EnvAllowedTools map[string]struct{}
+	// RequestedTools comes from X-Prey-Allowed-Tools. When set, a tool must match it as
+	// well as AllowedTools, so a request can only narrow the server allowlist.
+	RequestedTools map[string]struct{}
+	// DeniedTools wins over AllowedTools.
+	DeniedTools map[string]struct{}
+	// WriteTools limits AllowWrite to matching tools; empty grants every write tool.
+	WriteTools       map[string]struct{}
+	Timeout          time.Duration
+	DisableRateLimit bool
+	// RateLimitTiers and RateLimitWriteReserve configure the limiter, see
+	// internal.NewTieredLimiter.
+	RateLimitTiers        []internal.RateTier
+	RateLimitWriteReserve float64
+	Confirm               ConfirmConfig
+	DryRun                bool
+	LocationPrecision     internal.LocationPrecision
 }
 
-func envBool(key string) bool {
+// EnvBool reports whether an environment variable is set to 1, true or yes.
+func EnvBool(key string) bool {
 	val := strings.TrimSpace(strings.ToLower(os.Getenv(key)))
 	return val == "1" || val == "true" || val == "yes"
 }
 
-func allowedToolsFromEnv() map[string]struct{} {
-	val := strings.TrimSpace(os.Getenv(preyAllowedToolsEnvVar))
-	if val == "" {
+// lookupEnvBool is EnvBool that also reports whether the variable is set.
+func lookupEnvBool(key string) (bool, bool) {
+	if strings.TrimSpace(os.Getenv(key)) == "" {
+		return false, false
+	}
+	return EnvBool(key), true
+}
+
+// splitList splits a comma-separated list, dropping surrounding whitespace and empty
+// items.
+func splitList(val string) []string {
+	var out []string
+	for _, item := range strings.Split(val, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			out = append(out, item)
+		}
+	}
+	return out
+}
+
+func parseToolList(val string) map[string]struct{} {
+	if strings.TrimSpace(val) == "" {
 		return nil
 	}
 	set := make(map[string]struct{})
-	for _, t := range strings.Split(val, ",") {
-		name := strings.TrimSpace(t)
-		if name != "" {
-			set[name] = struct{}{}
-		}
+	for _, name := range splitList(val) {
+		set[name] = struct{}{}
 	}
 	return set
 }
 
-func timeoutFromEnv() time.Duration {
-	val := strings.TrimSpace(os.Getenv(preyTimeoutMsEnvVar))
-	if val == "" {
-		return 30 * time.Second
-	}
-	ms, err := strconv.Atoi(val)
+func allowedToolsFromEnv() map[string]struct{} {
+	return parseToolList(os.Getenv(preyAllowedToolsEnvVar))
+}
+
+// timeoutFromEnv reports PREY_TIMEOUT_MS when it is set to a valid value.
+func timeoutFromEnv() (time.Duration, bool) {
+	ms, err := strconv.Atoi(strings.TrimSpace(os.Getenv(preyTimeoutMsEnvVar)))
 	if err != nil || ms <= 0 {
-		return 30 * time.Second
+		return 0, false
 	}
-	return time.Duration(ms) * time.Millisecond
+	return time.Duration(ms) * time.Millisecond, true
 }
 
-func baseURLFromEnv() string {
-	u := strings.TrimRight(os.Getenv(preyAPIBaseEnvVar), "/")
-	if u == "" {
-		return defaultPreyURL
+// rateTiersFromEnv reports PREY_RATE_LIMIT_TIERS when it is set to a valid value.
+func rateTiersFromEnv() ([]internal.RateTier, bool) {
+	val := strings.TrimSpace(os.Getenv(preyRateLimitTiersEnvVar))
+	if val == "" {
+		return nil, false
 	}
-	return u
+	tiers, err := internal.ParseRateTiers(val)
+	return tiers, err == nil
+}
+
+// writeReserveFromEnv reports PREY_RATE_LIMIT_WRITE_RESERVE when it is set to a valid value.
+func writeReserveFromEnv() (float64, bool) {
+	share, err := strconv.ParseFloat(strings.TrimSpace(os.Getenv(preyWriteReserveEnvVar)), 64)
+	if err != nil || share < 0 || share >= 1 {
+		return 0, false
+	}
+	return share, true
 }
 
 func apiKeyFromEnv() string {
-	return strings.TrimSpace(os.Getenv(preyAPIKeyEnvVar))
+	return secretEnv(preyAPIKeyEnvVar)
+}
+
+// AuditKeyFromEnv returns the key of the audit log's hash chain from PREY_AUDIT_KEY or
+// PREY_AUDIT_KEY_FILE, or nil when neither is set. Callers read it once: a key that
+// changes mid-log would break the chain.
+func AuditKeyFromEnv() []byte {
+	if key := secretEnv(preyAuditKeyEnvVar); key != "" {
+		return []byte(key)
+	}
+	return nil
+}
+
+// fallbackConfirmConfig asks before every write should an invalid PREY_CONFIRM_POLICY
+// get past the startup check, which rejects it.
+var fallbackConfirmConfig = ConfirmConfig{Default: ConfirmAlways}
+
+// ConfirmConfigFromEnv parses PREY_CONFIRM_POLICY.
+func ConfirmConfigFromEnv() (ConfirmConfig, error) {
+	cfg, err := ParseConfirmConfig(os.Getenv(preyConfirmEnvVar))
+	if err != nil {
+		return ConfirmConfig{}, fmt.Errorf("%s: %w", preyConfirmEnvVar, err)
+	}
+	return cfg, nil
+}
+
+func confirmConfigFromEnv() ConfirmConfig {
+	cfg, err := ConfirmConfigFromEnv()
+	if err != nil {
+		slog.Error("invalid confirmation policy, confirming every write", "error", err)
+		return fallbackConfirmConfig
+	}
+	return cfg
+}
+
+// fallbackLocationPrecision (about 5km) guards against exposing exact coordinates should
+// an invalid PREY_LOCATION_PRECISION get past the startup check, which rejects it.
+var fallbackLocationPrecision = internal.LocationPrecision{Mode: internal.LocationGeohash, Digits: 5}
+
+// LocationPrecisionFromEnv parses PREY_LOCATION_PRECISION.
+func LocationPrecisionFromEnv() (internal.LocationPrecision, error) {
+	return internal.ParseLocationPrecision(os.Getenv(preyLocationEnvVar))
+}
+
+func locationPrecisionFromEnv() internal.LocationPrecision {
+	p, err := LocationPrecisionFromEnv()
+	if err != nil {
+		slog.Error("invalid location precision, using fallback", "error", err, "fallback", fallbackLocationPrecision.String())
+		return fallbackLocationPrecision
+	}
+	return p
 }
@@ -0,0 +1,110 @@
+package prey
+
+import (
+	"context"
+	"net/http"
+	"testing"
+)
+
+// narrowed returns the config of a request sending header as X-Prey-Allowed-Tools to a
+// server with the given allowlist.
+func narrowed(allowed map[string]struct{}, header string) Config {
+	return Config{AllowedTools: allowed, RequestedTools: parseToolList(header)}
+}
+
+func TestNarrowAllowedTools(t *testing.T) {
+	if cfg := narrowed(nil, ""); cfg.RequestedTools != nil || !IsToolAllowed(cfg, "prey.devices.delete") {
+		t.Fatalf("expected no header to leave every tool allowed, got %+v", cfg)
+	}
+	cfg := narrowed(nil, "prey.devices.list, prey.zones.list")
+	if !IsToolAllowed(cfg, "prey.zones.list") || IsToolAllowed(cfg, "prey.devices.delete") {
+		t.Fatalf("expected header list to apply, got %+v", cfg)
+	}
+	allowed := map[string]struct{}{"prey.devices.list": {}}
+	cfg = narrowed(allowed, "prey.devices.list,prey.devices.delete")
+	if IsToolAllowed(cfg, "prey.devices.delete") {
+		t.Fatalf("header must not add tools outside the server allowlist")
+	}
+	if !IsToolAllowed(cfg, "prey.devices.list") {
+		t.Fatalf("expected prey.devices.list to stay allowed")
+	}
+}
+
+func TestDisjointHeaderAllowlistAllowsNothing(t *testing.T) {
+	clearProfileEnv(t)
+	t.Setenv(preyAllowedToolsEnvVar, "prey.account.get")
+	req, _ := http.NewRequest(http.MethodPost, "/mcp", nil)
+	req.Header.Set(preyAllowedToolsHeader, "bogus")
+	cfg := ConfigFromContext(ExtractInfoFromHeaders(context.Background(), req))
+	for _, tool := range []string{"prey.account.get", "prey.devices.delete"} {
+		if IsToolAllowed(cfg, tool) {
+			t.Fatalf("expected %s to be refused when the header shares no tools with the allowlist", tool)
+		}
+	}
+	if IsToolAllowed(Config{AllowedTools: map[string]struct{}{}}, "prey.devices.list") {
+		t.Fatalf("expected an empty allowlist to allow nothing")
+	}
+	if !IsToolAllowed(Config{}, "prey.devices.list") {
+		t.Fatalf("expected no allowlist to allow every tool")
+	}
+}
+
+func TestNarrowAllowedToolsPatterns(t *testing.T) {
+	cases := []struct {
+		allowed map[string]struct{}
+		header  string
+		tool    string
+		want    bool
+	}{
+		{map[string]struct{}{"prey.zones.*": {}, "prey.devices.list": {}}, "prey.zones.list,prey.devices.*,prey.labels.*", "prey.zones.list", true},
+		{map[string]struct{}{"prey.zones.*": {}, "prey.devices.list": {}}, "prey.zones.list,prey.devices.*,prey.labels.*", "prey.devices.list", true},
+		{map[string]struct{}{"prey.zones.*": {}, "prey.devices.list": {}}, "prey.zones.list,prey.devices.*,prey.labels.*", "prey.zones.get", false},
+		{map[string]struct{}{"prey.zones.*": {}, "prey.devices.list": {}}, "prey.zones.list,prey.devices.*,prey.labels.*", "prey.devices.delete", false},
+		{map[string]struct{}{"prey.zones.*": {}, "prey.devices.list": {}}, "prey.zones.list,prey.devices.*,prey.labels.*", "prey.labels.list", false},
+		// Pattern against pattern: only tools matching both are allowed.
+		{map[string]struct{}{"prey.devices.*": {}}, "prey.devices.get*", "prey.devices.get", true},
+		{map[string]struct{}{"prey.devices.*": {}}, "prey.devices.get*", "prey.devices.list", false},
+		{map[string]struct{}{"prey.devices.*": {}}, "*.list", "prey.devices.list", true},
+		{map[string]struct{}{"prey.devices.*": {}}, "*.list", "prey.zones.list", false},
+		{map[string]struct{}{"prey.zones.?": {}}, "prey.zones.*", "prey.zones.list", false},
+		{map[string]struct{}{"prey.devices.*": {}}, "prey.zones.*", "prey.devices.list", false},
+	}
+	for _, c := range cases {
+		if got := IsToolAllowed(narrowed(c.allowed, c.header), c.tool); got != c.want {
+			t.Fatalf("allowlist %v, header %q: IsToolAllowed(%s) = %v, expected %v", sortedNames(c.allowed), c.header, c.tool, got, c.want)
+		}
+	}
+}
+
+func TestIsToolAllowed(t *testing.T) {
+	cfg := Config{
+		AllowedTools: map[string]struct{}{"prey.zones.*": {}, "prey.devices.list": {}},
+		DeniedTools:  map[string]struct{}{"prey.zones.delete": {}},
+	}
+	for tool, want := range map[string]bool{
+		"prey.zones.list":     true,
+		"prey.zones.delete":   false,
+		"prey.devices.list":   true,
+		"prey.devices.delete": false,
+	} {
+		if got := IsToolAllowed(cfg, tool); got != want {
+			t.Fatalf("IsToolAllowed(%s) = %v, expected %v", tool, got, want)
+		}
+	}
+	if IsToolAllowed(Config{DeniedTools: map[string]struct{}{"prey.*.delete": {}}}, "prey.devices.delete") {
+		t.Fatalf("expected denylist pattern to apply without an allowlist")
+	}
+}
+
+func TestIsWriteAllowed(t *testing.T) {
+	if IsWriteAllowed(Config{}, "prey.labels.create") {
+		t.Fatalf("expected writes to be disabled by default")
+	}
+	if !IsWriteAllowed(Config{AllowWrite: true}, "prey.devices.delete") {
+		t.Fatalf("expected AllowWrite without grants to allow every write tool")
+	}
+	cfg := Config{AllowWrite: true, WriteTools: map[string]struct{}{"prey.labels.*": {}}}
+	if !IsWriteAllowed(cfg, "prey.labels.create") || IsWriteAllowed(cfg, "prey.devices.delete") {
+		t.Fatalf("expected writes only for granted tools")
+	}
+}
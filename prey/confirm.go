@@ -0,0 +1,81 @@
+package prey
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ConfirmPolicy decides when a write tool must be confirmed by the user through MCP elicitation.
+type ConfirmPolicy string
+
+const (
+	ConfirmAlways          ConfirmPolicy = "always"
+	ConfirmNever           ConfirmPolicy = "never"
+	ConfirmOnlyDestructive ConfirmPolicy = "only-destructive"
+)
+
+// ConfirmConfig holds the default confirmation policy and per-tool overrides.
+type ConfirmConfig struct {
+	Default ConfirmPolicy            `json:"default"`
+	Tools   map[string]ConfirmPolicy `json:"tools,omitempty"`
+}
+
+func parseConfirmPolicy(val string) (ConfirmPolicy, bool) {
+	switch p := ConfirmPolicy(strings.ToLower(strings.TrimSpace(val))); p {
+	case ConfirmAlways, ConfirmNever, ConfirmOnlyDestructive:
+		return p, true
+	default:
+		return "", false
+	}
+}
+
+// ParseConfirmConfig parses a comma-separated list where a bare policy sets the default
+// and tool=policy entries override it, e.g. "only-destructive,prey.zones.update=always".
+// Unknown policies are errors; the entries that did parse are returned along with them.
+func ParseConfirmConfig(val string) (ConfirmConfig, error) {
+	cfg := ConfirmConfig{Default: ConfirmOnlyDestructive}
+	var errs []error
+	for _, entry := range splitList(val) {
+		tool, policy, ok := strings.Cut(entry, "=")
+		if !ok {
+			policy = tool
+		}
+		p, valid := parseConfirmPolicy(policy)
+		if !valid {
+			errs = append(errs, fmt.Errorf("unknown policy %q", strings.TrimSpace(policy)))
+			continue
+		}
+		if !ok {
+			cfg.Default = p
+			continue
+		}
+		tool = strings.TrimSpace(tool)
+		if tool == "" {
+			errs = append(errs, fmt.Errorf("missing tool name in %q", entry))
+			continue
+		}
+		if cfg.Tools == nil {
+			cfg.Tools = make(map[string]ConfirmPolicy)
+		}
+		cfg.Tools[tool] = p
+	}
+	return cfg, errors.Join(errs...)
+}
+
+// RequiresConfirmation reports whether a call to toolName must be confirmed by the user.
+// destructive marks calls that cannot be undone, such as deleting or locking a device.
+func RequiresConfirmation(cfg Config, toolName string, destructive bool) bool {
+	policy := cfg.Confirm.Default
+	if p, ok := cfg.Confirm.Tools[toolName]; ok {
+		policy = p
+	}
+	switch policy {
+	case ConfirmAlways:
+		return true
+	case ConfirmNever:
+		return false
+	default:
+		return destructive
+	}
+}
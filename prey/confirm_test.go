@@ -0,0 +1,61 @@
+package prey
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseConfirmConfig(t *testing.T) {
+	cfg, err := ParseConfirmConfig("")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cfg.Default != ConfirmOnlyDestructive {
+		t.Fatalf("expected only-destructive default, got %s", cfg.Default)
+	}
+	cfg, err = ParseConfirmConfig("never, prey.devices.delete=always")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cfg.Default != ConfirmNever {
+		t.Fatalf("expected never default, got %s", cfg.Default)
+	}
+	if cfg.Tools["prey.devices.delete"] != ConfirmAlways {
+		t.Fatalf("expected always override for prey.devices.delete")
+	}
+	for _, val := range []string{"sometimes", "prey.devices.delete=alwyas", "=always"} {
+		if _, err := ParseConfirmConfig(val); err == nil {
+			t.Fatalf("expected an error for %q", val)
+		}
+	}
+}
+
+func TestConfirmConfigFromEnvFallback(t *testing.T) {
+	t.Setenv(preyConfirmEnvVar, "prey.devices.delete=alwyas")
+	if _, err := ConfirmConfigFromEnv(); err == nil || !strings.Contains(err.Error(), preyConfirmEnvVar) {
+		t.Fatalf("expected a %s error, got %v", preyConfirmEnvVar, err)
+	}
+	if cfg := confirmConfigFromEnv(); !RequiresConfirmation(Config{Confirm: cfg}, "prey.labels.create", false) {
+		t.Fatalf("expected an invalid policy to confirm every write")
+	}
+}
+
+func TestRequiresConfirmation(t *testing.T) {
+	confirm, err := ParseConfirmConfig("prey.labels.create=always,prey.devices.delete=never")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	cfg := Config{Confirm: confirm}
+	if !RequiresConfirmation(cfg, "prey.devices.action.trigger", true) {
+		t.Fatalf("expected destructive call to require confirmation")
+	}
+	if RequiresConfirmation(cfg, "prey.devices.action.trigger", false) {
+		t.Fatalf("expected non-destructive call to skip confirmation")
+	}
+	if !RequiresConfirmation(cfg, "prey.labels.create", false) {
+		t.Fatalf("expected always policy to require confirmation")
+	}
+	if RequiresConfirmation(cfg, "prey.devices.delete", true) {
+		t.Fatalf("expected never policy to skip confirmation")
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"net/http"
 	"strings"
 
+	"github.com/mark3labs/mcp-go/mcp"
 	"github.com/mark3labs/mcp-go/server"
 )
 
@@ -13,6 +14,8 @@ type configKey struct{}
 
 type clientKey struct{}
 
+type pinnedKey struct{}
+
 type httpContextFunc func(ctx context.Context, req *http.Request) context.Context
 
 func WithConfig(ctx context.Context, cfg Config) context.Context {
@@ -40,34 +43,62 @@ func ClientFromContext(ctx context.Context) *Client {
 
 func ExtractInfoFromEnv(ctx context.Context) context.Context {
 	cfg := ConfigFromContext(ctx)
-	cfg.URL = baseURLFromEnv()
-	cfg.APIKey = apiKeyFromEnv()
-	cfg.AllowWrite = envBool(preyAllowWriteEnvVar)
-	cfg.AllowedTools = allowedToolsFromEnv()
-	cfg.Timeout = timeoutFromEnv()
-	cfg.Debug = envBool(preyDebugEnvVar)
-	cfg.DisableRateLimit = envBool(preyDisableRateLimit)
+	if err := resolveSettings(&cfg, ""); err != nil {
+		slog.Warn("failed to resolve Prey settings", "error", err)
+	}
+	applyGlobalSettings(&cfg)
 	return WithConfig(ctx, cfg)
 }
 
 func ExtractInfoFromHeaders(ctx context.Context, req *http.Request) context.Context {
 	cfg := ConfigFromContext(ctx)
-	url := strings.TrimRight(req.Header.Get(preyURLHeader), "/")
-	if url == "" {
-		url = baseURLFromEnv()
+	profile := strings.TrimSpace(req.Header.Get(preyProfileHeader))
+	if err := selectProfile(&cfg, profile); err != nil {
+		// An unknown or unselectable profile fails closed: without a base URL every call is refused.
+		slog.Warn("rejected X-Prey-Profile", "profile", profile, "remote_addr", req.RemoteAddr, "error", err)
+		cfg.URL, cfg.APIKey, cfg.AllowWrite = "", "", false
+	} else {
+		cfg.URL, cfg.APIKey = upstreamFromHeaders(req, cfg.URL, cfg.APIKey)
 	}
+	// Headers may only narrow what the server allows.
+	cfg.AllowWrite = cfg.AllowWrite && !headerFalse(req, preyAllowWriteHeader)
+	cfg.RequestedTools = parseToolList(req.Header.Get(preyAllowedToolsHeader))
+	applyGlobalSettings(&cfg)
+	return WithConfig(ctx, cfg)
+}
+
+// applyGlobalSettings sets the fields that are not per profile, which only come from
+// the environment.
+func applyGlobalSettings(cfg *Config) {
+	cfg.Debug = EnvBool(preyDebugEnvVar)
+	cfg.Confirm = confirmConfigFromEnv()
+	cfg.DryRun = EnvBool(preyDryRunEnvVar)
+	cfg.LocationPrecision = locationPrecisionFromEnv()
+}
+
+// upstreamFromHeaders resolves the Prey base URL and API key for a request, given the
+// profile's. A custom X-Prey-URL must pass the upstream allowlist and never receives
+// the profile's API key; a rejected URL leaves both empty so calls fail with
+// ErrUpstreamNotAllowed.
+func upstreamFromHeaders(req *http.Request, base, baseKey string) (string, string) {
 	apiKey := strings.TrimSpace(req.Header.Get(preyAPIKeyHeader))
-	if apiKey == "" {
-		apiKey = apiKeyFromEnv()
+	url := strings.TrimRight(req.Header.Get(preyURLHeader), "/")
+	if url == "" || url == base {
+		if apiKey == "" {
+			apiKey = baseKey
+		}
+		return base, apiKey
 	}
-	cfg.URL = url
-	cfg.APIKey = apiKey
-	cfg.AllowWrite = envBool(preyAllowWriteEnvVar)
-	cfg.AllowedTools = allowedToolsFromEnv()
-	cfg.Timeout = timeoutFromEnv()
-	cfg.Debug = envBool(preyDebugEnvVar)
-	cfg.DisableRateLimit = envBool(preyDisableRateLimit)
-	return WithConfig(ctx, cfg)
+	if err := upstreamPolicyFromEnv().Check(url); err != nil {
+		slog.Warn("rejected X-Prey-URL", "url", url, "remote_addr", req.RemoteAddr, "error", err)
+		return "", ""
+	}
+	return url, apiKey
+}
+
+func headerFalse(req *http.Request, key string) bool {
+	val := strings.TrimSpace(strings.ToLower(req.Header.Get(key)))
+	return val == "0" || val == "false" || val == "no"
 }
 
 func ExtractClientFromEnv(ctx context.Context) context.Context {
@@ -86,19 +117,29 @@ func ExtractClientFromHeaders(ctx context.Context, _ *http.Request) context.Cont
 	return WithClient(ctx, NewClient(cfg))
 }
 
-func ComposeStdioContextFuncs(funcs ...server.StdioContextFunc) server.StdioContextFunc {
-	return func(ctx context.Context) context.Context {
-		for _, f := range funcs {
-			ctx = f(ctx)
+// PinConfig marks the configuration and client in ctx as chosen for the call, so
+// EnvConfigMiddleware keeps them.
+func PinConfig(ctx context.Context) context.Context {
+	return context.WithValue(ctx, pinnedKey{}, true)
+}
+
+// EnvConfigMiddleware resolves the environment configuration and client again for
+// every tool call. The stdio transport runs its context function once per connection,
+// so without it stdio calls would keep the settings and API key read at startup.
+func EnvConfigMiddleware(next server.ToolHandlerFunc) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		if pinned, _ := ctx.Value(pinnedKey{}).(bool); pinned {
+			return next(ctx, request)
 		}
-		return ctx
+		ctx = ExtractInfoFromEnv(ctx)
+		return next(WithClient(ctx, NewClient(ConfigFromContext(ctx))), request)
 	}
 }
 
-func ComposeSSEContextFuncs(funcs ...httpContextFunc) server.SSEContextFunc {
-	return func(ctx context.Context, req *http.Request) context.Context {
+func ComposeStdioContextFuncs(funcs ...server.StdioContextFunc) server.StdioContextFunc {
+	return func(ctx context.Context) context.Context {
 		for _, f := range funcs {
-			ctx = f(ctx, req)
+			ctx = f(ctx)
 		}
 		return ctx
 	}
@@ -119,17 +160,3 @@ func ComposedStdioContextFunc() server.StdioContextFunc {
 		ExtractClientFromEnv,
 	)
 }
-
-func ComposedSSEContextFunc() server.SSEContextFunc {
-	return ComposeSSEContextFuncs(
-		ExtractInfoFromHeaders,
-		ExtractClientFromHeaders,
-	)
-}
-
-func ComposedHTTPContextFunc() server.HTTPContextFunc {
-	return ComposeHTTPContextFuncs(
-		ExtractInfoFromHeaders,
-		ExtractClientFromHeaders,
-	)
-}
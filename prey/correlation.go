@@ -0,0 +1,53 @@
+package prey
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+)
+
+// maxCorrelationIDLen bounds caller-supplied IDs, which end up in logs and headers.
+const maxCorrelationIDLen = 128
+
+type correlationKey struct{}
+
+// WithCorrelationID sets the ID logged with a tool call and sent to Prey in the
+// X-Request-ID header of every request the call makes.
+func WithCorrelationID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, correlationKey{}, id)
+}
+
+func CorrelationIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(correlationKey{}).(string)
+	return id
+}
+
+// NewCorrelationID returns a random 128-bit ID.
+func NewCorrelationID() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// ExtractCorrelationID keeps the caller's X-Request-ID, so a tool call can be traced
+// from the caller's logs to ours and to Prey. Invalid values are ignored.
+func ExtractCorrelationID(ctx context.Context, req *http.Request) context.Context {
+	id := req.Header.Get(correlationHeader)
+	if !validCorrelationID(id) {
+		return ctx
+	}
+	return WithCorrelationID(ctx, id)
+}
+
+func validCorrelationID(id string) bool {
+	if id == "" || len(id) > maxCorrelationIDLen {
+		return false
+	}
+	for _, r := range id {
+		if r <= ' ' || r > '~' {
+			return false
+		}
+	}
+	return true
+}
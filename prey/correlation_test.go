@@ -0,0 +1,25 @@
+package prey
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestExtractCorrelationID(t *testing.T) {
+	cases := map[string]string{
+		"abc-123":                "abc-123",
+		"":                       "",
+		"has space":              "",
+		strings.Repeat("x", 129): "",
+	}
+	for header, want := range cases {
+		req := httptest.NewRequest(http.MethodPost, "/mcp", nil)
+		req.Header.Set("X-Request-ID", header)
+		if got := CorrelationIDFromContext(ExtractCorrelationID(context.Background(), req)); got != want {
+			t.Fatalf("expected %q for header %q, got %q", want, header, got)
+		}
+	}
+}
@@ -0,0 +1,120 @@
+package prey
+
+import (
+	"os"
+	"strings"
+)
+
+// redactedSecret replaces secrets in ConfigDescription.
+const redactedSecret = "***"
+
+// ProfileDescription is the effective configuration of one profile with the API key
+// redacted.
+type ProfileDescription struct {
+	Name   string `json:"name,omitempty"`
+	Active bool   `json:"active"`
+	// Selectable profiles may be chosen with X-Prey-Profile.
+	Selectable bool `json:"selectable"`
+	// APIKey is "***" when a key is configured and empty otherwise.
+	APIKey       string   `json:"api_key"`
+	APIKeySource string   `json:"api_key_source,omitempty"`
+	BaseURL      string   `json:"base_url"`
+	AllowWrite   bool     `json:"allow_write"`
+	AllowedTools []string `json:"allowed_tools,omitempty"`
+	DeniedTools  []string `json:"denied_tools,omitempty"`
+	WriteTools   []string `json:"write_tools,omitempty"`
+	Timeout      string   `json:"timeout"`
+	RateLimit    bool     `json:"rate_limit"`
+	// RateLimitTiers are LIMIT/PERIOD:BURST entries.
+	RateLimitTiers        []string `json:"rate_limit_tiers,omitempty"`
+	RateLimitWriteReserve float64  `json:"rate_limit_write_reserve"`
+}
+
+// ConfigDescription is the effective configuration, as printed by `config print`.
+type ConfigDescription struct {
+	ConfigFile        string               `json:"config_file,omitempty"`
+	Profiles          []ProfileDescription `json:"profiles"`
+	Debug             bool                 `json:"debug"`
+	DryRun            bool                 `json:"dry_run"`
+	Confirm           ConfirmConfig        `json:"confirm"`
+	LocationPrecision string               `json:"location_precision,omitempty"`
+}
+
+// DescribeConfig resolves every profile, the active one first.
+func DescribeConfig() ConfigDescription {
+	var global Config
+	applyGlobalSettings(&global)
+	d := ConfigDescription{
+		Debug:             global.Debug,
+		DryRun:            global.DryRun,
+		Confirm:           global.Confirm,
+		LocationPrecision: global.LocationPrecision.String(),
+	}
+	names := []string{""}
+	s := profiles.Load()
+	if s != nil {
+		d.ConfigFile = s.file.Path
+		for _, name := range s.file.ProfileNames() {
+			if name != s.active {
+				names = append(names, name)
+			}
+		}
+	}
+	for _, name := range names {
+		var cfg Config
+		if err := resolveSettings(&cfg, name); err != nil {
+			continue
+		}
+		p := ProfileDescription{
+			Name:         cfg.Profile,
+			Active:       name == "",
+			Selectable:   s != nil && s.file.Profiles[cfg.Profile].Selectable,
+			APIKeySource: apiKeySource(s, name),
+			BaseURL:      cfg.URL,
+			AllowWrite:   cfg.AllowWrite,
+			AllowedTools: sortedNames(cfg.AllowedTools),
+			DeniedTools:  sortedNames(cfg.DeniedTools),
+			WriteTools:   sortedNames(cfg.WriteTools),
+			Timeout:      cfg.Timeout.String(),
+			RateLimit:    !cfg.DisableRateLimit,
+		}
+		if p.RateLimit {
+			for _, t := range cfg.RateLimitTiers {
+				p.RateLimitTiers = append(p.RateLimitTiers, t.String())
+			}
+			p.RateLimitWriteReserve = cfg.RateLimitWriteReserve
+		}
+		if cfg.APIKey != "" {
+			p.APIKey = redactedSecret
+		}
+		d.Profiles = append(d.Profiles, p)
+	}
+	return d
+}
+
+// apiKeySource names where a profile's API key comes from, following the
+// precedence of resolveSettings.
+func apiKeySource(s *profileSettings, name string) string {
+	if name == "" {
+		if path := strings.TrimSpace(os.Getenv(preyAPIKeyEnvVar + fileSuffix)); path != "" {
+			return preyAPIKeyEnvVar + fileSuffix + " " + path
+		}
+		if strings.TrimSpace(os.Getenv(preyAPIKeyEnvVar)) != "" {
+			return preyAPIKeyEnvVar
+		}
+	}
+	if s == nil {
+		return ""
+	}
+	if name == "" {
+		name = s.active
+	}
+	p := s.file.Profiles[name]
+	switch {
+	case p.keyFile != nil:
+		return "api_key_file " + p.keyFile.Path()
+	case p.key != "":
+		return "api_key"
+	}
+	return ""
+}
@@ -0,0 +1,79 @@
+package prey
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"sync"
+)
+
+// ErrDryRun is returned for write requests made with a dry-run context.
+var ErrDryRun = errors.New("dry run: request was not sent to Prey")
+
+// DryRunRequest is a write request that was captured instead of sent.
+type DryRunRequest struct {
+	Method string `json:"method"`
+	Path   string `json:"path"`
+	Query  string `json:"query,omitempty"`
+	Body   any    `json:"body,omitempty"`
+}
+
+// DryRunRecorder collects the write requests of a dry-run call.
+type DryRunRecorder struct {
+	mu       sync.Mutex
+	requests []DryRunRequest
+}
+
+type dryRunKey struct{}
+
+// WithDryRun makes clients capture write requests made with the returned context in
+// the recorder instead of sending them. Reads are still sent so targets can be resolved.
+func WithDryRun(ctx context.Context) (context.Context, *DryRunRecorder) {
+	rec := &DryRunRecorder{}
+	return context.WithValue(ctx, dryRunKey{}, rec), rec
+}
+
+// IsDryRun reports whether ctx is a dry-run context.
+func IsDryRun(ctx context.Context) bool {
+	_, ok := ctx.Value(dryRunKey{}).(*DryRunRecorder)
+	return ok
+}
+
+// Requests returns the captured requests.
+func (r *DryRunRecorder) Requests() []DryRunRequest {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]DryRunRequest(nil), r.requests...)
+}
+
+// capture records req if it is a write made with a dry-run context.
+func (c *Client) capture(req *http.Request) (bool, error) {
+	rec, ok := req.Context().Value(dryRunKey{}).(*DryRunRecorder)
+	if !ok || req.Method == http.MethodGet || req.Method == http.MethodHead {
+		return false, nil
+	}
+	captured := DryRunRequest{
+		Method: req.Method,
+		Path:   c.relativePath(req.URL.Path),
+		Query:  req.URL.RawQuery,
+	}
+	if req.Body != nil {
+		b, err := io.ReadAll(req.Body)
+		if err != nil {
+			return true, err
+		}
+		req.Body = io.NopCloser(bytes.NewReader(b))
+		if len(b) > 0 {
+			if err := json.Unmarshal(b, &captured.Body); err != nil {
+				captured.Body = string(b)
+			}
+		}
+	}
+	rec.mu.Lock()
+	rec.requests = append(rec.requests, captured)
+	rec.mu.Unlock()
+	return true, nil
+}
@@ -3,6 +3,10 @@ package prey
 import "errors"
 
 var (
-	ErrMissingClient = errors.New("prey client not available in context")
-	ErrWriteDisabled = errors.New("write operations are disabled (PREY_ALLOW_WRITE=false)")
+	ErrMissingClient   = errors.New("prey client not available in context")
+	ErrWriteDisabled   = errors.New("write operations are disabled (PREY_ALLOW_WRITE=false)")
+	ErrWriteNotGranted = errors.New("write operations are not granted for this tool (PREY_WRITE_TOOLS)")
+	ErrNotConfirmed    = errors.New("operation was not confirmed by the user")
+
+	ErrUpstreamNotAllowed = errors.New("X-Prey-URL was rejected by the upstream allowlist")
 )
@@ -1,10 +1,83 @@
 package prey
 
-// IsToolAllowed returns true when no allowlist is configured or the tool name is present in the allowlist.
+import (
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// IsToolAllowed returns true when the tool matches no entry of the denylist and the
+// allowlist, the environment allowlist and the per-request list each either are unset
+// (nil) or have an entry the tool matches. An empty, non-nil list allows nothing.
+// Entries are tool names or path.Match patterns such as prey.zones.*.
 func IsToolAllowed(cfg Config, toolName string) bool {
-	if len(cfg.AllowedTools) == 0 {
+	if matchTool(cfg.DeniedTools, toolName) {
+		return false
+	}
+	return allowedBy(cfg.AllowedTools, toolName) && allowedBy(cfg.EnvAllowedTools, toolName) &&
+		allowedBy(cfg.RequestedTools, toolName)
+}
+
+func allowedBy(list map[string]struct{}, toolName string) bool {
+	return list == nil || matchTool(list, toolName)
+}
+
+// IsWriteAllowed reports whether writes are enabled for the tool: AllowWrite is set and
+// either no write grants are configured or the tool matches one.
+func IsWriteAllowed(cfg Config, toolName string) bool {
+	return cfg.AllowWrite && (len(cfg.WriteTools) == 0 || matchTool(cfg.WriteTools, toolName))
+}
+
+// isToolPattern reports whether a tool list entry is a pattern rather than a name.
+func isToolPattern(entry string) bool {
+	return strings.ContainsAny(entry, `*?[\`)
+}
+
+// matchTool reports whether toolName is in set or matches one of its patterns.
+// Invalid patterns match nothing.
+func matchTool(set map[string]struct{}, toolName string) bool {
+	if _, ok := set[toolName]; ok {
 		return true
 	}
-	_, ok := cfg.AllowedTools[toolName]
-	return ok
+	for entry := range set {
+		if !isToolPattern(entry) {
+			continue
+		}
+		if ok, err := path.Match(entry, toolName); err == nil && ok {
+			return true
+		}
+	}
+	return false
+}
+
+// ToolAccessKey summarises the configuration fields that decide which tools can be
+// called, so callers can detect when that set changes.
+func ToolAccessKey(cfg Config) string {
+	return strings.Join([]string{
+		strconv.FormatBool(cfg.AllowWrite),
+		strconv.FormatBool(cfg.DryRun),
+		toolListKey(cfg.AllowedTools),
+		toolListKey(cfg.EnvAllowedTools),
+		toolListKey(cfg.RequestedTools),
+		strings.Join(sortedNames(cfg.DeniedTools), ","),
+		strings.Join(sortedNames(cfg.WriteTools), ","),
+	}, "|")
+}
+
+// toolListKey tells an unset list (every tool) from an empty one (no tool).
+func toolListKey(list map[string]struct{}) string {
+	if list == nil {
+		return "*"
+	}
+	return "[" + strings.Join(sortedNames(list), ",") + "]"
+}
+
+func sortedNames[V any](set map[string]V) []string {
+	names := make([]string, 0, len(set))
+	for name := range set {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
 }
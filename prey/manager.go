@@ -0,0 +1,137 @@
+package prey
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"mcp-prey/internal"
+)
+
+// configPollInterval is how often ConfigManager.Watch checks the config file.
+const configPollInterval = 2 * time.Second
+
+// ConfigManager loads the config file and reloads it, along with the secret files,
+// on request (e.g. SIGHUP) or when the file changes. A reload swaps the settings
+// atomically; one that fails validation keeps the current settings. Sessions are not
+// affected beyond seeing the new settings on their next request.
+type ConfigManager struct {
+	path    string
+	profile string
+
+	mu        sync.Mutex
+	info      os.FileInfo
+	listeners []func(toolsChanged bool)
+}
+
+// NewConfigManager returns a manager for the config file at path ("" for none) and
+// the profile selected by flag or environment.
+func NewConfigManager(path, profile string) *ConfigManager {
+	return &ConfigManager{path: path, profile: profile}
+}
+
+// Load installs the config file without notifying listeners.
+func (m *ConfigManager) Load() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.loadLocked()
+}
+
+func (m *ConfigManager) loadLocked() error {
+	if m.path == "" {
+		return SetConfigFile(nil, m.profile)
+	}
+	info, err := os.Stat(m.path)
+	if err != nil {
+		return fmt.Errorf("read config file: %w", err)
+	}
+	f, err := LoadConfigFile(m.path)
+	if err != nil {
+		return err
+	}
+	if err := SetConfigFile(f, m.profile); err != nil {
+		return err
+	}
+	m.info = info
+	return nil
+}
+
+// OnReload registers fn to run after each reload, including partly failed ones.
+// toolsChanged reports whether the tools any profile may call changed.
+func (m *ConfigManager) OnReload(fn func(toolsChanged bool)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.listeners = append(m.listeners, fn)
+}
+
+// Reload re-reads the config file and the secret files.
+func (m *ConfigManager) Reload() error {
+	m.mu.Lock()
+	before := toolAccessKeys()
+	err := errors.Join(m.loadLocked(), reloadSecrets())
+	changed := toolAccessKeys() != before
+	listeners := append([]func(bool){}, m.listeners...)
+	m.mu.Unlock()
+	if err == nil {
+		slog.Info("configuration reloaded", "profile", ActiveProfile(), "tools_changed", changed)
+	}
+	for _, fn := range listeners {
+		fn(changed)
+	}
+	return err
+}
+
+// Watch reloads the configuration whenever the config file changes, until ctx is done.
+func (m *ConfigManager) Watch(ctx context.Context) {
+	if m.path == "" {
+		return
+	}
+	ticker := time.NewTicker(configPollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		info, err := os.Stat(m.path)
+		m.mu.Lock()
+		modified := err == nil && internal.FileChanged(m.info, info)
+		m.mu.Unlock()
+		if !modified {
+			continue
+		}
+		if err := m.Reload(); err != nil {
+			slog.Error("failed to reload configuration, keeping the current one", "error", err)
+			// Do not retry the same broken file on every tick.
+			m.mu.Lock()
+			m.info = info
+			m.mu.Unlock()
+		}
+	}
+}
+
+// toolAccessKeys summarises the tool access of the active profile and of every
+// profile selectable per request.
+func toolAccessKeys() string {
+	names := []string{""}
+	if s := profiles.Load(); s != nil {
+		names = append(names, s.file.ProfileNames()...)
+	}
+	sort.Strings(names)
+	keys := make([]string, 0, len(names))
+	for _, name := range names {
+		var cfg Config
+		if err := resolveSettings(&cfg, name); err != nil {
+			continue
+		}
+		keys = append(keys, name+"="+ToolAccessKey(cfg))
+	}
+	return strings.Join(keys, ";")
+}
@@ -0,0 +1,126 @@
+package prey
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+func writeConfig(t *testing.T, path, doc string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(doc), 0o600); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestConfigManagerReload(t *testing.T) {
+	clearProfileEnv(t)
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	writeConfig(t, path, "profiles:\n  default:\n    api_key: k\n    allowed_tools: [prey.devices.list]\n")
+	m := NewConfigManager(path, "")
+	if err := m.Load(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	t.Cleanup(func() { _ = SetConfigFile(nil, "") })
+
+	var notified []bool
+	m.OnReload(func(toolsChanged bool) { notified = append(notified, toolsChanged) })
+
+	writeConfig(t, path, "profiles:\n  default:\n    api_key: k\n    allowed_tools: [prey.devices.list]\n    timeout: 5s\n")
+	if err := m.Reload(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	cfg := ConfigFromContext(ExtractInfoFromEnv(context.Background()))
+	if cfg.Timeout != 5*time.Second {
+		t.Fatalf("expected reloaded timeout, got %v", cfg.Timeout)
+	}
+
+	writeConfig(t, path, "profiles:\n  default:\n    api_key: k\n    allowed_tools: [prey.devices.list, prey.zones.list]\n")
+	if err := m.Reload(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(notified) != 2 || notified[0] || !notified[1] {
+		t.Fatalf("expected only the second reload to change tools, got %v", notified)
+	}
+
+	writeConfig(t, path, "profiles:\n  default:\n    timeout: never\n")
+	if err := m.Reload(); err == nil {
+		t.Fatalf("expected error for an invalid file")
+	}
+	cfg = ConfigFromContext(ExtractInfoFromEnv(context.Background()))
+	if !IsToolAllowed(cfg, "prey.zones.list") || cfg.APIKey != "k" {
+		t.Fatalf("expected the previous settings to be kept, got %+v", cfg)
+	}
+}
+
+func TestConfigManagerReloadsSecretFiles(t *testing.T) {
+	clearProfileEnv(t)
+	path := filepath.Join(t.TempDir(), "prey-key")
+	writeConfig(t, path, "old-key")
+	t.Setenv(preyAPIKeyEnvVar+fileSuffix, path)
+	if err := LoadSecretFiles(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	writeConfig(t, path, "new-key-rotated")
+	if err := NewConfigManager("", "").Reload(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := apiKeyFromEnv(); got != "new-key-rotated" {
+		t.Fatalf("expected the rotated key, got %q", got)
+	}
+}
+
+func TestReloadAppliesToStdioToolCalls(t *testing.T) {
+	clearProfileEnv(t)
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	writeConfig(t, path, "profiles:\n  default:\n    api_key: old-key\n")
+	m := NewConfigManager(path, "")
+	if err := m.Load(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	t.Cleanup(func() { _ = SetConfigFile(nil, "") })
+
+	s := server.NewMCPServer("test", "0.0.0",
+		server.WithToolCapabilities(true),
+		server.WithToolHandlerMiddleware(EnvConfigMiddleware),
+	)
+	s.AddTool(mcp.NewTool("whoami"), func(ctx context.Context, _ mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		return mcp.NewToolResultText(ClientFromContext(ctx).APIKey), nil
+	})
+	// Like the stdio transport, build the context once for the whole connection.
+	ctx := ComposedStdioContextFunc()(context.Background())
+	call := func(ctx context.Context) string {
+		msg, _ := json.Marshal(map[string]any{
+			"jsonrpc": "2.0", "id": 1, "method": "tools/call",
+			"params": map[string]any{"name": "whoami"},
+		})
+		resp, ok := s.HandleMessage(ctx, msg).(mcp.JSONRPCResponse)
+		if !ok {
+			t.Fatalf("expected a result")
+		}
+		result := resp.Result.(mcp.CallToolResult)
+		return result.Content[0].(mcp.TextContent).Text
+	}
+	if got := call(ctx); got != "old-key" {
+		t.Fatalf("expected old-key, got %q", got)
+	}
+
+	writeConfig(t, path, "profiles:\n  default:\n    api_key: new-key\n")
+	if err := m.Reload(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := call(ctx); got != "new-key" {
+		t.Fatalf("expected the reloaded key on the next call, got %q", got)
+	}
+
+	pinned := PinConfig(WithClient(ctx, NewClient(Config{APIKey: "pinned-key"})))
+	if got := call(pinned); got != "pinned-key" {
+		t.Fatalf("expected a pinned config to be kept, got %q", got)
+	}
+}
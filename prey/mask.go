@@ -0,0 +1,59 @@
+package prey
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"mcp-prey/internal"
+)
+
+// MaskPolicyFromEnv builds the response masking policy from PREY_MASK_* settings.
+// It returns nil when none are set, keeping the default of redacting secrets only.
+func MaskPolicyFromEnv() (*internal.MaskPolicy, error) {
+	keys := splitList(os.Getenv(preyMaskKeysEnvVar))
+	allow := splitList(os.Getenv(preyMaskAllowKeysEnvVar))
+	patterns := splitList(os.Getenv(preyMaskPatternsEnvVar))
+	custom := strings.TrimSpace(os.Getenv(preyMaskRegexEnvVar))
+	mode := strings.ToLower(strings.TrimSpace(os.Getenv(preyMaskModeEnvVar)))
+	if len(keys) == 0 && len(allow) == 0 && len(patterns) == 0 && custom == "" && mode == "" {
+		return nil, nil
+	}
+
+	p := &internal.MaskPolicy{ExtraKeys: keys, AllowKeys: allow, Mode: internal.MaskModeRedact}
+	switch mode {
+	case "", internal.MaskModeRedact:
+	case internal.MaskModePseudonymize:
+		p.Mode = mode
+		if strings.TrimSpace(os.Getenv(preyMaskSaltEnvVar+fileSuffix)) != "" {
+			// Follow the file, so a rotated salt applies without a restart.
+			p.SaltFunc = func() []byte { return []byte(secretEnv(preyMaskSaltEnvVar)) }
+		} else {
+			p.Salt = []byte(secretEnv(preyMaskSaltEnvVar))
+		}
+	default:
+		return nil, fmt.Errorf("invalid %s: %q (want redact or pseudonymize)", preyMaskModeEnvVar, mode)
+	}
+	for _, name := range patterns {
+		if strings.EqualFold(name, "all") {
+			for _, all := range []string{"email", "phone", "mac", "serial", "ip"} {
+				p.Rules = append(p.Rules, internal.BuiltinMaskRules[all])
+			}
+			continue
+		}
+		rule, ok := internal.BuiltinMaskRules[strings.ToLower(name)]
+		if !ok {
+			return nil, fmt.Errorf("invalid %s: unknown pattern %q", preyMaskPatternsEnvVar, name)
+		}
+		p.Rules = append(p.Rules, rule)
+	}
+	if custom != "" {
+		re, err := regexp.Compile(custom)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s: %w", preyMaskRegexEnvVar, err)
+		}
+		p.Rules = append(p.Rules, internal.MaskRule{Name: "custom", Pattern: re})
+	}
+	return p, nil
+}
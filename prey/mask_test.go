@@ -0,0 +1,55 @@
+package prey
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"mcp-prey/internal"
+)
+
+func TestMaskPolicyFromEnv(t *testing.T) {
+	if p, err := MaskPolicyFromEnv(); err != nil || p != nil {
+		t.Fatalf("expected no policy without settings, got %v %v", p, err)
+	}
+	t.Setenv(preyMaskPatternsEnvVar, "email, ip")
+	t.Setenv(preyMaskRegexEnvVar, `EMP-\d+`)
+	t.Setenv(preyMaskModeEnvVar, "pseudonymize")
+	p, err := MaskPolicyFromEnv()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if p.Mode != internal.MaskModePseudonymize || len(p.Rules) != 3 {
+		t.Fatalf("unexpected policy: %+v", p)
+	}
+	t.Setenv(preyMaskPatternsEnvVar, "ssn")
+	if _, err := MaskPolicyFromEnv(); err == nil {
+		t.Fatalf("expected error for unknown pattern")
+	}
+}
+
+func TestMaskSaltFileFollowsChanges(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "salt")
+	if err := os.WriteFile(path, []byte("first"), 0o600); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	t.Setenv(preyMaskPatternsEnvVar, "email")
+	t.Setenv(preyMaskModeEnvVar, "pseudonymize")
+	t.Setenv(preyMaskSaltEnvVar, "")
+	t.Setenv(preyMaskSaltEnvVar+fileSuffix, path)
+	p, err := MaskPolicyFromEnv()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	before := p.Mask("ana@example.com")
+
+	if err := os.WriteFile(path, []byte("second"), 0o600); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := reloadSecrets(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if after := p.Mask("ana@example.com"); after == before {
+		t.Fatalf("expected the new salt to change the pseudonym, got %v twice", after)
+	}
+}
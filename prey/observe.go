@@ -0,0 +1,52 @@
+package prey
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// RequestInfo describes a finished upstream Prey request.
+type RequestInfo struct {
+	Request *http.Request
+	// Route is the request path relative to the base URL with IDs replaced by {id}.
+	Route string
+	// Status is zero when no response was received.
+	Status  int
+	Err     error
+	Elapsed time.Duration
+	// RateLimitWait is how long the request waited for the rate limiter.
+	RateLimitWait time.Duration
+}
+
+// RequestObserver is told about every upstream Prey request made with a context.
+type RequestObserver func(info RequestInfo)
+
+type observersKey struct{}
+
+// WithRequestObserver adds obs to the observers notified for requests made with ctx.
+func WithRequestObserver(ctx context.Context, obs RequestObserver) context.Context {
+	existing, _ := ctx.Value(observersKey{}).([]RequestObserver)
+	observers := make([]RequestObserver, 0, len(existing)+1)
+	observers = append(observers, existing...)
+	observers = append(observers, obs)
+	return context.WithValue(ctx, observersKey{}, observers)
+}
+
+func notifyObservers(info RequestInfo) {
+	observers, _ := info.Request.Context().Value(observersKey{}).([]RequestObserver)
+	for _, obs := range observers {
+		obs(info)
+	}
+}
+
+// RouteTemplate turns an API path such as /devices/123/reports/456 into
+// /devices/{id}/reports/{id}. Prey paths alternate collection names and IDs.
+func RouteTemplate(path string) string {
+	segments := strings.Split(strings.Trim(path, "/"), "/")
+	for i := 1; i < len(segments); i += 2 {
+		segments[i] = "{id}"
+	}
+	return "/" + strings.Join(segments, "/")
+}
@@ -0,0 +1,388 @@
+package prey
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+
+	"mcp-prey/internal"
+)
+
+// defaultProfileName is used when the config file names no default profile.
+const defaultProfileName = "default"
+
+// Profile is a named set of Prey settings in the config file. Unset fields keep the
+// built-in defaults.
+type Profile struct {
+	BaseURL string `yaml:"base_url" toml:"base_url" json:"base_url,omitempty"`
+	APIKey  string `yaml:"api_key" toml:"api_key" json:"api_key,omitempty"`
+	// APIKeyFile is read instead of APIKey, and re-read when it changes. A relative
+	// path is resolved against the config file's directory.
+	APIKeyFile   string   `yaml:"api_key_file" toml:"api_key_file" json:"api_key_file,omitempty"`
+	AllowWrite   *bool    `yaml:"allow_write" toml:"allow_write" json:"allow_write,omitempty"`
+	AllowedTools []string `yaml:"allowed_tools" toml:"allowed_tools" json:"allowed_tools,omitempty"`
+	DeniedTools  []string `yaml:"denied_tools" toml:"denied_tools" json:"denied_tools,omitempty"`
+	// WriteTools limits writes to the matching tools; AllowWrite must still enable them.
+	WriteTools []string         `yaml:"write_tools" toml:"write_tools" json:"write_tools,omitempty"`
+	Timeout    string           `yaml:"timeout" toml:"timeout" json:"timeout,omitempty"`
+	RateLimit  ProfileRateLimit `yaml:"rate_limit" toml:"rate_limit" json:"rate_limit,omitempty"`
+	// Selectable lets callers choose the profile with X-Prey-Profile. The active profile
+	// is used without the header and need not be selectable.
+	Selectable bool `yaml:"selectable" toml:"selectable" json:"selectable,omitempty"`
+
+	key     string
+	keyFile *internal.Secret
+	timeout time.Duration
+}
+
+// ProfileRateLimit configures the client-side Prey rate limiter for a profile.
+type ProfileRateLimit struct {
+	Disable *bool `yaml:"disable" toml:"disable" json:"disable,omitempty"`
+	// Tiers are LIMIT/PERIOD[:BURST] entries, as in PREY_RATE_LIMIT_TIERS.
+	Tiers []string `yaml:"tiers" toml:"tiers" json:"tiers,omitempty"`
+	// WriteReserve is the share of every tier only write calls may use.
+	WriteReserve *float64 `yaml:"write_reserve" toml:"write_reserve" json:"write_reserve,omitempty"`
+
+	tiers []internal.RateTier
+}
+
+// ConfigFile is the file passed with --config.
+type ConfigFile struct {
+	Path string `yaml:"-" toml:"-" json:"-"`
+	// DefaultProfile is used unless --profile or PREY_PROFILE selects another.
+	DefaultProfile string             `yaml:"default_profile" toml:"default_profile" json:"default_profile,omitempty"`
+	Profiles       map[string]Profile `yaml:"profiles" toml:"profiles" json:"profiles"`
+}
+
+// LoadConfigFile reads and validates a YAML or TOML config file. The format follows
+// the extension: .toml is TOML, anything else YAML.
+func LoadConfigFile(path string) (*ConfigFile, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read config file: %w", err)
+	}
+	format := "yaml"
+	if strings.EqualFold(filepath.Ext(path), ".toml") {
+		format = "toml"
+	}
+	f, err := ParseConfigFile(b, format, filepath.Dir(path))
+	if err != nil {
+		return nil, fmt.Errorf("config file %s: %w", path, err)
+	}
+	f.Path = path
+	return f, nil
+}
+
+// ParseConfigFile parses a config file in format "yaml" or "toml". Unknown fields are
+// rejected so typos do not silently fall back to defaults. Relative key files are
+// resolved against dir.
+func ParseConfigFile(b []byte, format, dir string) (*ConfigFile, error) {
+	var f ConfigFile
+	switch format {
+	case "toml":
+		md, err := toml.Decode(string(b), &f)
+		if err != nil {
+			return nil, err
+		}
+		if undecoded := md.Undecoded(); len(undecoded) > 0 {
+			return nil, fmt.Errorf("unknown field %q", undecoded[0].String())
+		}
+	case "yaml":
+		dec := yaml.NewDecoder(bytes.NewReader(b))
+		dec.KnownFields(true)
+		if err := dec.Decode(&f); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("unsupported config format %q", format)
+	}
+	if len(f.Profiles) == 0 {
+		return nil, errors.New("no profiles defined")
+	}
+	for name, p := range f.Profiles {
+		if err := p.prepare(dir); err != nil {
+			return nil, fmt.Errorf("profile %s: %w", name, err)
+		}
+		f.Profiles[name] = p
+	}
+	if f.DefaultProfile != "" {
+		if _, ok := f.Profiles[f.DefaultProfile]; !ok {
+			return nil, fmt.Errorf("default_profile %q is not defined", f.DefaultProfile)
+		}
+	}
+	return &f, nil
+}
+
+func (p *Profile) prepare(dir string) error {
+	if p.BaseURL != "" {
+		u, err := url.Parse(p.BaseURL)
+		if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+			return fmt.Errorf("base_url %q is not an absolute http(s) URL", p.BaseURL)
+		}
+		p.BaseURL = strings.TrimRight(p.BaseURL, "/")
+	}
+	if p.Timeout != "" {
+		d, err := time.ParseDuration(p.Timeout)
+		if err != nil || d <= 0 {
+			return fmt.Errorf("timeout %q is not a positive duration", p.Timeout)
+		}
+		p.timeout = d
+	}
+	if len(p.RateLimit.Tiers) > 0 {
+		tiers, err := internal.ParseRateTiers(strings.Join(p.RateLimit.Tiers, ","))
+		if err != nil {
+			return fmt.Errorf("rate_limit: %w", err)
+		}
+		p.RateLimit.tiers = tiers
+	}
+	if r := p.RateLimit.WriteReserve; r != nil && (*r < 0 || *r >= 1) {
+		return fmt.Errorf("rate_limit: write_reserve %v must be at least 0 and below 1", *r)
+	}
+	// Check the tiers and reserve together, as NewTieredLimiter will use them.
+	limits := Config{RateLimitTiers: internal.DefaultPreyTiers, RateLimitWriteReserve: internal.DefaultWriteReserve}
+	p.apply(&limits)
+	if err := checkRateLimit(limits); err != nil {
+		return fmt.Errorf("rate_limit: %w", err)
+	}
+	switch {
+	case p.APIKey != "" && p.APIKeyFile != "":
+		return errors.New("api_key and api_key_file are mutually exclusive")
+	case p.APIKeyFile != "":
+		path := p.APIKeyFile
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(dir, path)
+		}
+		secret, err := internal.OpenSecret(path)
+		if err != nil {
+			return fmt.Errorf("api_key_file: %w", err)
+		}
+		p.keyFile = secret
+	default:
+		p.key = strings.TrimSpace(p.APIKey)
+	}
+	return nil
+}
+
+// apply sets the fields the profile defines.
+func (p *Profile) apply(cfg *Config) {
+	if p.BaseURL != "" {
+		cfg.URL = p.BaseURL
+	}
+	if p.keyFile != nil {
+		cfg.APIKey = p.keyFile.Value()
+	} else if p.key != "" {
+		cfg.APIKey = p.key
+	}
+	if len(p.WriteTools) > 0 {
+		cfg.WriteTools = parseToolList(strings.Join(p.WriteTools, ","))
+	}
+	if p.AllowWrite != nil {
+		cfg.AllowWrite = *p.AllowWrite
+	}
+	if len(p.AllowedTools) > 0 {
+		cfg.AllowedTools = parseToolList(strings.Join(p.AllowedTools, ","))
+	}
+	if len(p.DeniedTools) > 0 {
+		cfg.DeniedTools = parseToolList(strings.Join(p.DeniedTools, ","))
+	}
+	if p.timeout > 0 {
+		cfg.Timeout = p.timeout
+	}
+	if p.RateLimit.Disable != nil {
+		cfg.DisableRateLimit = *p.RateLimit.Disable
+	}
+	if p.RateLimit.tiers != nil {
+		cfg.RateLimitTiers = p.RateLimit.tiers
+	}
+	if p.RateLimit.WriteReserve != nil {
+		cfg.RateLimitWriteReserve = *p.RateLimit.WriteReserve
+	}
+}
+
+// ProfileNames returns the file's profile names, sorted.
+func (f *ConfigFile) ProfileNames() []string {
+	names := make([]string, 0, len(f.Profiles))
+	for name := range f.Profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+type profileSettings struct {
+	file   *ConfigFile
+	active string
+}
+
+var profiles atomic.Pointer[profileSettings]
+
+// SetConfigFile installs the config file and selects its active profile: name when
+// given, else the file's default_profile, else a profile called "default", else the
+// only profile. A nil file clears it; name then must be empty.
+func SetConfigFile(f *ConfigFile, name string) error {
+	if f == nil {
+		if name != "" {
+			return fmt.Errorf("profile %q selected without a config file", name)
+		}
+		profiles.Store(nil)
+		return nil
+	}
+	if name == "" {
+		name = f.DefaultProfile
+	}
+	if name == "" {
+		if _, ok := f.Profiles[defaultProfileName]; ok {
+			name = defaultProfileName
+		} else if len(f.Profiles) == 1 {
+			name = f.ProfileNames()[0]
+		}
+	}
+	if name == "" {
+		return fmt.Errorf("config file defines several profiles: select one with --profile or default_profile")
+	}
+	p, ok := f.Profiles[name]
+	if !ok {
+		return fmt.Errorf("profile %q is not defined in %s", name, f.Path)
+	}
+	// The environment overrides the active profile's rate limit, which must still fit
+	// together; other profiles were checked when the file was loaded.
+	limits := Config{RateLimitTiers: internal.DefaultPreyTiers, RateLimitWriteReserve: internal.DefaultWriteReserve}
+	p.apply(&limits)
+	applyEnvSettings(&limits)
+	if err := checkRateLimit(limits); err != nil {
+		return fmt.Errorf("profile %s: rate limit: %w", name, err)
+	}
+	profiles.Store(&profileSettings{file: f, active: name})
+	return nil
+}
+
+// ActiveProfile returns the profile selected at startup, or "" without a config file.
+func ActiveProfile() string {
+	if s := profiles.Load(); s != nil {
+		return s.active
+	}
+	return ""
+}
+
+// ProfileConfig returns the configuration a request selecting profile name ("" for the
+// active profile) gets without other headers.
+func ProfileConfig(name string) (Config, error) {
+	var cfg Config
+	if err := resolveSettings(&cfg, name); err != nil {
+		return Config{}, err
+	}
+	applyGlobalSettings(&cfg)
+	return cfg, nil
+}
+
+// selectProfile resolves the settings of the profile a caller names in X-Prey-Profile.
+// Only the active profile and profiles marked selectable may be chosen.
+func selectProfile(cfg *Config, name string) error {
+	if s := profiles.Load(); s != nil && name != "" && name != s.active {
+		if p, ok := s.file.Profiles[name]; ok && !p.Selectable {
+			return fmt.Errorf("profile %q is not selectable", name)
+		}
+	}
+	return resolveSettings(cfg, name)
+}
+
+// resolveSettings sets the per-profile fields of cfg for profile name ("" for the
+// active profile). Precedence, lowest first: built-in defaults, the config file
+// profile, then environment variables. Environment variables only override the
+// active profile, so a profile selected per request never receives PREY_API_KEY or
+// another profile's settings. The environment's restrictions apply to every profile.
+func resolveSettings(cfg *Config, name string) error {
+	cfg.URL = defaultPreyURL
+	cfg.APIKey = ""
+	cfg.AllowWrite = false
+	cfg.AllowedTools = nil
+	cfg.EnvAllowedTools = nil
+	cfg.DeniedTools = nil
+	cfg.WriteTools = nil
+	cfg.Timeout = defaultTimeout
+	cfg.DisableRateLimit = false
+	cfg.RateLimitTiers = internal.DefaultPreyTiers
+	cfg.RateLimitWriteReserve = internal.DefaultWriteReserve
+	cfg.Profile = ""
+
+	s := profiles.Load()
+	useEnv := name == "" || (s != nil && name == s.active)
+	if s != nil {
+		if name == "" {
+			name = s.active
+		}
+		p, ok := s.file.Profiles[name]
+		if !ok {
+			return fmt.Errorf("unknown profile %q", name)
+		}
+		p.apply(cfg)
+		cfg.Profile = name
+	} else if name != "" {
+		return fmt.Errorf("unknown profile %q: no config file is loaded", name)
+	}
+	if useEnv {
+		applyEnvSettings(cfg)
+	}
+	applyEnvLimits(cfg)
+	return nil
+}
+
+// applyEnvLimits applies the restrictions of the environment to any profile:
+// PREY_ALLOW_WRITE=false disables writes, PREY_DENIED_TOOLS adds to the denylist and
+// PREY_ALLOWED_TOOLS limits the tools the profile allows.
+func applyEnvLimits(cfg *Config) {
+	if v, ok := lookupEnvBool(preyAllowWriteEnvVar); ok && !v {
+		cfg.AllowWrite = false
+	}
+	if tools := parseToolList(os.Getenv(preyDeniedToolsEnvVar)); tools != nil {
+		denied := make(map[string]struct{}, len(cfg.DeniedTools)+len(tools))
+		for _, set := range []map[string]struct{}{cfg.DeniedTools, tools} {
+			for tool := range set {
+				denied[tool] = struct{}{}
+			}
+		}
+		cfg.DeniedTools = denied
+	}
+	cfg.EnvAllowedTools = allowedToolsFromEnv()
+}
+
+// applyEnvSettings overrides cfg with the per-profile environment variables that are set.
+func applyEnvSettings(cfg *Config) {
+	if u := strings.TrimRight(os.Getenv(preyAPIBaseEnvVar), "/"); u != "" {
+		cfg.URL = u
+	}
+	if key := apiKeyFromEnv(); key != "" {
+		cfg.APIKey = key
+	}
+	if tools := parseToolList(os.Getenv(preyWriteToolsEnvVar)); tools != nil {
+		cfg.WriteTools = tools
+	}
+	if v, ok := lookupEnvBool(preyAllowWriteEnvVar); ok {
+		cfg.AllowWrite = v
+	}
+	if tools := allowedToolsFromEnv(); tools != nil {
+		cfg.AllowedTools = tools
+	}
+	if d, ok := timeoutFromEnv(); ok {
+		cfg.Timeout = d
+	}
+	if v, ok := lookupEnvBool(preyDisableRateLimit); ok {
+		cfg.DisableRateLimit = v
+	}
+	if tiers, ok := rateTiersFromEnv(); ok {
+		cfg.RateLimitTiers = tiers
+	}
+	if share, ok := writeReserveFromEnv(); ok {
+		cfg.RateLimitWriteReserve = share
+	}
+}
@@ -0,0 +1,284 @@
+package prey
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+const testConfigYAML = `
+default_profile: acme
+profiles:
+  acme:
+    base_url: https://api.preyproject.com/v1/
+    api_key_file: acme.key
+    allow_write: true
+    allowed_tools: [prey.devices.list, prey.devices.get]
+    timeout: 10s
+  globex:
+    base_url: https://globex.example.com/v1
+    api_key: globex-key
+    selectable: true
+    rate_limit:
+      disable: true
+`
+
+func loadTestConfig(t *testing.T) *ConfigFile {
+	t.Helper()
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "acme.key"), []byte("acme-key\n"), 0o600); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	path := filepath.Join(dir, "config.yaml")
+	if err := os.WriteFile(path, []byte(testConfigYAML), 0o600); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	f, err := LoadConfigFile(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return f
+}
+
+func useConfigFile(t *testing.T, f *ConfigFile, profile string) {
+	t.Helper()
+	if err := SetConfigFile(f, profile); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	t.Cleanup(func() { _ = SetConfigFile(nil, "") })
+}
+
+func clearProfileEnv(t *testing.T) {
+	for _, key := range []string{preyAPIBaseEnvVar, preyAPIKeyEnvVar, preyAllowWriteEnvVar, preyAllowedToolsEnvVar, preyDeniedToolsEnvVar, preyWriteToolsEnvVar, preyTimeoutMsEnvVar, preyDisableRateLimit, preyRateLimitTiersEnvVar, preyWriteReserveEnvVar} {
+		t.Setenv(key, "")
+	}
+}
+
+func TestConfigFileProfiles(t *testing.T) {
+	clearProfileEnv(t)
+	useConfigFile(t, loadTestConfig(t), "")
+
+	cfg := ConfigFromContext(ExtractInfoFromEnv(context.Background()))
+	if cfg.Profile != "acme" || cfg.URL != "https://api.preyproject.com/v1" || cfg.APIKey != "acme-key" {
+		t.Fatalf("expected acme profile, got %+v", cfg)
+	}
+	if !cfg.AllowWrite || cfg.Timeout != 10*time.Second || !IsToolAllowed(cfg, "prey.devices.get") || IsToolAllowed(cfg, "prey.zones.list") {
+		t.Fatalf("unexpected acme settings: %+v", cfg)
+	}
+
+	req, _ := http.NewRequest(http.MethodPost, "/mcp", nil)
+	req.Header.Set(preyProfileHeader, "globex")
+	cfg = ConfigFromContext(ExtractInfoFromHeaders(context.Background(), req))
+	if cfg.Profile != "globex" || cfg.URL != "https://globex.example.com/v1" || cfg.APIKey != "globex-key" || !cfg.DisableRateLimit {
+		t.Fatalf("expected globex profile, got %+v", cfg)
+	}
+	if cfg.AllowWrite || cfg.Timeout != defaultTimeout {
+		t.Fatalf("expected defaults for unset globex fields, got %+v", cfg)
+	}
+}
+
+func TestEnvOverridesActiveProfileOnly(t *testing.T) {
+	clearProfileEnv(t)
+	t.Setenv(preyAPIKeyEnvVar, "env-key")
+	t.Setenv(preyAllowWriteEnvVar, "false")
+	t.Setenv(preyTimeoutMsEnvVar, "2000")
+	useConfigFile(t, loadTestConfig(t), "")
+
+	cfg := ConfigFromContext(ExtractInfoFromEnv(context.Background()))
+	if cfg.APIKey != "env-key" || cfg.AllowWrite || cfg.Timeout != 2*time.Second {
+		t.Fatalf("expected env to override the active profile, got %+v", cfg)
+	}
+
+	req, _ := http.NewRequest(http.MethodPost, "/mcp", nil)
+	req.Header.Set(preyProfileHeader, "globex")
+	cfg = ConfigFromContext(ExtractInfoFromHeaders(context.Background(), req))
+	if cfg.APIKey != "globex-key" || cfg.Timeout != defaultTimeout {
+		t.Fatalf("env must not apply to another profile, got %+v", cfg)
+	}
+}
+
+func TestProfileToolGrants(t *testing.T) {
+	clearProfileEnv(t)
+	f, err := ParseConfigFile([]byte(`
+profiles:
+  default:
+    api_key: key
+    denied_tools: [prey.devices.delete]
+    write_tools: [prey.labels.*]
+`), "yaml", "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	useConfigFile(t, f, "")
+
+	cfg := ConfigFromContext(ExtractInfoFromEnv(context.Background()))
+	if cfg.AllowWrite || IsWriteAllowed(cfg, "prey.labels.create") {
+		t.Fatalf("expected write_tools alone not to enable writes, got %+v", cfg)
+	}
+
+	t.Setenv(preyAllowWriteEnvVar, "true")
+	cfg = ConfigFromContext(ExtractInfoFromEnv(context.Background()))
+	if !cfg.AllowWrite || !IsWriteAllowed(cfg, "prey.labels.create") || IsWriteAllowed(cfg, "prey.zones.update") {
+		t.Fatalf("expected write_tools to grant label writes only, got %+v", cfg)
+	}
+	if IsToolAllowed(cfg, "prey.devices.delete") {
+		t.Fatalf("expected denied_tools to apply")
+	}
+
+	t.Setenv(preyAllowWriteEnvVar, "false")
+	t.Setenv(preyDeniedToolsEnvVar, "prey.zones.*")
+	cfg = ConfigFromContext(ExtractInfoFromEnv(context.Background()))
+	if cfg.AllowWrite || IsToolAllowed(cfg, "prey.zones.list") || IsToolAllowed(cfg, "prey.devices.delete") {
+		t.Fatalf("expected env to restrict the profile grants, got %+v", cfg)
+	}
+}
+
+func TestEnvLimitsApplyToEveryProfile(t *testing.T) {
+	clearProfileEnv(t)
+	f, err := ParseConfigFile([]byte(`
+default_profile: acme
+profiles:
+  acme:
+    api_key: acme-key
+  globex:
+    api_key: globex-key
+    allow_write: true
+    allowed_tools: [prey.devices.*, prey.zones.*]
+    denied_tools: [prey.devices.delete]
+    selectable: true
+`), "yaml", "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	useConfigFile(t, f, "")
+	globex := func() Config {
+		req, _ := http.NewRequest(http.MethodPost, "/mcp", nil)
+		req.Header.Set(preyProfileHeader, "globex")
+		return ConfigFromContext(ExtractInfoFromHeaders(context.Background(), req))
+	}
+	if cfg := globex(); !cfg.AllowWrite || !IsToolAllowed(cfg, "prey.zones.list") {
+		t.Fatalf("expected globex settings without env limits, got %+v", cfg)
+	}
+
+	t.Setenv(preyAllowWriteEnvVar, "false")
+	t.Setenv(preyAllowedToolsEnvVar, "prey.devices.*,prey.labels.list")
+	t.Setenv(preyDeniedToolsEnvVar, "prey.devices.get")
+	cfg := globex()
+	if cfg.APIKey != "globex-key" || cfg.AllowWrite {
+		t.Fatalf("expected PREY_ALLOW_WRITE=false to disable writes for globex, got %+v", cfg)
+	}
+	for tool, want := range map[string]bool{
+		"prey.devices.list":   true,
+		"prey.devices.get":    false, // PREY_DENIED_TOOLS
+		"prey.devices.delete": false, // profile denied_tools
+		"prey.zones.list":     false, // outside PREY_ALLOWED_TOOLS
+		"prey.labels.list":    false, // outside the profile's allowed_tools
+	} {
+		if got := IsToolAllowed(cfg, tool); got != want {
+			t.Fatalf("IsToolAllowed(%s) = %v, want %v", tool, got, want)
+		}
+	}
+
+	// PREY_ALLOW_WRITE=true is a grant, not a limit: it only applies to the active profile.
+	t.Setenv(preyAllowWriteEnvVar, "true")
+	t.Setenv(preyAllowedToolsEnvVar, "")
+	t.Setenv(preyDeniedToolsEnvVar, "")
+	if cfg := ConfigFromContext(ExtractInfoFromEnv(context.Background())); !cfg.AllowWrite {
+		t.Fatalf("expected PREY_ALLOW_WRITE to enable writes for the active profile")
+	}
+}
+
+func TestHeaderProfileMustBeSelectable(t *testing.T) {
+	clearProfileEnv(t)
+	f, err := ParseConfigFile([]byte(`
+default_profile: acme
+profiles:
+  acme:
+    api_key: acme-key
+  admin:
+    api_key: admin-key
+    allow_write: true
+  tenant:
+    api_key: tenant-key
+    selectable: true
+`), "yaml", "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	useConfigFile(t, f, "")
+	selectFrom := func(profile string) Config {
+		req, _ := http.NewRequest(http.MethodPost, "/mcp", nil)
+		req.Header.Set(preyProfileHeader, profile)
+		return ConfigFromContext(ExtractInfoFromHeaders(context.Background(), req))
+	}
+	if cfg := selectFrom("admin"); cfg.URL != "" || cfg.APIKey != "" || cfg.AllowWrite {
+		t.Fatalf("expected a profile that is not selectable to fail closed, got %+v", cfg)
+	}
+	if cfg := selectFrom("tenant"); cfg.APIKey != "tenant-key" {
+		t.Fatalf("expected the selectable profile, got %+v", cfg)
+	}
+	if cfg := selectFrom("acme"); cfg.APIKey != "acme-key" {
+		t.Fatalf("expected the active profile to be selectable, got %+v", cfg)
+	}
+}
+
+func TestUnknownHeaderProfileFailsClosed(t *testing.T) {
+	clearProfileEnv(t)
+	t.Setenv(preyAPIKeyEnvVar, "env-key")
+	useConfigFile(t, loadTestConfig(t), "")
+
+	req, _ := http.NewRequest(http.MethodPost, "/mcp", nil)
+	req.Header.Set(preyProfileHeader, "initech")
+	cfg := ConfigFromContext(ExtractInfoFromHeaders(context.Background(), req))
+	if cfg.URL != "" || cfg.APIKey != "" || cfg.AllowWrite {
+		t.Fatalf("expected unknown profile to fail closed, got %+v", cfg)
+	}
+}
+
+func TestParseConfigFileTOML(t *testing.T) {
+	f, err := ParseConfigFile([]byte(`
+[profiles.default]
+base_url = "https://api.preyproject.com/v1"
+api_key = "key"
+timeout = "5s"
+`), "toml", "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := SetConfigFile(f, ""); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer func() { _ = SetConfigFile(nil, "") }()
+	if ActiveProfile() != "default" {
+		t.Fatalf("expected the default profile, got %q", ActiveProfile())
+	}
+}
+
+func TestParseConfigFileErrors(t *testing.T) {
+	cases := map[string]string{
+		"unknown field":   "profiles:\n  a:\n    api_kye: x\n",
+		"no profiles":     "default_profile: a\n",
+		"missing default": "default_profile: b\nprofiles:\n  a:\n    api_key: x\n",
+		"bad url":         "profiles:\n  a:\n    base_url: api.example.com\n",
+		"bad timeout":     "profiles:\n  a:\n    timeout: soon\n",
+		"key and file":    "profiles:\n  a:\n    api_key: x\n    api_key_file: /k\n",
+	}
+	for name, doc := range cases {
+		if _, err := ParseConfigFile([]byte(doc), "yaml", ""); err == nil {
+			t.Fatalf("%s: expected error", name)
+		}
+	}
+	f, err := ParseConfigFile([]byte("profiles:\n  a: {}\n  b: {}\n"), "yaml", "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := SetConfigFile(f, ""); err == nil {
+		t.Fatalf("expected error selecting among several profiles without a default")
+	}
+	if err := SetConfigFile(nil, "a"); err == nil {
+		t.Fatalf("expected error for a profile without a config file")
+	}
+}
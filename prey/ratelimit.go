@@ -0,0 +1,82 @@
+package prey
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"mcp-prey/internal"
+)
+
+// limiterIdleTimeout is the minimum time an unused limiter is kept, so a limiter is
+// not dropped while a long call is still using it.
+const limiterIdleTimeout = time.Hour
+
+// limiterRegistry shares one limiter per upstream, API key and limiter settings.
+// Prey's limits apply per API key, while a Client is created for every request.
+type limiterRegistry struct {
+	mu        sync.Mutex
+	entries   map[string]*limiterEntry
+	lastPrune time.Time
+}
+
+type limiterEntry struct {
+	limiter  *internal.MultiLimiter
+	lastUsed time.Time
+}
+
+var limiters = limiterRegistry{entries: make(map[string]*limiterEntry)}
+
+func limiterFromConfig(cfg Config) *internal.MultiLimiter {
+	if cfg.DisableRateLimit {
+		return nil
+	}
+	return limiters.get(cfg, time.Now())
+}
+
+func (r *limiterRegistry) get(cfg Config, now time.Time) *internal.MultiLimiter {
+	key := limiterKey(cfg)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.prune(now)
+	if e, ok := r.entries[key]; ok {
+		e.lastUsed = now
+		return e.limiter
+	}
+	tiers := cfg.RateLimitTiers
+	if len(tiers) == 0 {
+		tiers = internal.DefaultPreyTiers
+	}
+	limiter, err := internal.NewTieredLimiter(tiers, cfg.RateLimitWriteReserve)
+	if err != nil {
+		// Loading the configuration rejects such settings, so this is not expected. Keep
+		// the configured tiers rather than fall back to possibly higher defaults.
+		slog.Error("invalid rate limit write reserve, reserving nothing for writes", "profile", cfg.Profile, "error", err)
+		limiter, _ = internal.NewTieredLimiter(tiers, 0)
+	}
+	r.entries[key] = &limiterEntry{limiter: limiter, lastUsed: now}
+	return limiter
+}
+
+// prune drops limiters that have been idle long enough to be full again, at most once
+// a minute.
+func (r *limiterRegistry) prune(now time.Time) {
+	if now.Sub(r.lastPrune) < time.Minute {
+		return
+	}
+	r.lastPrune = now
+	for key, e := range r.entries {
+		if now.Sub(e.lastUsed) > max(limiterIdleTimeout, e.limiter.RefillTime()) {
+			delete(r.entries, key)
+		}
+	}
+}
+
+// limiterKey identifies a limiter without keeping the API key in memory.
+func limiterKey(cfg Config) string {
+	sum := sha256.Sum256([]byte(cfg.URL + "\x00" + cfg.APIKey))
+	return fmt.Sprintf("%s|%v|%v", hex.EncodeToString(sum[:]), cfg.RateLimitTiers, cfg.RateLimitWriteReserve)
+}
@@ -0,0 +1,94 @@
+package prey
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"mcp-prey/internal"
+)
+
+func TestLimiterSharedPerAPIKey(t *testing.T) {
+	cfg := Config{URL: "https://api.example.com", APIKey: "a", RateLimitTiers: internal.DefaultPreyTiers, RateLimitWriteReserve: 0.2}
+	first := NewClient(cfg).Limiter
+	if first == nil || NewClient(cfg).Limiter != first {
+		t.Fatalf("expected clients with the same key to share a limiter")
+	}
+	other := cfg
+	other.APIKey = "b"
+	if NewClient(other).Limiter == first {
+		t.Fatalf("expected another API key to get its own limiter")
+	}
+	other = cfg
+	other.RateLimitWriteReserve = 0
+	if NewClient(other).Limiter == first {
+		t.Fatalf("expected changed settings to get a new limiter")
+	}
+	other = cfg
+	other.DisableRateLimit = true
+	if NewClient(other).Limiter != nil {
+		t.Fatalf("expected no limiter when rate limiting is disabled")
+	}
+}
+
+func TestLimiterRegistryPrunesIdle(t *testing.T) {
+	r := limiterRegistry{entries: make(map[string]*limiterEntry)}
+	cfg := Config{APIKey: "a", RateLimitTiers: internal.DefaultPreyTiers}
+	now := time.Now()
+	first := r.get(cfg, now)
+	if r.get(cfg, now.Add(limiterIdleTimeout/2)) != first {
+		t.Fatalf("expected the limiter to be reused")
+	}
+	if r.get(cfg, now.Add(2*limiterIdleTimeout)) == first {
+		t.Fatalf("expected an idle limiter to be replaced")
+	}
+}
+
+func TestRateLimitSettings(t *testing.T) {
+	clearProfileEnv(t)
+	f, err := ParseConfigFile([]byte(`
+profiles:
+  default:
+    api_key: key
+    rate_limit:
+      tiers: ["1/1s:5", "100/1h"]
+      write_reserve: 0.4
+`), "yaml", "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	useConfigFile(t, f, "")
+
+	var cfg Config
+	if err := resolveSettings(&cfg, ""); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(cfg.RateLimitTiers) != 2 || cfg.RateLimitTiers[0].Burst != 5 || cfg.RateLimitWriteReserve != 0.4 {
+		t.Fatalf("expected profile rate limits, got %v %v", cfg.RateLimitTiers, cfg.RateLimitWriteReserve)
+	}
+
+	t.Setenv(preyRateLimitTiersEnvVar, "3/1s")
+	t.Setenv(preyWriteReserveEnvVar, "0")
+	if err := resolveSettings(&cfg, ""); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(cfg.RateLimitTiers) != 1 || cfg.RateLimitTiers[0].Limit != 3 || cfg.RateLimitWriteReserve != 0 {
+		t.Fatalf("expected env to override the profile, got %v %v", cfg.RateLimitTiers, cfg.RateLimitWriteReserve)
+	}
+
+	if _, err := ParseConfigFile([]byte("profiles:\n  a:\n    rate_limit:\n      write_reserve: 1.5\n"), "yaml", ""); err == nil {
+		t.Fatalf("expected error for an invalid write_reserve")
+	}
+	if _, err := ParseConfigFile([]byte("profiles:\n  a:\n    rate_limit:\n      tiers: [\"1/1s\", \"30/1m\"]\n      write_reserve: 0.3\n"), "yaml", ""); err == nil || !strings.Contains(err.Error(), "burst is too small") {
+		t.Fatalf("expected error for tiers too small for the write_reserve, got %v", err)
+	}
+}
+
+func TestLimiterKeepsTiersWhenReserveDoesNotFit(t *testing.T) {
+	r := limiterRegistry{entries: make(map[string]*limiterEntry)}
+	cfg := Config{APIKey: "a", RateLimitTiers: []internal.RateTier{{Limit: 1, Period: time.Second, Burst: 1}}, RateLimitWriteReserve: 0.5}
+	status := r.get(cfg, time.Now()).Status()
+	if len(status) != 1 || status[0].Tier.Limit != 1 || status[0].Remaining != 1 {
+		t.Fatalf("expected the configured tier without a reserve, got %+v", status)
+	}
+}
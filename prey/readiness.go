@@ -0,0 +1,177 @@
+package prey
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultReadyCacheTTL = 30 * time.Second
+	// readyFailureTTL is shorter so a fixed key or outage is noticed quickly, while
+	// frequent probes still cannot hammer the Prey API.
+	readyFailureTTL = 5 * time.Second
+	readyTimeout    = 5 * time.Second
+)
+
+// Readiness check statuses.
+const (
+	CheckOK      = "ok"
+	CheckFail    = "fail"
+	CheckSkipped = "skipped"
+)
+
+// ReadinessCheck is the outcome of one readiness check.
+type ReadinessCheck struct {
+	Status     string    `json:"status"`
+	Error      string    `json:"error,omitempty"`
+	DurationMs int64     `json:"duration_ms"`
+	CheckedAt  time.Time `json:"checked_at"`
+	Cached     bool      `json:"cached,omitempty"`
+}
+
+// ReadinessReport is the outcome of every readiness check.
+type ReadinessReport struct {
+	Status string                    `json:"status"`
+	Checks map[string]ReadinessCheck `json:"checks"`
+}
+
+// Readiness checks that the environment configuration is valid and that the
+// configured Prey API accepts the environment API key. The upstream result is cached
+// so probes do not spend the key's rate limit. Without an environment or active
+// profile API key, as when every caller sends its own, the upstream check is skipped.
+type Readiness struct {
+	ttl time.Duration
+	now func() time.Time
+
+	mu       sync.Mutex
+	upstream *ReadinessCheck
+}
+
+// NewReadiness returns a Readiness caching successful upstream checks for ttl.
+func NewReadiness(ttl time.Duration) *Readiness {
+	return &Readiness{ttl: ttl, now: time.Now}
+}
+
+// ReadinessFromEnv returns a Readiness using PREY_READY_CACHE_TTL (default 30s).
+func ReadinessFromEnv() (*Readiness, error) {
+	ttl := defaultReadyCacheTTL
+	if val := strings.TrimSpace(os.Getenv(preyReadyCacheTTLEnvVar)); val != "" {
+		d, err := time.ParseDuration(val)
+		if err != nil || d < 0 {
+			return nil, fmt.Errorf("invalid %s %q", preyReadyCacheTTLEnvVar, val)
+		}
+		ttl = d
+	}
+	return NewReadiness(ttl), nil
+}
+
+// Check runs the checks, reusing a recent upstream result.
+func (r *Readiness) Check(ctx context.Context) ReadinessReport {
+	report := ReadinessReport{Status: CheckOK, Checks: map[string]ReadinessCheck{}}
+	start := r.now()
+	config := ReadinessCheck{Status: CheckOK, CheckedAt: start}
+	cfg, err := validateActiveConfig()
+	if err != nil {
+		config.Status, config.Error = CheckFail, err.Error()
+	}
+	config.DurationMs = r.now().Sub(start).Milliseconds()
+	report.Checks["config"] = config
+
+	switch {
+	case config.Status != CheckOK:
+		report.Checks["upstream"] = ReadinessCheck{Status: CheckSkipped, Error: "configuration is invalid", CheckedAt: start}
+	case cfg.APIKey == "":
+		report.Checks["upstream"] = ReadinessCheck{Status: CheckSkipped, Error: "no API key is configured; callers send their own", CheckedAt: start}
+	default:
+		report.Checks["upstream"] = r.checkUpstream(ctx)
+	}
+	for _, c := range report.Checks {
+		if c.Status == CheckFail {
+			report.Status = CheckFail
+		}
+	}
+	return report
+}
+
+func (r *Readiness) checkUpstream(ctx context.Context) ReadinessCheck {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if c := r.upstream; c != nil {
+		ttl := r.ttl
+		if c.Status != CheckOK {
+			ttl = min(ttl, readyFailureTTL)
+		}
+		if r.now().Sub(c.CheckedAt) < ttl {
+			cached := *c
+			cached.Cached = true
+			return cached
+		}
+	}
+	start := r.now()
+	c := ReadinessCheck{Status: CheckOK, CheckedAt: start}
+	if err := pingAccount(ctx); err != nil {
+		c.Status, c.Error = CheckFail, err.Error()
+	}
+	c.DurationMs = r.now().Sub(start).Milliseconds()
+	r.upstream = &c
+	return c
+}
+
+// pingAccount makes a cheap authenticated call with the environment configuration.
+func pingAccount(ctx context.Context) error {
+	cfg := ConfigFromContext(ExtractInfoFromEnv(context.Background()))
+	cfg.DisableRateLimit = true
+	client := NewClient(cfg)
+	ctx, cancel := context.WithTimeout(ctx, min(cfg.Timeout, readyTimeout))
+	defer cancel()
+	req, err := client.NewRequest(http.MethodGet, "/account", nil, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := client.do(req.WithContext(ctx))
+	if err != nil {
+		return fmt.Errorf("prey api unreachable: %w", err)
+	}
+	defer resp.Body.Close()
+	switch {
+	case resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden:
+		return fmt.Errorf("prey api rejected the API key: %s", resp.Status)
+	case resp.StatusCode >= 300:
+		return fmt.Errorf("prey api error: %s", resp.Status)
+	}
+	return nil
+}
+
+// readinessStatus is what ServeHTTP exposes of a ReadinessReport: the probe is
+// unauthenticated, so error details, which can name hosts and config values, are
+// logged instead.
+type readinessStatus struct {
+	Status string            `json:"status"`
+	Checks map[string]string `json:"checks"`
+}
+
+// ServeHTTP writes each check's status as JSON, with status 503 unless every check
+// passed, and logs the failures.
+func (r *Readiness) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	report := r.Check(req.Context())
+	status := readinessStatus{Status: report.Status, Checks: make(map[string]string, len(report.Checks))}
+	for name, c := range report.Checks {
+		status.Checks[name] = c.Status
+		if c.Status == CheckFail && !c.Cached {
+			slog.Warn("readiness check failed", "check", name, "error", c.Error)
+		}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	if report.Status != CheckOK {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	_ = json.NewEncoder(w).Encode(status)
+}
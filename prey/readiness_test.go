@@ -0,0 +1,100 @@
+package prey
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestReadinessChecksUpstreamWithCache(t *testing.T) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		if r.URL.Path != "/account" || r.Header.Get("apikey") != "good" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		_, _ = w.Write([]byte(`{}`))
+	}))
+	defer srv.Close()
+	t.Setenv(preyAPIBaseEnvVar, srv.URL)
+	t.Setenv(preyAPIKeyEnvVar, "good")
+
+	now := time.Now()
+	r := NewReadiness(time.Minute)
+	r.now = func() time.Time { return now }
+	if report := r.Check(context.Background()); report.Status != CheckOK {
+		t.Fatalf("expected ready, got %+v", report)
+	}
+	report := r.Check(context.Background())
+	if !report.Checks["upstream"].Cached || calls.Load() != 1 {
+		t.Fatalf("expected cached upstream check, got %+v after %d calls", report, calls.Load())
+	}
+
+	t.Setenv(preyAPIKeyEnvVar, "bad")
+	now = now.Add(2 * time.Minute)
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("expected 503 for a rejected key, got %d", rec.Code)
+	}
+	var body readinessStatus
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if body.Checks["upstream"] != CheckFail || body.Checks["config"] != CheckOK {
+		t.Fatalf("unexpected report: %+v", body)
+	}
+	if strings.Contains(rec.Body.String(), "401") || strings.Contains(rec.Body.String(), srv.URL) {
+		t.Fatalf("expected no error details in the probe response, got %s", rec.Body)
+	}
+
+	// Failures are retried sooner than successes.
+	t.Setenv(preyAPIKeyEnvVar, "good")
+	now = now.Add(readyFailureTTL)
+	if report := r.Check(context.Background()); report.Status != CheckOK {
+		t.Fatalf("expected recovery after the failure TTL, got %+v", report)
+	}
+}
+
+func TestReadinessInvalidConfig(t *testing.T) {
+	t.Setenv(preyAPIKeyEnvVar, "")
+	t.Setenv(preyAPIBaseEnvVar, "not a url")
+	t.Setenv(preyTimeoutMsEnvVar, "soon")
+	report := NewReadiness(time.Minute).Check(context.Background())
+	if report.Status != CheckFail || report.Checks["config"].Status != CheckFail {
+		t.Fatalf("expected config failure, got %+v", report)
+	}
+	if report.Checks["upstream"].Status != CheckSkipped {
+		t.Fatalf("expected upstream check to be skipped, got %+v", report.Checks["upstream"])
+	}
+}
+
+func TestReadinessWithoutEnvKey(t *testing.T) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer srv.Close()
+	t.Setenv(preyAPIBaseEnvVar, srv.URL)
+	t.Setenv(preyAPIKeyEnvVar, "")
+
+	rec := httptest.NewRecorder()
+	NewReadiness(time.Minute).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected a multi-tenant deployment to be ready, got %d: %s", rec.Code, rec.Body)
+	}
+	var body readinessStatus
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if body.Status != CheckOK || body.Checks["upstream"] != CheckSkipped || calls.Load() != 0 {
+		t.Fatalf("expected the upstream check to be skipped, got %+v after %d calls", body, calls.Load())
+	}
+}
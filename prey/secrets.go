@@ -0,0 +1,86 @@
+package prey
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+
+	"mcp-prey/internal"
+)
+
+// fileSuffix marks the variable naming a file that holds a secret setting, e.g.
+// PREY_API_KEY_FILE for PREY_API_KEY.
+const fileSuffix = "_FILE"
+
+// secretEnvVars are the settings that can be read from a <NAME>_FILE file.
+var secretEnvVars = []string{preyAPIKeyEnvVar, preyMaskSaltEnvVar, preyAuditKeyEnvVar}
+
+var (
+	secretsMu sync.Mutex
+	secrets   = map[string]*internal.Secret{}
+)
+
+// LoadSecretFiles reads every secret configured with a <NAME>_FILE variable, so a
+// missing, unreadable or empty file stops the server at startup. Setting both NAME
+// and NAME_FILE is an error.
+func LoadSecretFiles() error {
+	var errs []error
+	for _, name := range secretEnvVars {
+		if _, err := secretFromEnv(name); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// secretEnv returns NAME, or the current contents of the file named by NAME_FILE.
+// Errors are reported by LoadSecretFiles; here they yield an empty value.
+func secretEnv(name string) string {
+	val, _ := secretFromEnv(name)
+	return val
+}
+
+func secretFromEnv(name string) (string, error) {
+	path := strings.TrimSpace(os.Getenv(name + fileSuffix))
+	if path == "" {
+		return strings.TrimSpace(os.Getenv(name)), nil
+	}
+	if strings.TrimSpace(os.Getenv(name)) != "" {
+		return "", fmt.Errorf("%s and %s%s are both set", name, name, fileSuffix)
+	}
+	secretsMu.Lock()
+	defer secretsMu.Unlock()
+	s, ok := secrets[name]
+	if !ok || s.Path() != path {
+		var err error
+		if s, err = internal.OpenSecret(path); err != nil {
+			return "", fmt.Errorf("%s%s: %w", name, fileSuffix, err)
+		}
+		secrets[name] = s
+	}
+	return s.Value(), nil
+}
+
+// reloadSecrets re-reads every secret file now. A file that fails keeps its value.
+// Secrets whose <NAME>_FILE no longer names their file are dropped.
+func reloadSecrets() error {
+	secretsMu.Lock()
+	defer secretsMu.Unlock()
+	var errs []error
+	for _, name := range secretEnvVars {
+		s, ok := secrets[name]
+		if !ok {
+			continue
+		}
+		if s.Path() != strings.TrimSpace(os.Getenv(name+fileSuffix)) {
+			delete(secrets, name)
+			continue
+		}
+		if err := s.Reload(); err != nil {
+			errs = append(errs, fmt.Errorf("%s%s: %w", name, fileSuffix, err))
+		}
+	}
+	return errors.Join(errs...)
+}
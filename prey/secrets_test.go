@@ -0,0 +1,46 @@
+package prey
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestAPIKeyFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "prey-key")
+	if err := os.WriteFile(path, []byte("file-key\n"), 0o600); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	t.Setenv(preyAPIKeyEnvVar, "")
+	t.Setenv(preyAPIKeyEnvVar+fileSuffix, path)
+	if err := LoadSecretFiles(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := apiKeyFromEnv(); got != "file-key" {
+		t.Fatalf("expected key from file, got %q", got)
+	}
+
+	t.Setenv(preyAPIKeyEnvVar, "env-key")
+	if err := LoadSecretFiles(); err == nil {
+		t.Fatalf("expected error when both the variable and its file are set")
+	}
+}
+
+func TestLoadSecretFilesErrorsOmitValues(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "empty")
+	if err := os.WriteFile(path, nil, 0o600); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	t.Setenv(preyAPIKeyEnvVar, "")
+	t.Setenv(preyAPIKeyEnvVar+fileSuffix, path)
+	t.Setenv(preyMaskSaltEnvVar, "")
+	t.Setenv(preyMaskSaltEnvVar+fileSuffix, filepath.Join(t.TempDir(), "missing"))
+	err := LoadSecretFiles()
+	if err == nil {
+		t.Fatalf("expected errors for empty and missing files")
+	}
+	if !strings.Contains(err.Error(), "PREY_API_KEY_FILE") || !strings.Contains(err.Error(), "PREY_MASK_SALT_FILE") {
+		t.Fatalf("expected both variables named, got %v", err)
+	}
+}
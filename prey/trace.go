@@ -0,0 +1,51 @@
+package prey
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.39.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// startRequestSpan starts a client span for an upstream request as a child of the
+// tool span in the request context, and propagates it in the request headers.
+func startRequestSpan(req *http.Request, route string) (*http.Request, trace.Span) {
+	ctx, span := otel.Tracer("mcp-prey").Start(req.Context(), req.Method+" "+route,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.HTTPRequestMethodKey.String(req.Method),
+			semconv.URLTemplate(route),
+			semconv.ServerAddress(req.URL.Hostname()),
+		),
+	)
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(req.Header))
+	return req.WithContext(ctx), span
+}
+
+// endRequestSpan records the outcome of an upstream request. Client spans treat
+// 4xx and 5xx responses as errors.
+func endRequestSpan(span trace.Span, status int, err error) {
+	defer span.End()
+	switch {
+	case err != nil:
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	case status >= 400:
+		span.SetAttributes(semconv.ErrorTypeKey.String(strconv.Itoa(status)))
+		span.SetStatus(codes.Error, http.StatusText(status))
+	}
+	if status != 0 {
+		span.SetAttributes(semconv.HTTPResponseStatusCode(status))
+	}
+}
+
+// ExtractTraceContext continues the trace named by the incoming request's
+// propagation headers (e.g. traceparent), so tool spans join the caller's trace.
+func ExtractTraceContext(ctx context.Context, req *http.Request) context.Context {
+	return otel.GetTextMapPropagator().Extract(ctx, propagation.HeaderCarrier(req.Header))
+}
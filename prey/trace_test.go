@@ -0,0 +1,84 @@
+package prey
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestClientRequestSpan(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	prevTP, prevProp := otel.GetTracerProvider(), otel.GetTextMapPropagator()
+	otel.SetTracerProvider(tp)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	defer func() {
+		otel.SetTracerProvider(prevTP)
+		otel.SetTextMapPropagator(prevProp)
+	}()
+
+	var traceparent string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		traceparent = r.Header.Get("traceparent")
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer srv.Close()
+
+	ctx, parent := tp.Tracer("test").Start(context.Background(), "mcp.tool.prey.devices.get")
+	client := NewClient(Config{URL: srv.URL, APIKey: "key", Timeout: time.Second, DisableRateLimit: true})
+	req, _ := client.NewRequest(http.MethodGet, "/devices/42", nil, nil)
+	if err := client.DoJSON(req.WithContext(ctx), nil); err == nil {
+		t.Fatalf("expected error for 404")
+	}
+	parent.End()
+
+	spans := recorder.Ended()
+	if len(spans) != 2 {
+		t.Fatalf("expected 2 spans, got %d", len(spans))
+	}
+	span := spans[0]
+	if span.Name() != "GET /devices/{id}" {
+		t.Fatalf("unexpected span name %q", span.Name())
+	}
+	if span.Parent().SpanID() != parent.SpanContext().SpanID() {
+		t.Fatalf("expected upstream span to be a child of the tool span")
+	}
+	if span.Status().Code != codes.Error {
+		t.Fatalf("expected error status, got %v", span.Status())
+	}
+	attrs := map[attribute.Key]attribute.Value{}
+	for _, kv := range span.Attributes() {
+		attrs[kv.Key] = kv.Value
+	}
+	if attrs["url.template"].AsString() != "/devices/{id}" || attrs["http.response.status_code"].AsInt64() != 404 {
+		t.Fatalf("unexpected attributes: %v", span.Attributes())
+	}
+	if traceparent == "" {
+		t.Fatalf("expected traceparent header upstream")
+	}
+}
+
+func TestExtractTraceContext(t *testing.T) {
+	prev := otel.GetTextMapPropagator()
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	defer otel.SetTextMapPropagator(prev)
+
+	req := httptest.NewRequest(http.MethodPost, "/mcp", nil)
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	ctx := ExtractTraceContext(context.Background(), req)
+	tp := sdktrace.NewTracerProvider()
+	_, span := tp.Tracer("test").Start(ctx, "child")
+	defer span.End()
+	if got := span.SpanContext().TraceID().String(); got != "4bf92f3577b34da6a3ce929d0e0e4736" {
+		t.Fatalf("expected incoming trace ID, got %s", got)
+	}
+}
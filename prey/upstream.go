@@ -0,0 +1,68 @@
+package prey
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"path"
+	"strings"
+)
+
+// UpstreamPolicy limits the Prey API base URLs callers may select with X-Prey-URL.
+type UpstreamPolicy struct {
+	// Hosts are allowed hosts (host or host:port) or path.Match patterns such as *.example.com.
+	// An entry without a port does not match URLs with one.
+	Hosts []string
+	// AllowHTTP permits plain http:// upstreams.
+	AllowHTTP bool
+}
+
+// upstreamPolicyFromEnv reads PREY_UPSTREAM_ALLOWLIST, which defaults to the host of the
+// configured base URL, and PREY_UPSTREAM_ALLOW_HTTP.
+func upstreamPolicyFromEnv() UpstreamPolicy {
+	var hosts []string
+	for _, h := range splitList(os.Getenv(preyUpstreamAllowlistEnvVar)) {
+		hosts = append(hosts, strings.ToLower(h))
+	}
+	if len(hosts) == 0 {
+		var cfg Config
+		_ = resolveSettings(&cfg, "")
+		if u, err := url.Parse(cfg.URL); err == nil && u.Host != "" {
+			hosts = []string{strings.ToLower(u.Host)}
+		}
+	}
+	return UpstreamPolicy{Hosts: hosts, AllowHTTP: EnvBool(preyUpstreamAllowHTTPEnvVar)}
+}
+
+// Check validates a caller-supplied base URL against the policy.
+func (p UpstreamPolicy) Check(raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return fmt.Errorf("invalid URL: %w", err)
+	}
+	switch {
+	case u.Scheme == "https":
+	case u.Scheme == "http" && p.AllowHTTP:
+	default:
+		return fmt.Errorf("scheme %q is not allowed", u.Scheme)
+	}
+	if u.Host == "" || u.User != nil {
+		return fmt.Errorf("URL must have a host and no credentials")
+	}
+	if p.allowsHost(u) {
+		return nil
+	}
+	return fmt.Errorf("host %q is not in the upstream allowlist", u.Host)
+}
+
+// allowsHost matches entries against the URL's host and port as written, so an entry
+// without a port only allows URLs without one.
+func (p UpstreamPolicy) allowsHost(u *url.URL) bool {
+	host := strings.ToLower(u.Host)
+	for _, pattern := range p.Hosts {
+		if ok, _ := path.Match(pattern, host); ok {
+			return true
+		}
+	}
+	return false
+}
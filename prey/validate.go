@@ -0,0 +1,207 @@
+package prey
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path"
+	"strings"
+
+	"mcp-prey/internal"
+)
+
+// ValidateConfig reports problems with the active profile's settings, including a
+// missing API key, and with environment settings that the server would otherwise
+// silently replace with defaults.
+func ValidateConfig() error {
+	cfg, err := validateActiveConfig()
+	if err == nil && cfg.APIKey == "" {
+		err = fmt.Errorf("no API key: set %s or api_key in the config file profile", preyAPIKeyEnvVar)
+	}
+	return err
+}
+
+// validateActiveConfig resolves the active profile and reports the problems
+// ValidateConfig does, except a missing API key.
+func validateActiveConfig() (Config, error) {
+	var errs []error
+	var cfg Config
+	if err := resolveSettings(&cfg, ""); err != nil {
+		return cfg, err
+	}
+	if u, err := url.Parse(cfg.URL); err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+		errs = append(errs, fmt.Errorf("base URL %q is not an absolute http(s) URL", cfg.URL))
+	}
+	if val := strings.TrimSpace(os.Getenv(preyTimeoutMsEnvVar)); val != "" {
+		if _, ok := timeoutFromEnv(); !ok {
+			errs = append(errs, fmt.Errorf("%s %q is not a positive number of milliseconds", preyTimeoutMsEnvVar, val))
+		}
+	}
+	errs = append(errs, rateLimitEnvError())
+	if _, err := LocationPrecisionFromEnv(); err != nil {
+		errs = append(errs, fmt.Errorf("%s: %w", preyLocationEnvVar, err))
+	}
+	return cfg, errors.Join(errs...)
+}
+
+// ValidateRateLimit reports invalid PREY_RATE_LIMIT_* settings, which would otherwise
+// be ignored, and rate limit tiers of the active profile that cannot keep its write
+// reserve.
+func ValidateRateLimit() error {
+	errs := []error{rateLimitEnvError()}
+	var cfg Config
+	if err := resolveSettings(&cfg, ""); err != nil {
+		return err
+	}
+	if err := checkRateLimit(cfg); err != nil {
+		errs = append(errs, fmt.Errorf("rate limit: %w", err))
+	}
+	return errors.Join(errs...)
+}
+
+func rateLimitEnvError() error {
+	var errs []error
+	if val := strings.TrimSpace(os.Getenv(preyRateLimitTiersEnvVar)); val != "" {
+		if _, err := internal.ParseRateTiers(val); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", preyRateLimitTiersEnvVar, err))
+		}
+	}
+	if val := strings.TrimSpace(os.Getenv(preyWriteReserveEnvVar)); val != "" {
+		if _, ok := writeReserveFromEnv(); !ok {
+			errs = append(errs, fmt.Errorf("%s %q is not a share between 0 and 1", preyWriteReserveEnvVar, val))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// checkRateLimit reports tiers that cannot keep the write reserve, as the limiter of
+// cfg would be built.
+func checkRateLimit(cfg Config) error {
+	if cfg.DisableRateLimit {
+		return nil
+	}
+	_, err := internal.NewTieredLimiter(cfg.RateLimitTiers, cfg.RateLimitWriteReserve)
+	return err
+}
+
+// ValidateSettings checks the whole configuration: the active profile as
+// ValidateConfig does, the secret files, every config file profile, tool names in
+// allowlists and confirmation policies against known (tool name to whether it
+// writes), and settings that contradict each other.
+func ValidateSettings(known map[string]bool) error {
+	errs := []error{ValidateConfig(), LoadSecretFiles()}
+	checkTools := func(source string, entries []string) {
+		for _, entry := range entries {
+			if err := checkToolEntry(known, entry); err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", source, err))
+			}
+		}
+	}
+	checkTools(preyAllowedToolsEnvVar, sortedNames(allowedToolsFromEnv()))
+	checkTools(preyDeniedToolsEnvVar, sortedNames(parseToolList(os.Getenv(preyDeniedToolsEnvVar))))
+	checkTools(preyWriteToolsEnvVar, sortedNames(parseToolList(os.Getenv(preyWriteToolsEnvVar))))
+
+	profileNames := []string{""}
+	if s := profiles.Load(); s != nil {
+		for _, name := range s.file.ProfileNames() {
+			p := s.file.Profiles[name]
+			checkTools(fmt.Sprintf("profile %s: allowed_tools", name), p.AllowedTools)
+			checkTools(fmt.Sprintf("profile %s: denied_tools", name), p.DeniedTools)
+			checkTools(fmt.Sprintf("profile %s: write_tools", name), p.WriteTools)
+			if name != s.active {
+				profileNames = append(profileNames, name)
+			}
+		}
+	}
+	for _, name := range profileNames {
+		var cfg Config
+		if err := resolveSettings(&cfg, name); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		label := "environment"
+		if cfg.Profile != "" {
+			label = "profile " + cfg.Profile
+		}
+		if name != "" {
+			if cfg.APIKey == "" {
+				errs = append(errs, fmt.Errorf("%s: no API key", label))
+			}
+		}
+		errs = append(errs, validateToolAccess(label, cfg, known)...)
+		if !cfg.DisableRateLimit {
+			if _, err := internal.NewTieredLimiter(cfg.RateLimitTiers, cfg.RateLimitWriteReserve); err != nil {
+				errs = append(errs, fmt.Errorf("%s: rate limit: %w", label, err))
+			}
+		}
+	}
+
+	errs = append(errs, validateConfirmPolicy(known))
+	if secretEnv(preyMaskSaltEnvVar) != "" && strings.ToLower(strings.TrimSpace(os.Getenv(preyMaskModeEnvVar))) != "pseudonymize" {
+		errs = append(errs, fmt.Errorf("%s is set but %s is not pseudonymize", preyMaskSaltEnvVar, preyMaskModeEnvVar))
+	}
+	return errors.Join(errs...)
+}
+
+// checkToolEntry reports a tool list entry that names an unknown tool, or a pattern
+// that is invalid or matches no known tool.
+func checkToolEntry(known map[string]bool, entry string) error {
+	if !isToolPattern(entry) {
+		if _, ok := known[entry]; !ok {
+			return fmt.Errorf("unknown tool %q", entry)
+		}
+		return nil
+	}
+	if _, err := path.Match(entry, ""); err != nil {
+		return fmt.Errorf("invalid pattern %q", entry)
+	}
+	for name := range known {
+		if ok, _ := path.Match(entry, name); ok {
+			return nil
+		}
+	}
+	return fmt.Errorf("pattern %q matches no tool", entry)
+}
+
+// validateToolAccess reports tool lists of a resolved profile that contradict each
+// other, such as allowlisted write tools that can never write.
+func validateToolAccess(label string, cfg Config, known map[string]bool) []error {
+	var errs []error
+	for _, tool := range sortedNames(cfg.AllowedTools) {
+		switch {
+		case matchTool(cfg.DeniedTools, tool):
+			errs = append(errs, fmt.Errorf("%s: %s is both allowed and denied", label, tool))
+		case !known[tool] || IsWriteAllowed(cfg, tool):
+		case !cfg.AllowWrite:
+			errs = append(errs, fmt.Errorf("%s: write tool %s is allowed but writes are disabled", label, tool))
+		default:
+			errs = append(errs, fmt.Errorf("%s: write tool %s is allowed but not granted in write tools", label, tool))
+		}
+	}
+	if !cfg.AllowWrite && len(cfg.WriteTools) > 0 {
+		errs = append(errs, fmt.Errorf("%s: write tools are granted but writes are disabled", label))
+	}
+	for _, tool := range sortedNames(cfg.WriteTools) {
+		if write, ok := known[tool]; ok && !write {
+			errs = append(errs, fmt.Errorf("%s: %s is granted write access but is not a write tool", label, tool))
+		}
+	}
+	return errs
+}
+
+// validateConfirmPolicy reports an invalid PREY_CONFIRM_POLICY and overrides for tools
+// that do not exist.
+func validateConfirmPolicy(known map[string]bool) error {
+	var errs []error
+	cfg, err := ParseConfirmConfig(os.Getenv(preyConfirmEnvVar))
+	if err != nil {
+		errs = append(errs, fmt.Errorf("%s: %w", preyConfirmEnvVar, err))
+	}
+	for _, tool := range sortedNames(cfg.Tools) {
+		if _, exists := known[tool]; !exists {
+			errs = append(errs, fmt.Errorf("%s: unknown tool %q", preyConfirmEnvVar, tool))
+		}
+	}
+	return errors.Join(errs...)
+}
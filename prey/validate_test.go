@@ -0,0 +1,159 @@
+package prey
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var testKnownTools = map[string]bool{
+	"prey.devices.list":   false,
+	"prey.devices.delete": true,
+	"prey.zones.update":   true,
+	"prey.zones.list":     false,
+}
+
+func TestValidateSettings(t *testing.T) {
+	clearProfileEnv(t)
+	t.Setenv(preyAPIKeyEnvVar, "key")
+	t.Setenv(preyConfirmEnvVar, "")
+	t.Setenv(preyDryRunEnvVar, "")
+	t.Setenv(preyMaskSaltEnvVar, "")
+	if err := ValidateSettings(testKnownTools); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	t.Setenv(preyAllowedToolsEnvVar, "prey.devices.list,prey.devise.get,prey.devices.delete")
+	t.Setenv(preyConfirmEnvVar, "sometimes,prey.zone.update=always")
+	t.Setenv(preyDryRunEnvVar, "true")
+	t.Setenv(preyMaskSaltEnvVar, "salt")
+	t.Setenv(preyWriteReserveEnvVar, "2")
+	err := ValidateSettings(testKnownTools)
+	if err == nil {
+		t.Fatalf("expected problems")
+	}
+	for _, want := range []string{
+		`PREY_ALLOWED_TOOLS: unknown tool "prey.devise.get"`,
+		"write tool prey.devices.delete is allowed but writes are disabled",
+		`PREY_CONFIRM_POLICY: unknown policy "sometimes"`,
+		`PREY_CONFIRM_POLICY: unknown tool "prey.zone.update"`,
+		"PREY_MASK_SALT is set but PREY_MASK_MODE is not pseudonymize",
+		`PREY_RATE_LIMIT_WRITE_RESERVE "2" is not a share between 0 and 1`,
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Fatalf("expected %q in:\n%v", want, err)
+		}
+	}
+	// Dry runs need no write permission.
+	if strings.Contains(err.Error(), "PREY_DRY_RUN") {
+		t.Fatalf("expected PREY_DRY_RUN without writes to be accepted:\n%v", err)
+	}
+}
+
+func TestValidateSettingsToolGrants(t *testing.T) {
+	clearProfileEnv(t)
+	t.Setenv(preyAPIKeyEnvVar, "key")
+	t.Setenv(preyConfirmEnvVar, "")
+	t.Setenv(preyDryRunEnvVar, "")
+	t.Setenv(preyMaskSaltEnvVar, "")
+	t.Setenv(preyAllowedToolsEnvVar, "prey.zones.*,prey.devices.list,prey.devices.delete")
+	t.Setenv(preyDeniedToolsEnvVar, "prey.devices.list,prey.labels.*,prey.[")
+	t.Setenv(preyAllowWriteEnvVar, "true")
+	t.Setenv(preyWriteToolsEnvVar, "prey.zones.update,prey.zones.list")
+	err := ValidateSettings(testKnownTools)
+	if err == nil {
+		t.Fatalf("expected problems")
+	}
+	for _, want := range []string{
+		`PREY_DENIED_TOOLS: pattern "prey.labels.*" matches no tool`,
+		`PREY_DENIED_TOOLS: invalid pattern "prey.["`,
+		"environment: prey.devices.list is both allowed and denied",
+		"environment: write tool prey.devices.delete is allowed but not granted in write tools",
+		"environment: prey.zones.list is granted write access but is not a write tool",
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Fatalf("expected %q in:\n%v", want, err)
+		}
+	}
+	if strings.Contains(err.Error(), "prey.zones.*") {
+		t.Fatalf("expected a pattern matching known tools to be accepted:\n%v", err)
+	}
+
+	// Write grants only narrow PREY_ALLOW_WRITE.
+	t.Setenv(preyAllowWriteEnvVar, "")
+	if err := ValidateSettings(testKnownTools); err == nil || !strings.Contains(err.Error(), "environment: write tools are granted but writes are disabled") {
+		t.Fatalf("expected write tools without PREY_ALLOW_WRITE to be reported, got %v", err)
+	}
+}
+
+func TestValidateSettingsRateLimit(t *testing.T) {
+	clearProfileEnv(t)
+	t.Setenv(preyAPIKeyEnvVar, "key")
+	t.Setenv(preyConfirmEnvVar, "")
+	t.Setenv(preyDryRunEnvVar, "")
+	t.Setenv(preyMaskSaltEnvVar, "")
+	t.Setenv(preyRateLimitTiersEnvVar, "1/1s")
+	t.Setenv(preyWriteReserveEnvVar, "0.2")
+	err := ValidateSettings(testKnownTools)
+	if err == nil || !strings.Contains(err.Error(), "environment: rate limit: rate limit tier 1/1s:1: burst is too small") {
+		t.Fatalf("expected a rate limit problem, got %v", err)
+	}
+	t.Setenv(preyRateLimitTiersEnvVar, "2/1s,1/1m/")
+	if err := ValidateSettings(testKnownTools); err == nil || !strings.Contains(err.Error(), "PREY_RATE_LIMIT_TIERS: ") {
+		t.Fatalf("expected an invalid tiers problem, got %v", err)
+	}
+}
+
+func TestValidateRateLimit(t *testing.T) {
+	clearProfileEnv(t)
+	if err := ValidateRateLimit(); err != nil {
+		t.Fatalf("expected the defaults to be valid, got %v", err)
+	}
+	t.Setenv(preyRateLimitTiersEnvVar, "2/1s,1/1m/")
+	if err := ValidateRateLimit(); err == nil || !strings.Contains(err.Error(), "PREY_RATE_LIMIT_TIERS: ") {
+		t.Fatalf("expected invalid tiers to be reported, got %v", err)
+	}
+	t.Setenv(preyRateLimitTiersEnvVar, "1/1s")
+	t.Setenv(preyWriteReserveEnvVar, "0.3")
+	if err := ValidateRateLimit(); err == nil || !strings.Contains(err.Error(), "burst is too small") {
+		t.Fatalf("expected the reserve not to fit, got %v", err)
+	}
+
+	// A config file reload must not combine the environment's tiers with a profile
+	// reserve that does not fit.
+	t.Setenv(preyWriteReserveEnvVar, "")
+	f, err := ParseConfigFile([]byte("profiles:\n  default:\n    rate_limit:\n      write_reserve: 0.3\n"), "yaml", "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := SetConfigFile(f, ""); err == nil || !strings.Contains(err.Error(), "profile default: rate limit") {
+		t.Fatalf("expected the active profile to be rejected, got %v", err)
+	}
+}
+
+func TestValidateSettingsProfiles(t *testing.T) {
+	clearProfileEnv(t)
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	writeConfig(t, path, "default_profile: a\nprofiles:\n  a:\n    api_key: k\n    allowed_tools: [prey.devices.lst]\n  b:\n    base_url: https://b.example.com\n")
+	if err := NewConfigManager(path, "").Load(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	t.Cleanup(func() { _ = SetConfigFile(nil, "") })
+
+	err := ValidateSettings(testKnownTools)
+	if err == nil || !strings.Contains(err.Error(), `profile a: allowed_tools: unknown tool "prey.devices.lst"`) || !strings.Contains(err.Error(), "profile b: no API key") {
+		t.Fatalf("expected profile problems, got %v", err)
+	}
+}
+
+func TestDescribeConfigRedactsKeys(t *testing.T) {
+	clearProfileEnv(t)
+	t.Setenv(preyAPIKeyEnvVar, "super-secret")
+	d := DescribeConfig()
+	if len(d.Profiles) != 1 || d.Profiles[0].APIKey != redactedSecret || d.Profiles[0].APIKeySource != preyAPIKeyEnvVar {
+		t.Fatalf("unexpected description: %+v", d)
+	}
+	if strings.Contains(d.Profiles[0].BaseURL+d.Profiles[0].APIKeySource, "super-secret") {
+		t.Fatalf("key leaked: %+v", d)
+	}
+}
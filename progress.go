@@ -0,0 +1,44 @@
+package mcprey
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+type progressTokenKey struct{}
+
+func withProgressToken(ctx context.Context, request mcp.CallToolRequest) context.Context {
+	if request.Params.Meta == nil || request.Params.Meta.ProgressToken == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, progressTokenKey{}, request.Params.Meta.ProgressToken)
+}
+
+// ReportProgress sends a notifications/progress message for the current tool call.
+// It is a no-op when the client did not ask for progress updates. A total of 0 means unknown.
+func ReportProgress(ctx context.Context, progress, total float64, message string) {
+	token := ctx.Value(progressTokenKey{})
+	if token == nil {
+		return
+	}
+	srv := server.ServerFromContext(ctx)
+	if srv == nil {
+		return
+	}
+	params := map[string]any{
+		"progressToken": token,
+		"progress":      progress,
+	}
+	if total > 0 {
+		params["total"] = total
+	}
+	if message != "" {
+		params["message"] = message
+	}
+	if err := srv.SendNotificationToClient(ctx, "notifications/progress", params); err != nil {
+		slog.Debug("failed to send progress notification", "error", err)
+	}
+}
@@ -0,0 +1,46 @@
+package mcprey
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/mark3labs/mcp-go/server"
+)
+
+type progressParams struct{}
+
+func TestReportProgress(t *testing.T) {
+	srv := server.NewMCPServer("test", "0.0.0")
+	tool := MustTool("test.progress", "Report progress.", func(ctx context.Context, _ progressParams) (string, error) {
+		ReportProgress(ctx, 1, 2, "halfway")
+		return "done", nil
+	})
+	tool.Register(srv)
+
+	session := newTestSession("s1")
+	ctx := srv.WithContext(context.Background(), session)
+	handle(ctx, srv, map[string]any{
+		"jsonrpc": "2.0", "id": 1, "method": "tools/call",
+		"params": map[string]any{"name": "test.progress", "_meta": map[string]any{"progressToken": "tok"}},
+	})
+	select {
+	case n := <-session.notifications:
+		fields := n.Params.AdditionalFields
+		if n.Method != "notifications/progress" || fields["progressToken"] != "tok" || fields["progress"] != 1.0 || fields["total"] != 2.0 || fields["message"] != "halfway" {
+			t.Fatalf("unexpected notification: %+v", n)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("expected a progress notification")
+	}
+
+	handle(ctx, srv, map[string]any{
+		"jsonrpc": "2.0", "id": 2, "method": "tools/call",
+		"params": map[string]any{"name": "test.progress"},
+	})
+	select {
+	case n := <-session.notifications:
+		t.Fatalf("expected no notification without a progress token, got %+v", n)
+	default:
+	}
+}
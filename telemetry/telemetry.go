@@ -0,0 +1,149 @@
+// Package telemetry configures the OpenTelemetry tracer provider from the standard
+// OTEL_* environment variables.
+package telemetry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.39.0"
+)
+
+const (
+	sdkDisabledEnvVar    = "OTEL_SDK_DISABLED"
+	tracesExporterEnvVar = "OTEL_TRACES_EXPORTER"
+	endpointEnvVar       = "OTEL_EXPORTER_OTLP_ENDPOINT"
+	tracesEndpointEnvVar = "OTEL_EXPORTER_OTLP_TRACES_ENDPOINT"
+	protocolEnvVar       = "OTEL_EXPORTER_OTLP_PROTOCOL"
+	tracesProtocolEnvVar = "OTEL_EXPORTER_OTLP_TRACES_PROTOCOL"
+	// tracesFileEnvVar names a file that the console exporter writes to instead of stderr.
+	tracesFileEnvVar = "PREY_OTEL_TRACES_FILE"
+
+	serviceName = "mcp-prey"
+)
+
+// Exporter names accepted in OTEL_TRACES_EXPORTER.
+const (
+	ExporterOTLP    = "otlp"
+	ExporterConsole = "console"
+	ExporterNone    = "none"
+)
+
+// Shutdown flushes pending spans and stops the exporters.
+type Shutdown func(context.Context) error
+
+// Setup installs a global tracer provider and the W3C trace context and baggage
+// propagators. Exporters come from OTEL_TRACES_EXPORTER (comma-separated "otlp",
+// "console" or "none"); when it is unset, OTLP is used if an OTLP endpoint is
+// configured, the console exporter if PREY_OTEL_TRACES_FILE is set, and tracing stays
+// off otherwise. The OTLP exporter reads its endpoint, headers and TLS settings from
+// the standard variables; the console exporter writes to stderr, never stdout, which
+// carries the stdio transport.
+func Setup(ctx context.Context) (Shutdown, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+	noop := func(context.Context) error { return nil }
+	if strings.EqualFold(strings.TrimSpace(os.Getenv(sdkDisabledEnvVar)), "true") {
+		return noop, nil
+	}
+
+	var opts []sdktrace.TracerProviderOption
+	var closers []io.Closer
+	for _, name := range exporterNames() {
+		switch name {
+		case ExporterNone:
+		case ExporterOTLP:
+			exp, err := otlpExporter(ctx)
+			if err != nil {
+				return nil, err
+			}
+			opts = append(opts, sdktrace.WithBatcher(exp))
+		case ExporterConsole:
+			var w io.Writer = os.Stderr
+			if path := strings.TrimSpace(os.Getenv(tracesFileEnvVar)); path != "" {
+				f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+				if err != nil {
+					return nil, fmt.Errorf("open traces file: %w", err)
+				}
+				closers = append(closers, f)
+				w = f
+			}
+			exp, err := stdouttrace.New(stdouttrace.WithWriter(w))
+			if err != nil {
+				return nil, err
+			}
+			opts = append(opts, sdktrace.WithSyncer(exp))
+		default:
+			return nil, fmt.Errorf("unsupported %s value %q: want otlp, console or none", tracesExporterEnvVar, name)
+		}
+	}
+	if len(opts) == 0 {
+		return noop, nil
+	}
+
+	res, err := resource.New(ctx,
+		resource.WithAttributes(semconv.ServiceName(serviceName)),
+		resource.WithFromEnv(),
+		resource.WithTelemetrySDK(),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("build telemetry resource: %w", err)
+	}
+	tp := sdktrace.NewTracerProvider(append(opts, sdktrace.WithResource(res))...)
+	otel.SetTracerProvider(tp)
+	return func(ctx context.Context) error {
+		err := tp.Shutdown(ctx)
+		for _, c := range closers {
+			err = errors.Join(err, c.Close())
+		}
+		return err
+	}, nil
+}
+
+func exporterNames() []string {
+	val := strings.TrimSpace(os.Getenv(tracesExporterEnvVar))
+	if val == "" {
+		var names []string
+		if os.Getenv(endpointEnvVar) != "" || os.Getenv(tracesEndpointEnvVar) != "" {
+			names = append(names, ExporterOTLP)
+		}
+		if os.Getenv(tracesFileEnvVar) != "" {
+			names = append(names, ExporterConsole)
+		}
+		return names
+	}
+	var names []string
+	for _, name := range strings.Split(val, ",") {
+		if name = strings.ToLower(strings.TrimSpace(name)); name != "" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// otlpExporter picks the transport from OTEL_EXPORTER_OTLP_(TRACES_)PROTOCOL,
+// defaulting to http/protobuf as the specification does.
+func otlpExporter(ctx context.Context) (sdktrace.SpanExporter, error) {
+	protocol := strings.TrimSpace(os.Getenv(tracesProtocolEnvVar))
+	if protocol == "" {
+		protocol = strings.TrimSpace(os.Getenv(protocolEnvVar))
+	}
+	switch protocol {
+	case "", "http/protobuf":
+		return otlptracehttp.New(ctx)
+	case "grpc":
+		return otlptracegrpc.New(ctx)
+	default:
+		return nil, fmt.Errorf("unsupported OTLP protocol %q: want http/protobuf or grpc", protocol)
+	}
+}
@@ -0,0 +1,63 @@
+package telemetry
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+)
+
+func TestExporterNames(t *testing.T) {
+	t.Setenv(tracesExporterEnvVar, "")
+	t.Setenv(endpointEnvVar, "")
+	t.Setenv(tracesEndpointEnvVar, "")
+	t.Setenv(tracesFileEnvVar, "")
+	if names := exporterNames(); len(names) != 0 {
+		t.Fatalf("expected tracing off by default, got %v", names)
+	}
+	t.Setenv(endpointEnvVar, "http://collector:4318")
+	if names := exporterNames(); !reflect.DeepEqual(names, []string{ExporterOTLP}) {
+		t.Fatalf("expected otlp with an endpoint, got %v", names)
+	}
+	t.Setenv(tracesExporterEnvVar, "OTLP, console")
+	if names := exporterNames(); !reflect.DeepEqual(names, []string{ExporterOTLP, ExporterConsole}) {
+		t.Fatalf("expected explicit exporters, got %v", names)
+	}
+}
+
+func TestSetupFileExporter(t *testing.T) {
+	prev := otel.GetTracerProvider()
+	defer otel.SetTracerProvider(prev)
+	path := filepath.Join(t.TempDir(), "traces.json")
+	t.Setenv(tracesExporterEnvVar, "")
+	t.Setenv(endpointEnvVar, "")
+	t.Setenv(tracesEndpointEnvVar, "")
+	t.Setenv(tracesFileEnvVar, path)
+
+	shutdown, err := Setup(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	_, span := otel.Tracer("mcp-prey").Start(context.Background(), "mcp.tool.prey.account.get")
+	span.End()
+	if err := shutdown(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(b) == 0 {
+		t.Fatalf("expected spans in %s", path)
+	}
+}
+
+func TestSetupRejectsUnknownExporter(t *testing.T) {
+	t.Setenv(tracesExporterEnvVar, "zipkin")
+	if _, err := Setup(context.Background()); err == nil {
+		t.Fatalf("expected error for unsupported exporter")
+	}
+}
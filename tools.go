@@ -76,9 +76,11 @@ func ConvertTool[T any, R any](name, description string, toolHandler ToolHandler
 	if argType.Kind() != reflect.Struct {
 		return zero, nil, errors.New("tool handler second argument must be a struct")
 	}
+	outputType := structuredOutputType(handlerType.Out(0))
 
 	handler := func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 		config := prey.ConfigFromContext(ctx)
+		ctx = withProgressToken(ctx, request)
 		ctx, span := otel.Tracer("mcp-prey").Start(ctx, fmt.Sprintf("mcp.tool.%s", name))
 		defer span.End()
 		span.SetAttributes(
@@ -194,6 +196,9 @@ func ConvertTool[T any, R any](name, description string, toolHandler ToolHandler
 			return nil, fmt.Errorf("failed to marshal return value: %s", err)
 		}
 
+		if outputType != nil {
+			return mcp.NewToolResultStructured(returnVal, string(returnBytes)), nil
+		}
 		return mcp.NewToolResultText(string(returnBytes)), nil
 	}
 
@@ -219,6 +224,13 @@ func ConvertTool[T any, R any](name, description string, toolHandler ToolHandler
 		Description:    description,
 		RawInputSchema: schemaBytes,
 	}
+	if outputType != nil {
+		outputSchemaBytes, err := createOutputSchema(outputType)
+		if err != nil {
+			return zero, nil, fmt.Errorf("failed to marshal output schema: %w", err)
+		}
+		t.RawOutputSchema = outputSchemaBytes
+	}
 	for _, option := range options {
 		option(&t)
 	}
@@ -233,6 +245,35 @@ func createJSONSchemaFromHandler(handler any) *jsonschema.Schema {
 	return inputSchema
 }
 
+// structuredOutputType returns the struct type described by a handler's result
+// type, or nil when the handler does not return a concrete struct.
+func structuredOutputType(returnType reflect.Type) reflect.Type {
+	if returnType.Kind() == reflect.Ptr {
+		returnType = returnType.Elem()
+	}
+	if returnType.Kind() != reflect.Struct {
+		return nil
+	}
+	if returnType == reflect.TypeOf(mcp.CallToolResult{}) {
+		return nil
+	}
+	return returnType
+}
+
+func createOutputSchema(outputType reflect.Type) ([]byte, error) {
+	jsonSchema := jsonSchemaReflector.ReflectFromType(outputType)
+	properties := make(map[string]any, jsonSchema.Properties.Len())
+	for pair := jsonSchema.Properties.Oldest(); pair != nil; pair = pair.Next() {
+		properties[pair.Key] = pair.Value
+	}
+	// MCP requires output schemas to describe an object.
+	return json.Marshal(mcp.ToolOutputSchema{
+		Type:       "object",
+		Properties: properties,
+		Required:   jsonSchema.Required,
+	})
+}
+
 var (
 	jsonSchemaReflector = jsonschema.Reflector{
 		BaseSchemaID:               "",
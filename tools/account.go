@@ -45,4 +45,5 @@ var AccountGet = mcprey.MustTool(
 
 func AddAccountTools(m *server.MCPServer) {
 	AccountGet.Register(m)
+	RateLimitStatusTool.Register(m)
 }
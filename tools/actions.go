@@ -2,6 +2,7 @@ package tools
 
 import (
 	"context"
+	"fmt"
 	"net/http"
 	"net/url"
 
@@ -18,11 +19,13 @@ type DeviceActionTriggerParams struct {
 	Command    string         `json:"command" jsonschema:"description=Command to execute (start)"`
 	ActionName string         `json:"action_name" jsonschema:"description=Action name (alarm|alert|lock)"`
 	Options    map[string]any `json:"options,omitempty" jsonschema:"description=Action options"`
+	WriteOptions
 }
 
 type DeviceStatusSetParams struct {
 	DeviceID string `json:"deviceId" jsonschema:"description=ID of the device"`
 	Missing  bool   `json:"missing" jsonschema:"description=true to mark missing, false to recover"`
+	WriteOptions
 }
 
 func deviceActionTrigger(ctx context.Context, args DeviceActionTriggerParams) (any, error) {
@@ -42,6 +45,12 @@ func deviceActionTrigger(ctx context.Context, args DeviceActionTriggerParams) (a
 	if client == nil {
 		return nil, &mcprey.HardError{Err: prey.ErrMissingClient}
 	}
+	summary := func() string {
+		return fmt.Sprintf("Trigger %s on %s.", args.ActionName, describeDevice(ctx, client, args.DeviceID))
+	}
+	if err := confirmWrite(ctx, "prey.devices.action.trigger", args.ActionName == "lock", summary); err != nil {
+		return nil, err
+	}
 	body := map[string]any{
 		"command":     args.Command,
 		"action_name": args.ActionName,
@@ -57,7 +66,7 @@ func deviceActionTrigger(ctx context.Context, args DeviceActionTriggerParams) (a
 	if err := client.DoJSON(req.WithContext(ctx), &payload); err != nil {
 		return nil, err
 	}
-	return internal.Wrap(internal.MaskSensitive(payload), nil), nil
+	return internal.Wrap(maskDeviceData(ctx, client, args.DeviceID, payload), nil), nil
 }
 
 func deviceStatusSet(ctx context.Context, args DeviceStatusSetParams) (any, error) {
@@ -71,6 +80,16 @@ func deviceStatusSet(ctx context.Context, args DeviceStatusSetParams) (any, erro
 	if client == nil {
 		return nil, &mcprey.HardError{Err: prey.ErrMissingClient}
 	}
+	summary := func() string {
+		status := "recovered"
+		if args.Missing {
+			status = "missing"
+		}
+		return fmt.Sprintf("Mark %s as %s.", describeDevice(ctx, client, args.DeviceID), status)
+	}
+	if err := confirmWrite(ctx, "prey.devices.status.set", false, summary); err != nil {
+		return nil, err
+	}
 	body := map[string]any{"missing": args.Missing}
 	var payload any
 	req, err := client.NewRequest(http.MethodPut, "/devices/"+args.DeviceID+"/missing", url.Values{}, body)
@@ -80,7 +99,7 @@ func deviceStatusSet(ctx context.Context, args DeviceStatusSetParams) (any, erro
 	if err := client.DoJSON(req.WithContext(ctx), &payload); err != nil {
 		return nil, err
 	}
-	return internal.Wrap(internal.MaskSensitive(payload), nil), nil
+	return internal.Wrap(maskDeviceData(ctx, client, args.DeviceID, payload), nil), nil
 }
 
 var DeviceActionTrigger = mcprey.MustTool(
@@ -0,0 +1,329 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+
+	mcprey "mcp-prey"
+	"mcp-prey/approval"
+	"mcp-prey/auth"
+	"mcp-prey/internal"
+	"mcp-prey/prey"
+)
+
+// Approvals holds destructive calls until a second caller approves them.
+type Approvals struct {
+	store *approval.Store
+
+	// configs keeps the requester's Prey configuration of pending requests in memory,
+	// so API keys are never written to the store.
+	mu      sync.Mutex
+	configs map[string]prey.Config
+}
+
+func NewApprovals(store *approval.Store) *Approvals {
+	return &Approvals{store: store, configs: map[string]prey.Config{}}
+}
+
+type approvedKey struct{}
+
+// queuedKey holds a function told the ID of the approval request a call was queued as.
+type queuedKey struct{}
+
+// withQueuedObserver returns a context whose calls report to fn when they are queued
+// for approval instead of run.
+func withQueuedObserver(ctx context.Context, fn func(id string)) context.Context {
+	return context.WithValue(ctx, queuedKey{}, fn)
+}
+
+// approvalID returns the ID of the approved request ctx executes, if any.
+func approvalID(ctx context.Context) string {
+	req, _ := ctx.Value(approvedKey{}).(approval.Request)
+	return req.ID
+}
+
+// requiresApproval reports whether a call needs a second caller's approval.
+func requiresApproval(toolName string, args map[string]any) bool {
+	switch toolName {
+	case "prey.devices.delete":
+		return true
+	case "prey.devices.action.trigger":
+		return args["action_name"] == "lock"
+	}
+	return false
+}
+
+// callerName names the caller for approvals: the identity subject, or "stdio".
+func callerName(ctx context.Context) string {
+	id, ok := auth.IdentityFromContext(ctx)
+	if !ok {
+		return ""
+	}
+	if id.Subject == "" && id.Method == auth.MethodStdio {
+		return auth.MethodStdio
+	}
+	return id.Subject
+}
+
+// Middleware turns calls requiring approval into pending requests, unless the call
+// is the execution of an approved request.
+func (a *Approvals) Middleware(next server.ToolHandlerFunc) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		args := request.GetArguments()
+		if !requiresApproval(request.Params.Name, args) || prey.IsDryRun(ctx) {
+			return next(ctx, request)
+		}
+		if approved, ok := ctx.Value(approvedKey{}).(approval.Request); ok && approved.Tool == request.Params.Name {
+			return next(ctx, request)
+		}
+		// Refuse calls the requester could not run anyway before queueing them.
+		if err := ensureToolAllowed(ctx, request.Params.Name, true); err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+		// Only an authenticated HTTP caller can be told apart from its approver; any
+		// other request would stay pending forever.
+		requester := callerName(ctx)
+		if id, _ := auth.IdentityFromContext(ctx); requester == "" || id.Method == auth.MethodStdio {
+			return mcp.NewToolResultError(fmt.Sprintf(
+				"%s was not run: it requires approval by a second caller, which needs authenticated callers on an HTTP transport",
+				request.Params.Name,
+			)), nil
+		}
+		cfg := prey.ConfigFromContext(ctx)
+		req, err := a.store.Create(approval.Request{
+			Tool:      request.Params.Name,
+			Arguments: args,
+			Requester: requester,
+			Summary:   approvalSummary(ctx, request.Params.Name, args),
+			Profile:   cfg.Profile,
+			Upstream:  cfg.URL,
+		})
+		if err != nil {
+			return nil, err
+		}
+		a.keepConfig(req.ID, cfg)
+		if queued, ok := ctx.Value(queuedKey{}).(func(string)); ok {
+			queued(req.ID)
+		}
+		b, err := json.Marshal(req)
+		if err != nil {
+			return nil, err
+		}
+		return mcp.NewToolResultText(fmt.Sprintf(
+			"Approval required: %s is pending as request %s until %s. Another authorised caller must approve it with prey.approvals.approve.\n%s",
+			request.Params.Name, req.ID, req.ExpiresAt.Format("2006-01-02T15:04:05Z07:00"), b,
+		)), nil
+	}
+}
+
+func approvalSummary(ctx context.Context, toolName string, args map[string]any) string {
+	deviceID, _ := args["deviceId"].(string)
+	target := fmt.Sprintf("device %s", deviceID)
+	if client := prey.ClientFromContext(ctx); client != nil && deviceID != "" {
+		target = describeDevice(ctx, client, deviceID)
+	}
+	if toolName == "prey.devices.delete" {
+		return "Delete " + target
+	}
+	return "Lock " + target
+}
+
+// keepConfig remembers the requester's configuration for a new request and forgets
+// those of requests that are no longer pending.
+func (a *Approvals) keepConfig(id string, cfg prey.Config) {
+	pending := map[string]bool{}
+	for _, r := range a.store.Pending() {
+		pending[r.ID] = true
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	for known := range a.configs {
+		if !pending[known] {
+			delete(a.configs, known)
+		}
+	}
+	a.configs[id] = cfg
+}
+
+// requesterConfig returns the Prey configuration the requester queued req with. After
+// a restart it is resolved again from the stored profile, which only works for the
+// profile's own upstream: API keys sent with a request are not stored.
+func (a *Approvals) requesterConfig(req approval.Request) (prey.Config, error) {
+	a.mu.Lock()
+	cfg, ok := a.configs[req.ID]
+	a.mu.Unlock()
+	if ok {
+		return cfg, nil
+	}
+	cfg, err := prey.ProfileConfig(req.Profile)
+	if err != nil {
+		return prey.Config{}, fmt.Errorf("approval request %s: %w", req.ID, err)
+	}
+	if cfg.URL != req.Upstream {
+		return prey.Config{}, fmt.Errorf("approval request %s targets %s, whose credentials are no longer available: the requester must submit it again", req.ID, req.Upstream)
+	}
+	return cfg, nil
+}
+
+// approve approves a pending request as the caller and runs it through the server
+// with the requester's Prey configuration. The approver's allowlists and roles must
+// allow the tool too, and auditing records the approver. A failed call returns the
+// request to pending.
+func (a *Approvals) approve(ctx context.Context, id string) (*mcp.CallToolResult, error) {
+	srv := server.ServerFromContext(ctx)
+	if srv == nil {
+		return nil, &mcprey.HardError{Err: fmt.Errorf("approvals require a server in context")}
+	}
+	pending := a.pending(id)
+	if pending == nil {
+		return nil, approval.ErrNotFound
+	}
+	if err := ensureToolAllowed(ctx, pending.Tool, true); err != nil {
+		return nil, err
+	}
+	cfg, err := a.requesterConfig(*pending)
+	if err != nil {
+		return nil, err
+	}
+	req, err := a.store.Approve(id, callerName(ctx))
+	if err != nil {
+		return nil, err
+	}
+	ctx = prey.PinConfig(prey.WithClient(prey.WithConfig(ctx, cfg), prey.NewClient(cfg)))
+	ctx = context.WithValue(ctx, approvedKey{}, req)
+	result, err := a.run(ctx, srv, req)
+	if err != nil || result.IsError {
+		if rerr := a.store.Release(req.ID); rerr != nil {
+			slog.Error("failed to release approval request", "id", req.ID, "error", rerr)
+		}
+	}
+	return result, err
+}
+
+func (a *Approvals) run(ctx context.Context, srv *server.MCPServer, req approval.Request) (*mcp.CallToolResult, error) {
+	switch resp := callTool(ctx, srv, "approval-"+req.ID, req.Tool, req.Arguments).(type) {
+	case mcp.JSONRPCResponse:
+		if result, ok := resp.Result.(mcp.CallToolResult); ok {
+			return &result, nil
+		}
+		return nil, fmt.Errorf("unexpected result for approved %s", req.Tool)
+	case mcp.JSONRPCError:
+		return nil, fmt.Errorf("approved %s failed: %s", req.Tool, resp.Error.Message)
+	default:
+		return nil, fmt.Errorf("unexpected response for approved %s", req.Tool)
+	}
+}
+
+// callTool runs a tools/call through s, so hooks and middleware apply as for client calls.
+func callTool(ctx context.Context, s *server.MCPServer, id, name string, args map[string]any) mcp.JSONRPCMessage {
+	msg, err := json.Marshal(mcp.JSONRPCRequest{
+		JSONRPC: mcp.JSONRPC_VERSION,
+		ID:      mcp.NewRequestId(id),
+		Request: mcp.Request{Method: string(mcp.MethodToolsCall)},
+		Params:  map[string]any{"name": name, "arguments": args},
+	})
+	if err != nil {
+		return mcp.NewJSONRPCError(mcp.NewRequestId(id), mcp.INTERNAL_ERROR, err.Error(), nil)
+	}
+	return s.HandleMessage(ctx, msg)
+}
+
+func (a *Approvals) pending(id string) *approval.Request {
+	for _, r := range a.store.Pending() {
+		if r.ID == id {
+			return &r
+		}
+	}
+	return nil
+}
+
+type ApprovalsListParams struct{}
+
+type ApprovalsApproveParams struct {
+	ID string `json:"id" jsonschema:"description=ID of the pending approval request"`
+}
+
+func ApprovalsListTool(a *Approvals) mcprey.Tool {
+	return mcprey.MustTool(
+		"prey.approvals.list",
+		"List pending approval requests for destructive actions.",
+		func(_ context.Context, _ ApprovalsListParams) (*internal.Envelope[[]approval.Request], error) {
+			return internal.NewEnvelope(a.store.Pending(), nil), nil
+		},
+		mcp.WithTitleAnnotation("List approval requests"),
+		mcp.WithIdempotentHintAnnotation(true),
+		mcp.WithReadOnlyHintAnnotation(true),
+	)
+}
+
+func ApprovalsApproveTool(a *Approvals) mcprey.Tool {
+	return mcprey.MustTool(
+		"prey.approvals.approve",
+		"Approve a pending request created by another caller and run it (write).",
+		func(ctx context.Context, args ApprovalsApproveParams) (*mcp.CallToolResult, error) {
+			// Approving consumes the request, which a dry run must not do.
+			if prey.IsDryRun(ctx) {
+				return nil, fmt.Errorf("prey.approvals.approve cannot be dry-run")
+			}
+			if err := ensureToolAllowed(ctx, "prey.approvals.approve", true); err != nil {
+				return nil, err
+			}
+			if err := internal.RequireID(args.ID, "id"); err != nil {
+				return nil, err
+			}
+			return a.approve(ctx, args.ID)
+		},
+		mcp.WithTitleAnnotation("Approve request"),
+	)
+}
+
+// Register adds the approval tools to s.
+func (a *Approvals) Register(s *server.MCPServer) {
+	list, approve := ApprovalsListTool(a), ApprovalsApproveTool(a)
+	list.Register(s)
+	approve.Register(s)
+}
+
+// HTTPHandler serves the admin endpoints: GET {prefix} lists pending requests and
+// POST {prefix}{id}/approve approves one. contextFunc builds the Prey context for the
+// request, as for MCP calls; callers are authenticated by the surrounding middleware.
+func (a *Approvals) HTTPHandler(s *server.MCPServer, prefix string, contextFunc func(context.Context, *http.Request) context.Context) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rest := strings.TrimPrefix(r.URL.Path, prefix)
+		switch {
+		case rest == "" && r.Method == http.MethodGet:
+			writeJSON(w, http.StatusOK, internal.NewEnvelope(a.store.Pending(), nil))
+		case strings.HasSuffix(rest, "/approve") && r.Method == http.MethodPost:
+			id := strings.TrimSuffix(rest, "/approve")
+			ctx := contextFunc(r.Context(), r)
+			switch resp := callTool(ctx, s, "admin-approve-"+id, "prey.approvals.approve", map[string]any{"id": id}).(type) {
+			case mcp.JSONRPCResponse:
+				status := http.StatusOK
+				if result, ok := resp.Result.(mcp.CallToolResult); ok && result.IsError {
+					status = http.StatusConflict
+				}
+				writeJSON(w, status, resp.Result)
+			case mcp.JSONRPCError:
+				writeJSON(w, http.StatusBadGateway, resp.Error)
+			default:
+				writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "unexpected response"})
+			}
+		default:
+			http.NotFound(w, r)
+		}
+	})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,153 @@
+package tools
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+
+	"mcp-prey/approval"
+	"mcp-prey/auth"
+	"mcp-prey/prey"
+)
+
+func TestApprovalsTwoPerson(t *testing.T) {
+	var deletes atomic.Int32
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodDelete {
+			deletes.Add(1)
+		}
+		_, _ = w.Write([]byte(`{"id":"42","name":"Laptop"}`))
+	}))
+	defer upstream.Close()
+
+	store, err := approval.Open(filepath.Join(t.TempDir(), "approvals.json"), time.Hour)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	approvals := NewApprovals(store)
+	s := server.NewMCPServer("test", "0.0.0",
+		server.WithToolCapabilities(true),
+		server.WithToolHandlerMiddleware(approvals.Middleware),
+	)
+	DevicesDelete.Register(s)
+	approvals.Register(s)
+
+	cfg := prey.Config{URL: upstream.URL, APIKey: "key", AllowWrite: true, Timeout: time.Second, DisableRateLimit: true}
+	base := prey.WithClient(prey.WithConfig(context.Background(), cfg), prey.NewClient(cfg))
+	as := func(subject string) context.Context {
+		return auth.WithIdentity(base, auth.Identity{Subject: subject, Method: auth.MethodBearer})
+	}
+	call := func(ctx context.Context, name string, args map[string]any) mcp.CallToolResult {
+		resp, ok := callTool(ctx, s, "1", name, args).(mcp.JSONRPCResponse)
+		if !ok {
+			t.Fatalf("expected a result for %s", name)
+		}
+		return resp.Result.(mcp.CallToolResult)
+	}
+
+	// Without an identity nobody could approve the request, so it is refused.
+	if res := call(base, "prey.devices.delete", map[string]any{"deviceId": "42"}); !res.IsError || len(store.Pending()) != 0 {
+		t.Fatalf("expected an unauthenticated call to be refused, got %+v", res)
+	}
+	stdio := auth.WithIdentity(base, auth.Identity{Subject: auth.MethodStdio, Method: auth.MethodStdio})
+	if res := call(stdio, "prey.devices.delete", map[string]any{"deviceId": "42"}); !res.IsError || len(store.Pending()) != 0 {
+		t.Fatalf("expected a stdio call to be refused, got %+v", res)
+	}
+
+	res := call(as("alice"), "prey.devices.delete", map[string]any{"deviceId": "42"})
+	if res.IsError || deletes.Load() != 0 {
+		t.Fatalf("expected delete to be queued, got %+v", res)
+	}
+	pending := store.Pending()
+	if len(pending) != 1 || pending[0].Requester != "alice" || !strings.Contains(pending[0].Summary, "Laptop") {
+		t.Fatalf("unexpected pending requests: %+v", pending)
+	}
+
+	if res := call(as("alice"), "prey.approvals.approve", map[string]any{"id": pending[0].ID}); !res.IsError {
+		t.Fatalf("expected requester to be unable to approve")
+	}
+	if res := call(as("bob"), "prey.approvals.approve", map[string]any{"id": pending[0].ID}); res.IsError {
+		t.Fatalf("expected approval to run the delete, got %+v", res)
+	}
+	if deletes.Load() != 1 {
+		t.Fatalf("expected one upstream delete, got %d", deletes.Load())
+	}
+}
+
+func TestApprovalsRunWithRequesterConfig(t *testing.T) {
+	var requesterDeletes, approverDeletes atomic.Int32
+	requesterUpstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodDelete {
+			// The first attempt fails upstream.
+			if requesterDeletes.Add(1) == 1 {
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+		}
+		_, _ = w.Write([]byte(`{"id":"42","name":"Laptop"}`))
+	}))
+	defer requesterUpstream.Close()
+	approverUpstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodDelete {
+			approverDeletes.Add(1)
+		}
+		_, _ = w.Write([]byte(`{}`))
+	}))
+	defer approverUpstream.Close()
+
+	store, err := approval.Open(filepath.Join(t.TempDir(), "approvals.json"), time.Hour)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	approvals := NewApprovals(store)
+	s := server.NewMCPServer("test", "0.0.0",
+		server.WithToolCapabilities(true),
+		server.WithToolHandlerMiddleware(approvals.Middleware),
+	)
+	DevicesDelete.Register(s)
+	approvals.Register(s)
+
+	as := func(subject, upstream string) context.Context {
+		cfg := prey.Config{URL: upstream, APIKey: subject + "-key", AllowWrite: true, Timeout: time.Second, DisableRateLimit: true}
+		ctx := prey.WithClient(prey.WithConfig(context.Background(), cfg), prey.NewClient(cfg))
+		return auth.WithIdentity(ctx, auth.Identity{Subject: subject, Method: auth.MethodBearer})
+	}
+	call := func(ctx context.Context, name string, args map[string]any) mcp.CallToolResult {
+		resp, ok := callTool(ctx, s, "1", name, args).(mcp.JSONRPCResponse)
+		if !ok {
+			t.Fatalf("expected a result for %s", name)
+		}
+		return resp.Result.(mcp.CallToolResult)
+	}
+
+	call(as("alice", requesterUpstream.URL), "prey.devices.delete", map[string]any{"deviceId": "42"})
+	pending := store.Pending()
+	if len(pending) != 1 || pending[0].Upstream != requesterUpstream.URL {
+		t.Fatalf("unexpected pending requests: %+v", pending)
+	}
+	id := pending[0].ID
+
+	if res := call(as("bob", approverUpstream.URL), "prey.approvals.approve", map[string]any{"id": id}); !res.IsError {
+		t.Fatalf("expected the failed delete to be reported, got %+v", res)
+	}
+	if len(store.Pending()) != 1 {
+		t.Fatalf("expected a failed call to leave the request pending")
+	}
+	if res := call(as("bob", approverUpstream.URL), "prey.approvals.approve", map[string]any{"id": id}); res.IsError {
+		t.Fatalf("expected approval to run the delete, got %+v", res)
+	}
+	if requesterDeletes.Load() != 2 || approverDeletes.Load() != 0 {
+		t.Fatalf("expected deletes on the requester's upstream only, got %d and %d", requesterDeletes.Load(), approverDeletes.Load())
+	}
+	if len(store.Pending()) != 0 {
+		t.Fatalf("expected a successful call to consume the request")
+	}
+}
@@ -0,0 +1,132 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+
+	"mcp-prey/audit"
+	"mcp-prey/auth"
+	"mcp-prey/internal"
+	"mcp-prey/prey"
+)
+
+// auditTargetKeys are the tool arguments naming the objects a write acts on.
+var auditTargetKeys = append([]string{"zoneId", "labelId"}, deviceArgumentKeys...)
+
+// Audit records every write tool invocation to an audit log.
+type Audit struct {
+	logger *audit.Logger
+}
+
+func NewAudit(logger *audit.Logger) *Audit {
+	return &Audit{logger: logger}
+}
+
+// Middleware audits write tool calls, including calls refused by later checks. Dry runs
+// are marked as such, calls queued for approval are recorded as pending, and the
+// upstream status is that of the last write request, not of the lookups around it.
+// When the logger fails closed, a call runs only once its start has been recorded.
+func (a *Audit) Middleware(next server.ToolHandlerFunc) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		if !callIsWrite(ctx, request.Params.Name) {
+			return next(ctx, request)
+		}
+		start := time.Now()
+		entry := auditEntry(ctx, request, start)
+		if a.logger.FailClosed() {
+			started := entry
+			started.Result = audit.ResultStarted
+			if err := a.logger.Write(started); err != nil {
+				slog.Error("failed to write audit entry, refusing the call", "tool", entry.Tool, "error", err)
+				return mcp.NewToolResultError(fmt.Sprintf("%s was not run: the audit log cannot be written", request.Params.Name)), nil
+			}
+		}
+
+		var mu sync.Mutex
+		status := 0
+		queuedID := ""
+		ctx = withQueuedObserver(ctx, func(id string) {
+			mu.Lock()
+			queuedID = id
+			mu.Unlock()
+		})
+		ctx = prey.WithRequestObserver(ctx, func(info prey.RequestInfo) {
+			if info.Request == nil || !prey.IsWriteMethod(info.Request.Method) {
+				return
+			}
+			mu.Lock()
+			status = info.Status
+			mu.Unlock()
+		})
+		result, err := next(ctx, request)
+
+		entry.DurationMs = time.Since(start).Milliseconds()
+		entry.Result = audit.ResultOK
+		mu.Lock()
+		entry.UpstreamStatus = status
+		if queuedID != "" {
+			entry.ApprovalID = queuedID
+		}
+		mu.Unlock()
+		switch {
+		case queuedID != "" && err == nil:
+			entry.Result = audit.ResultPending
+		case err != nil:
+			entry.Result, entry.Error = audit.ResultError, err.Error()
+		case result != nil && result.IsError:
+			entry.Result, entry.Error = audit.ResultError, resultText(result)
+		}
+		if werr := a.logger.Write(entry); werr != nil {
+			slog.Error("failed to write audit entry", "tool", entry.Tool, "error", werr)
+		}
+		return result, err
+	}
+}
+
+// auditEntry describes the call, without its outcome.
+func auditEntry(ctx context.Context, request mcp.CallToolRequest, start time.Time) audit.Entry {
+	entry := audit.Entry{
+		Time:       start.UTC().Format(time.RFC3339Nano),
+		Tool:       request.Params.Name,
+		Targets:    argumentIDs(request.GetArguments(), auditTargetKeys),
+		DryRun:     callIsDryRun(ctx, request),
+		ApprovalID: approvalID(ctx),
+	}
+	if id, ok := auth.IdentityFromContext(ctx); ok {
+		entry.Subject, entry.AuthMethod = id.Subject, id.Method
+	}
+	if session := server.ClientSessionFromContext(ctx); session != nil {
+		entry.Session = session.SessionID()
+	}
+	if args := request.GetArguments(); len(args) > 0 {
+		entry.Arguments, _ = json.Marshal(internal.MaskSensitive(args))
+	}
+	return entry
+}
+
+// callIsWrite reports whether toolName is a registered write tool. Unknown tools
+// count as writes so they are audited.
+func callIsWrite(ctx context.Context, toolName string) bool {
+	if srv := server.ServerFromContext(ctx); srv != nil {
+		if tool := srv.GetTool(toolName); tool != nil {
+			return isWriteTool(tool.Tool)
+		}
+	}
+	return true
+}
+
+func resultText(result *mcp.CallToolResult) string {
+	for _, c := range result.Content {
+		if text, ok := c.(mcp.TextContent); ok {
+			return text.Text
+		}
+	}
+	return ""
+}
@@ -0,0 +1,217 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+
+	"mcp-prey/approval"
+	"mcp-prey/audit"
+	"mcp-prey/auth"
+	"mcp-prey/prey"
+)
+
+func TestAuditMiddleware(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			_, _ = w.Write([]byte(`{"id":42}`))
+			return
+		}
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer srv.Close()
+
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	logger, err := audit.Open(path, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer logger.Close()
+
+	client := prey.NewClient(prey.Config{URL: srv.URL, APIKey: "key", Timeout: time.Second, DisableRateLimit: true})
+	ctx := auth.WithIdentity(context.Background(), auth.Identity{Subject: "alice", Method: auth.MethodBearer})
+	req := mcp.CallToolRequest{}
+	req.Params.Name = "prey.devices.delete"
+	req.Params.Arguments = map[string]any{"deviceId": "42", "api_key": "secret"}
+
+	handler := NewAudit(logger).Middleware(func(ctx context.Context, _ mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		r, _ := client.NewRequest(http.MethodDelete, "/devices/42", nil, nil)
+		err := client.DoJSON(r.WithContext(ctx), nil)
+		// A lookup after the write must not replace its status.
+		lookup, _ := client.NewRequest(http.MethodGet, "/devices/42", nil, nil)
+		_ = client.DoJSON(lookup.WithContext(ctx), nil)
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+		return mcp.NewToolResultText("ok"), nil
+	})
+	if _, err := handler(ctx, req); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	last, err := audit.Verify(strings.NewReader(string(b)), nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if last.Subject != "alice" || last.UpstreamStatus != http.StatusNotFound || last.Result != audit.ResultError {
+		t.Fatalf("unexpected entry: %+v", last)
+	}
+	if len(last.Targets) != 1 || last.Targets[0] != "42" {
+		t.Fatalf("unexpected targets: %v", last.Targets)
+	}
+	if strings.Contains(string(b), "secret") {
+		t.Fatalf("expected sensitive arguments to be masked")
+	}
+}
+
+func TestAuditMiddlewareDryRun(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	logger, err := audit.Open(path, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer logger.Close()
+
+	req := mcp.CallToolRequest{}
+	req.Params.Name = "prey.devices.delete"
+	req.Params.Arguments = map[string]any{"deviceId": "42", "dry_run": true}
+	handler := NewAudit(logger).Middleware(func(context.Context, mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		return mcp.NewToolResultText("ok"), nil
+	})
+	if _, err := handler(context.Background(), req); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	last, err := audit.Verify(strings.NewReader(string(b)), nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !last.DryRun || last.UpstreamStatus != 0 {
+		t.Fatalf("expected a dry run entry without upstream status, got %+v", last)
+	}
+}
+
+func TestAuditMiddlewareQueuedForApproval(t *testing.T) {
+	var deletes atomic.Int32
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodDelete {
+			deletes.Add(1)
+		}
+		_, _ = w.Write([]byte(`{"id":"42"}`))
+	}))
+	defer upstream.Close()
+
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	logger, err := audit.Open(path, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer logger.Close()
+	store, err := approval.Open(filepath.Join(t.TempDir(), "approvals.json"), time.Hour)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	approvals := NewApprovals(store)
+	s := server.NewMCPServer("test", "0.0.0",
+		server.WithToolCapabilities(true),
+		server.WithToolHandlerMiddleware(NewAudit(logger).Middleware),
+		server.WithToolHandlerMiddleware(approvals.Middleware),
+	)
+	DevicesDelete.Register(s)
+	approvals.Register(s)
+
+	cfg := prey.Config{URL: upstream.URL, APIKey: "key", AllowWrite: true, Timeout: time.Second, DisableRateLimit: true}
+	base := prey.WithClient(prey.WithConfig(context.Background(), cfg), prey.NewClient(cfg))
+	as := func(subject string) context.Context {
+		return auth.WithIdentity(base, auth.Identity{Subject: subject, Method: auth.MethodBearer})
+	}
+	entries := func() []audit.Entry {
+		b, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		var out []audit.Entry
+		for _, line := range strings.Split(strings.TrimSpace(string(b)), "\n") {
+			var e audit.Entry
+			if err := json.Unmarshal([]byte(line), &e); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			out = append(out, e)
+		}
+		return out
+	}
+
+	callTool(as("alice"), s, "1", "prey.devices.delete", map[string]any{"deviceId": "42"})
+	logged := entries()
+	pending := store.Pending()
+	if len(logged) != 1 || len(pending) != 1 || deletes.Load() != 0 {
+		t.Fatalf("expected one queued delete, got entries %+v and pending %+v", logged, pending)
+	}
+	if logged[0].Result != audit.ResultPending || logged[0].ApprovalID != pending[0].ID {
+		t.Fatalf("expected the queued delete to be logged as pending, got %+v", logged[0])
+	}
+
+	callTool(as("bob"), s, "2", "prey.approvals.approve", map[string]any{"id": pending[0].ID})
+	var executed *audit.Entry
+	for _, e := range entries() {
+		if e.Tool == "prey.devices.delete" && e.Result == audit.ResultOK {
+			executed = &e
+		}
+	}
+	if deletes.Load() != 1 || executed == nil || executed.Subject != "bob" || executed.ApprovalID != pending[0].ID {
+		t.Fatalf("expected the approved delete to be logged as run by bob, got %+v", executed)
+	}
+}
+
+func TestAuditMiddlewareFailClosed(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	t.Setenv("PREY_AUDIT_LOG", path)
+	t.Setenv("PREY_AUDIT_FAIL_CLOSED", "true")
+	logger, err := audit.FromEnv(nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	calls := 0
+	handler := NewAudit(logger).Middleware(func(context.Context, mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		calls++
+		return mcp.NewToolResultText("ok"), nil
+	})
+	req := mcp.CallToolRequest{}
+	req.Params.Name = "prey.devices.delete"
+	req.Params.Arguments = map[string]any{"deviceId": "42"}
+
+	if res, err := handler(context.Background(), req); err != nil || res.IsError || calls != 1 {
+		t.Fatalf("expected the call to run, got %+v %v", res, err)
+	}
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if lines := strings.Split(strings.TrimSpace(string(b)), "\n"); len(lines) != 2 || !strings.Contains(lines[0], `"result":"started"`) {
+		t.Fatalf("expected a started and a final entry, got %q", b)
+	}
+
+	// Once the log cannot be written, calls are refused before they run.
+	_ = logger.Close()
+	if res, err := handler(context.Background(), req); err != nil || !res.IsError || calls != 1 {
+		t.Fatalf("expected the call to be refused, got %+v %v (%d calls)", res, err, calls)
+	}
+}
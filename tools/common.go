@@ -4,16 +4,46 @@ import (
 	"context"
 	"fmt"
 
+	"mcp-prey/auth"
 	"mcp-prey/prey"
 )
 
+// ensureToolAllowed checks the allowlists, write permission and scopes for a call.
+// Dry runs send no writes, so they do not need write permission.
 func ensureToolAllowed(ctx context.Context, toolName string, write bool) error {
 	cfg := prey.ConfigFromContext(ctx)
 	if !prey.IsToolAllowed(cfg, toolName) {
 		return fmt.Errorf("tool not allowed: %s", toolName)
 	}
-	if write && !cfg.AllowWrite {
+	gated := write && !prey.IsDryRun(ctx)
+	if gated && !cfg.AllowWrite {
 		return prey.ErrWriteDisabled
 	}
+	if gated && !prey.IsWriteAllowed(cfg, toolName) {
+		return prey.ErrWriteNotGranted
+	}
+	return ensureScope(ctx, toolName, write)
+}
+
+// ensureScope checks the OAuth scopes of the caller. Callers authenticated
+// another way are not scope-restricted.
+func ensureScope(ctx context.Context, toolName string, write bool) error {
+	id, ok := auth.IdentityFromContext(ctx)
+	if !ok || id.Method != auth.MethodOAuth {
+		return nil
+	}
+	scope := auth.ScopeRead
+	if write {
+		scope = auth.ScopeWrite
+	}
+	if !id.HasScope(scope) {
+		return fmt.Errorf("insufficient scope: %s requires %s", toolName, scope)
+	}
 	return nil
 }
+
+// WriteOptions are the arguments shared by every write tool.
+type WriteOptions struct {
+	DryRun         bool   `json:"dry_run,omitempty" jsonschema:"default=false,description=Validate and return the request that would be sent without calling Prey"`
+	IdempotencyKey string `json:"idempotency_key,omitempty" jsonschema:"description=Unique key for this change; repeating a call with the same key returns the first result instead of running it again"`
+}
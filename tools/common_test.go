@@ -0,0 +1,47 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"mcp-prey/auth"
+	"mcp-prey/prey"
+)
+
+func TestEnsureToolAllowedScopes(t *testing.T) {
+	ctx := prey.WithConfig(context.Background(), prey.Config{AllowWrite: true})
+	if err := ensureToolAllowed(ctx, "prey.devices.delete", true); err != nil {
+		t.Fatalf("unexpected error without identity: %v", err)
+	}
+
+	readOnly := auth.WithIdentity(ctx, auth.Identity{Subject: "alice", Method: auth.MethodOAuth, Scopes: []string{auth.ScopeRead}})
+	if err := ensureToolAllowed(readOnly, "prey.devices.list", false); err != nil {
+		t.Fatalf("unexpected error for read tool: %v", err)
+	}
+	if err := ensureToolAllowed(readOnly, "prey.devices.delete", true); err == nil {
+		t.Fatalf("expected insufficient scope for write tool")
+	}
+
+	static := auth.WithIdentity(ctx, auth.Identity{Subject: "ops", Method: auth.MethodBearer})
+	if err := ensureToolAllowed(static, "prey.devices.delete", true); err != nil {
+		t.Fatalf("static tokens should not be scope-restricted: %v", err)
+	}
+}
+
+func TestEnsureToolAllowedWriteGrants(t *testing.T) {
+	ctx := prey.WithConfig(context.Background(), prey.Config{
+		AllowWrite:  true,
+		WriteTools:  map[string]struct{}{"prey.labels.create": {}},
+		DeniedTools: map[string]struct{}{"prey.zones.*": {}},
+	})
+	if err := ensureToolAllowed(ctx, "prey.labels.create", true); err != nil {
+		t.Fatalf("unexpected error for granted tool: %v", err)
+	}
+	if err := ensureToolAllowed(ctx, "prey.devices.delete", true); !errors.Is(err, prey.ErrWriteNotGranted) {
+		t.Fatalf("expected ErrWriteNotGranted, got %v", err)
+	}
+	if err := ensureToolAllowed(ctx, "prey.zones.list", false); err == nil {
+		t.Fatalf("expected denied tool to be refused")
+	}
+}
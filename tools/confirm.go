@@ -0,0 +1,110 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+
+	"mcp-prey/approval"
+	"mcp-prey/internal"
+	"mcp-prey/prey"
+)
+
+var confirmSchema = map[string]any{
+	"type": "object",
+	"properties": map[string]any{
+		"confirm": map[string]any{
+			"type":        "boolean",
+			"title":       "Confirm",
+			"description": "Set to true to run the operation",
+		},
+	},
+	"required": []string{"confirm"},
+}
+
+// confirmWrite asks the user to approve a write call through MCP elicitation when the
+// configured policy requires it. Calls are refused when the client cannot elicit.
+// summary is only evaluated when a confirmation is actually requested.
+func confirmWrite(ctx context.Context, toolName string, destructive bool, summary func() string) error {
+	cfg := prey.ConfigFromContext(ctx)
+	// An approved request was already confirmed by a second caller, and dry runs
+	// send nothing to confirm.
+	if _, approved := ctx.Value(approvedKey{}).(approval.Request); approved || prey.IsDryRun(ctx) {
+		return nil
+	}
+	if !prey.RequiresConfirmation(cfg, toolName, destructive) {
+		return nil
+	}
+	srv := server.ServerFromContext(ctx)
+	if srv == nil || !clientSupportsElicitation(ctx) {
+		return fmt.Errorf("%s requires user confirmation, but the client does not support elicitation", toolName)
+	}
+	result, err := srv.RequestElicitation(ctx, mcp.ElicitationRequest{
+		Params: mcp.ElicitationParams{
+			Message:         fmt.Sprintf("%s\n\nDo you want to run %s?", summary(), toolName),
+			RequestedSchema: confirmSchema,
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("request confirmation: %w", err)
+	}
+	if result.Action != mcp.ElicitationResponseActionAccept {
+		return prey.ErrNotConfirmed
+	}
+	content, ok := result.Content.(map[string]any)
+	if !ok || content["confirm"] != true {
+		return prey.ErrNotConfirmed
+	}
+	return nil
+}
+
+func clientSupportsElicitation(ctx context.Context) bool {
+	session, ok := server.ClientSessionFromContext(ctx).(server.SessionWithClientInfo)
+	if !ok {
+		return false
+	}
+	return session.GetClientCapabilities().Elicitation != nil
+}
+
+// describeDevice returns a short human-readable description of a device for confirmation
+// prompts. It falls back to the ID when the device cannot be fetched.
+func describeDevice(ctx context.Context, client *prey.Client, deviceID string) string {
+	fallback := fmt.Sprintf("device %s", deviceID)
+	req, err := client.NewRequest(http.MethodGet, "/devices/"+deviceID, url.Values{}, nil)
+	if err != nil {
+		return fallback
+	}
+	var payload any
+	if err := client.DoJSON(req.WithContext(ctx), &payload); err != nil {
+		return fallback
+	}
+	var device Device
+	if err := internal.Decode(internal.MaskSensitive(payload), &device); err != nil || device.Name == "" {
+		return fallback
+	}
+	desc := fmt.Sprintf("device %q (%s)", device.Name, deviceID)
+	if owner := deviceOwner(device); owner != "" {
+		desc += fmt.Sprintf(", owner %s", owner)
+	}
+	return desc
+}
+
+func deviceOwner(device Device) string {
+	for _, key := range []string{"owner", "user", "assigned_to"} {
+		switch v := device.Extra[key].(type) {
+		case string:
+			return v
+		case map[string]any:
+			for _, field := range []string{"name", "email"} {
+				if s, ok := v[field].(string); ok && s != "" {
+					return s
+				}
+			}
+		}
+	}
+	return ""
+}
@@ -3,6 +3,7 @@ package tools
 import (
 	"context"
 	"encoding/base64"
+	"fmt"
 	"net/http"
 	"net/url"
 	"strings"
@@ -15,9 +16,50 @@ import (
 	"mcp-prey/prey"
 )
 
+// Device is a Prey device. Fields that are not modeled explicitly are kept in Extra.
+type Device struct {
+	ID      internal.FlexString `json:"id" jsonschema:"description=ID of the device"`
+	Name    string              `json:"name,omitempty" jsonschema:"description=Device name"`
+	Type    string              `json:"type,omitempty" jsonschema:"description=Device type such as laptop or phone"`
+	OS      string              `json:"os,omitempty" jsonschema:"description=Operating system"`
+	Vendor  string              `json:"vendor,omitempty" jsonschema:"description=Hardware vendor"`
+	Model   string              `json:"model,omitempty" jsonschema:"description=Hardware model"`
+	Missing *bool               `json:"missing,omitempty" jsonschema:"description=Whether the device is marked as missing"`
+	Extra   map[string]any      `json:"-"`
+}
+
+func (d *Device) UnmarshalJSON(b []byte) error {
+	type device Device
+	return internal.UnmarshalWithExtra(b, (*device)(d), &d.Extra)
+}
+
+func (d Device) MarshalJSON() ([]byte, error) {
+	type device Device
+	return internal.MarshalWithExtra(device(d), d.Extra)
+}
+
+// Report is a Prey device report. Fields that are not modeled explicitly are kept in Extra.
+type Report struct {
+	ID        internal.FlexString `json:"id" jsonschema:"description=ID of the report"`
+	DeviceID  internal.FlexString `json:"device_id,omitempty" jsonschema:"description=ID of the reporting device"`
+	CreatedAt string              `json:"created_at,omitempty" jsonschema:"description=Report creation time"`
+	Extra     map[string]any      `json:"-"`
+}
+
+func (r *Report) UnmarshalJSON(b []byte) error {
+	type report Report
+	return internal.UnmarshalWithExtra(b, (*report)(r), &r.Extra)
+}
+
+func (r Report) MarshalJSON() ([]byte, error) {
+	type report Report
+	return internal.MarshalWithExtra(report(r), r.Extra)
+}
+
 type DevicesListParams struct {
-	Page     int `json:"page,omitempty" jsonschema:"default=1,description=Page number"`
-	PageSize int `json:"page_size,omitempty" jsonschema:"default=20,minimum=1,maximum=100,description=Number of records per page"`
+	Page     int  `json:"page,omitempty" jsonschema:"default=1,description=Page number"`
+	PageSize int  `json:"page_size,omitempty" jsonschema:"default=20,minimum=1,maximum=100,description=Number of records per page"`
+	AllPages bool `json:"all_pages,omitempty" jsonschema:"default=false,description=Fetch every page (up to 100) instead of a single page; reports progress"`
 }
 
 type DevicesGetParams struct {
@@ -26,12 +68,14 @@ type DevicesGetParams struct {
 
 type DevicesDeleteParams struct {
 	DeviceID string `json:"deviceId" jsonschema:"description=ID of the device"`
+	WriteOptions
 }
 
 type DevicesReportsListParams struct {
 	DeviceID string `json:"deviceId" jsonschema:"description=ID of the device"`
 	Page     int    `json:"page,omitempty" jsonschema:"default=1,description=Page number"`
 	PageSize int    `json:"page_size,omitempty" jsonschema:"default=20,minimum=1,maximum=100,description=Number of records per page"`
+	AllPages bool   `json:"all_pages,omitempty" jsonschema:"default=false,description=Fetch every page (up to 100) instead of a single page; reports progress"`
 }
 
 type DevicesReportsGetParams struct {
@@ -44,7 +88,7 @@ type DevicesLocationHistoryParams struct {
 	Format   string `json:"format,omitempty" jsonschema:"default=json,description=Response format: json or csv"`
 }
 
-func devicesList(ctx context.Context, args DevicesListParams) (any, error) {
+func devicesList(ctx context.Context, args DevicesListParams) (*internal.Envelope[[]Device], error) {
 	if err := ensureToolAllowed(ctx, "prey.devices.list", false); err != nil {
 		return nil, err
 	}
@@ -52,6 +96,17 @@ func devicesList(ctx context.Context, args DevicesListParams) (any, error) {
 	if client == nil {
 		return nil, &mcprey.HardError{Err: prey.ErrMissingClient}
 	}
+	if args.AllPages {
+		items, meta, err := listAllPages(ctx, client, "/devices", args.PageSize)
+		if err != nil {
+			return nil, err
+		}
+		var devices []Device
+		if err := internal.Decode(maskDevices(ctx, items), &devices); err != nil {
+			return nil, err
+		}
+		return internal.NewEnvelope(filterDeviceScope(ctx, devices), meta), nil
+	}
 	q, err := internal.AddPagination(url.Values{}, args.Page, args.PageSize)
 	if err != nil {
 		return nil, err
@@ -64,14 +119,22 @@ func devicesList(ctx context.Context, args DevicesListParams) (any, error) {
 	if err := client.DoJSON(req.WithContext(ctx), &payload); err != nil {
 		return nil, err
 	}
-	meta, err := internal.Meta(args.Page, args.PageSize)
+	meta, err := internal.NewPageMeta(args.Page, args.PageSize)
 	if err != nil {
 		return nil, err
 	}
-	return internal.Wrap(internal.MaskSensitive(payload), meta), nil
+	items, err := internal.ListItems(payload)
+	if err != nil {
+		return nil, err
+	}
+	var devices []Device
+	if err := internal.Decode(maskDevices(ctx, items), &devices); err != nil {
+		return nil, err
+	}
+	return internal.NewEnvelope(filterDeviceScope(ctx, devices), meta), nil
 }
 
-func devicesGet(ctx context.Context, args DevicesGetParams) (any, error) {
+func devicesGet(ctx context.Context, args DevicesGetParams) (*internal.Envelope[Device], error) {
 	if err := ensureToolAllowed(ctx, "prey.devices.get", false); err != nil {
 		return nil, err
 	}
@@ -90,7 +153,11 @@ func devicesGet(ctx context.Context, args DevicesGetParams) (any, error) {
 	if err := client.DoJSON(req.WithContext(ctx), &payload); err != nil {
 		return nil, err
 	}
-	return internal.Wrap(internal.MaskSensitive(payload), nil), nil
+	var device Device
+	if err := internal.Decode(maskDevices(ctx, payload), &device); err != nil {
+		return nil, err
+	}
+	return internal.NewEnvelope(device, nil), nil
 }
 
 func devicesDelete(ctx context.Context, args DevicesDeleteParams) (any, error) {
@@ -104,6 +171,11 @@ func devicesDelete(ctx context.Context, args DevicesDeleteParams) (any, error) {
 	if client == nil {
 		return nil, &mcprey.HardError{Err: prey.ErrMissingClient}
 	}
+	if err := confirmWrite(ctx, "prey.devices.delete", true, func() string {
+		return "Delete " + describeDevice(ctx, client, args.DeviceID) + "."
+	}); err != nil {
+		return nil, err
+	}
 	var payload any
 	req, err := client.NewRequest(http.MethodDelete, "/devices/"+args.DeviceID, url.Values{}, nil)
 	if err != nil {
@@ -112,10 +184,10 @@ func devicesDelete(ctx context.Context, args DevicesDeleteParams) (any, error) {
 	if err := client.DoJSON(req.WithContext(ctx), &payload); err != nil {
 		return nil, err
 	}
-	return internal.Wrap(internal.MaskSensitive(payload), nil), nil
+	return internal.Wrap(maskDevices(ctx, payload), nil), nil
 }
 
-func devicesReportsList(ctx context.Context, args DevicesReportsListParams) (any, error) {
+func devicesReportsList(ctx context.Context, args DevicesReportsListParams) (*internal.Envelope[[]Report], error) {
 	if err := ensureToolAllowed(ctx, "prey.devices.reports.list", false); err != nil {
 		return nil, err
 	}
@@ -126,6 +198,17 @@ func devicesReportsList(ctx context.Context, args DevicesReportsListParams) (any
 	if client == nil {
 		return nil, &mcprey.HardError{Err: prey.ErrMissingClient}
 	}
+	if args.AllPages {
+		items, meta, err := listAllPages(ctx, client, "/devices/"+args.DeviceID+"/reports", args.PageSize)
+		if err != nil {
+			return nil, err
+		}
+		var reports []Report
+		if err := internal.Decode(maskDeviceData(ctx, client, args.DeviceID, items), &reports); err != nil {
+			return nil, err
+		}
+		return internal.NewEnvelope(reports, meta), nil
+	}
 	q, err := internal.AddPagination(url.Values{}, args.Page, args.PageSize)
 	if err != nil {
 		return nil, err
@@ -138,14 +221,22 @@ func devicesReportsList(ctx context.Context, args DevicesReportsListParams) (any
 	if err := client.DoJSON(req.WithContext(ctx), &payload); err != nil {
 		return nil, err
 	}
-	meta, err := internal.Meta(args.Page, args.PageSize)
+	meta, err := internal.NewPageMeta(args.Page, args.PageSize)
 	if err != nil {
 		return nil, err
 	}
-	return internal.Wrap(internal.MaskSensitive(payload), meta), nil
+	items, err := internal.ListItems(payload)
+	if err != nil {
+		return nil, err
+	}
+	var reports []Report
+	if err := internal.Decode(maskDeviceData(ctx, client, args.DeviceID, items), &reports); err != nil {
+		return nil, err
+	}
+	return internal.NewEnvelope(reports, meta), nil
 }
 
-func devicesReportsGet(ctx context.Context, args DevicesReportsGetParams) (any, error) {
+func devicesReportsGet(ctx context.Context, args DevicesReportsGetParams) (*internal.Envelope[Report], error) {
 	if err := ensureToolAllowed(ctx, "prey.devices.reports.get", false); err != nil {
 		return nil, err
 	}
@@ -167,7 +258,11 @@ func devicesReportsGet(ctx context.Context, args DevicesReportsGetParams) (any,
 	if err := client.DoJSON(req.WithContext(ctx), &payload); err != nil {
 		return nil, err
 	}
-	return internal.Wrap(internal.MaskSensitive(payload), nil), nil
+	var report Report
+	if err := internal.Decode(maskDeviceData(ctx, client, args.DeviceID, payload), &report); err != nil {
+		return nil, err
+	}
+	return internal.NewEnvelope(report, nil), nil
 }
 
 func devicesLocationHistory(ctx context.Context, args DevicesLocationHistoryParams) (any, error) {
@@ -191,6 +286,15 @@ func devicesLocationHistory(ctx context.Context, args DevicesLocationHistoryPara
 		if err != nil {
 			return nil, err
 		}
+		mcprey.ReportProgress(ctx, 1, 2, fmt.Sprintf("downloaded CSV export (%d bytes)", len(b)))
+		// Reducing the precision looks the device up again; skip it once cancelled.
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		if b, err = maskDeviceCSV(ctx, client, args.DeviceID, b); err != nil {
+			return nil, err
+		}
+		mcprey.ReportProgress(ctx, 2, 2, "applied location precision")
 		encoded := base64.StdEncoding.EncodeToString(b)
 		return internal.Wrap(map[string]any{
 			"content_type": contentType,
@@ -205,7 +309,7 @@ func devicesLocationHistory(ctx context.Context, args DevicesLocationHistoryPara
 	if err := client.DoJSON(req.WithContext(ctx), &payload); err != nil {
 		return nil, err
 	}
-	return internal.Wrap(internal.MaskSensitive(payload), nil), nil
+	return internal.Wrap(maskDeviceData(ctx, client, args.DeviceID, payload), nil), nil
 }
 
 var DevicesList = mcprey.MustTool(
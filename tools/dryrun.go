@@ -0,0 +1,96 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+
+	"mcp-prey/internal"
+	"mcp-prey/prey"
+)
+
+// DryRunResult describes what a write tool would have sent to Prey.
+type DryRunResult struct {
+	DryRun   bool                 `json:"dry_run"`
+	Tool     string               `json:"tool"`
+	Requests []prey.DryRunRequest `json:"requests"`
+	// Targets describes the devices and zones the call acts on, keyed by ID.
+	Targets map[string]string `json:"targets,omitempty"`
+}
+
+// DryRunMiddleware runs write tools without sending their writes to Prey when
+// PREY_DRY_RUN is set or the call passes dry_run. The handler still validates its
+// arguments; calls that fail validation return their error as usual.
+func DryRunMiddleware(next server.ToolHandlerFunc) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		if !callIsDryRun(ctx, request) || !callIsWrite(ctx, request.Params.Name) {
+			return next(ctx, request)
+		}
+		ctx, rec := prey.WithDryRun(ctx)
+		result, err := next(ctx, request)
+		requests := rec.Requests()
+		if len(requests) == 0 {
+			return result, err
+		}
+		b, err := json.Marshal(DryRunResult{
+			DryRun:   true,
+			Tool:     request.Params.Name,
+			Requests: requests,
+			Targets:  resolveTargets(ctx, request.GetArguments()),
+		})
+		if err != nil {
+			return nil, err
+		}
+		return mcp.NewToolResultText(string(b)), nil
+	}
+}
+
+// callIsDryRun reports whether PREY_DRY_RUN is set or the call passes dry_run.
+func callIsDryRun(ctx context.Context, request mcp.CallToolRequest) bool {
+	dryRun, _ := request.GetArguments()["dry_run"].(bool)
+	return dryRun || prey.ConfigFromContext(ctx).DryRun
+}
+
+// resolveTargets describes the devices and zone named in a call's arguments.
+func resolveTargets(ctx context.Context, args map[string]any) map[string]string {
+	client := prey.ClientFromContext(ctx)
+	if client == nil {
+		return nil
+	}
+	targets := map[string]string{}
+	for _, id := range targetDevices(args) {
+		if _, ok := targets[id]; !ok {
+			targets[id] = describeDevice(ctx, client, id)
+		}
+	}
+	if zoneID, ok := args["zoneId"].(string); ok && zoneID != "" {
+		targets[zoneID] = describeZone(ctx, client, zoneID)
+	}
+	return targets
+}
+
+func describeZone(ctx context.Context, client *prey.Client, zoneID string) string {
+	fallback := fmt.Sprintf("zone %s", zoneID)
+	req, err := client.NewRequest(http.MethodGet, "/zones/"+zoneID, url.Values{}, nil)
+	if err != nil {
+		return fallback
+	}
+	var payload any
+	if err := client.DoJSON(req.WithContext(ctx), &payload); err != nil {
+		return fallback
+	}
+	var zone Zone
+	if err := internal.Decode(internal.MaskSensitive(payload), &zone); err != nil || zone.Name == "" {
+		return fallback
+	}
+	desc := fmt.Sprintf("zone %q (%s)", zone.Name, zoneID)
+	if zone.Lat != nil && zone.Lng != nil && zone.Radius != nil {
+		desc += fmt.Sprintf(", %gm around %g,%g", *zone.Radius, *zone.Lat, *zone.Lng)
+	}
+	return desc
+}
@@ -0,0 +1,109 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+
+	"mcp-prey/prey"
+)
+
+func TestDryRunMiddleware(t *testing.T) {
+	var writes atomic.Int32
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			writes.Add(1)
+		}
+		_, _ = w.Write([]byte(`{"id":"z1","name":"Office","lat":1.5,"lng":2.5,"radius":100}`))
+	}))
+	defer upstream.Close()
+
+	s := server.NewMCPServer("test", "0.0.0",
+		server.WithToolCapabilities(true),
+		server.WithToolHandlerMiddleware(DryRunMiddleware),
+	)
+	ZonesUpdate.Register(s)
+
+	cfg := prey.Config{URL: upstream.URL + "/v1", APIKey: "key", AllowWrite: true, Timeout: time.Second, DisableRateLimit: true}
+	ctx := prey.WithClient(prey.WithConfig(context.Background(), cfg), prey.NewClient(cfg))
+	call := func(args map[string]any) mcp.CallToolResult {
+		resp, ok := callTool(ctx, s, "1", "prey.zones.update", args).(mcp.JSONRPCResponse)
+		if !ok {
+			t.Fatalf("expected a result")
+		}
+		return resp.Result.(mcp.CallToolResult)
+	}
+
+	res := call(map[string]any{"zoneId": "z1", "name": "HQ", "dry_run": true})
+	if res.IsError || writes.Load() != 0 {
+		t.Fatalf("expected dry run without writes, got %+v", res)
+	}
+	var out DryRunResult
+	if err := json.Unmarshal([]byte(resultText(&res)), &out); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(out.Requests) != 1 || out.Requests[0].Method != http.MethodPut || out.Requests[0].Path != "/zones/z1" {
+		t.Fatalf("unexpected requests: %+v", out.Requests)
+	}
+	if body, _ := out.Requests[0].Body.(map[string]any); body == nil {
+		t.Fatalf("expected request body, got %+v", out.Requests[0].Body)
+	}
+	if out.Targets["z1"] == "" {
+		t.Fatalf("expected zone to be resolved, got %+v", out.Targets)
+	}
+
+	if res := call(map[string]any{"name": "HQ", "dry_run": true}); !res.IsError {
+		t.Fatalf("expected validation errors to be returned")
+	}
+}
+
+func TestDryRunWithoutWritePermission(t *testing.T) {
+	s := server.NewMCPServer("test", "0.0.0",
+		server.WithToolCapabilities(true),
+		server.WithToolHandlerMiddleware(DryRunMiddleware),
+	)
+	ZonesCreate.Register(s)
+
+	// Neither writes nor an API key are configured.
+	cfg := prey.Config{URL: "https://prey.invalid/v1", Timeout: time.Second, DisableRateLimit: true}
+	call := func(cfg prey.Config, args map[string]any) mcp.CallToolResult {
+		ctx := prey.WithClient(prey.WithConfig(context.Background(), cfg), prey.NewClient(cfg))
+		resp, ok := callTool(ctx, s, "1", "prey.zones.create", args).(mcp.JSONRPCResponse)
+		if !ok {
+			t.Fatalf("expected a result")
+		}
+		return resp.Result.(mcp.CallToolResult)
+	}
+	args := map[string]any{"name": "HQ", "lat": 1.5, "lng": 2.5, "radius": 100}
+
+	if res := call(cfg, args); !res.IsError {
+		t.Fatalf("expected a real write to be refused")
+	}
+	args["dry_run"] = true
+	res := call(cfg, args)
+	var out DryRunResult
+	if res.IsError || json.Unmarshal([]byte(resultText(&res)), &out) != nil || len(out.Requests) != 1 {
+		t.Fatalf("expected a dry run, got %+v", res)
+	}
+
+	delete(args, "dry_run")
+	cfg.DryRun = true
+	if res := call(cfg, args); res.IsError {
+		t.Fatalf("expected PREY_DRY_RUN to dry-run the call, got %+v", res)
+	}
+	tool := s.GetTool("prey.zones.create").Tool
+	if !ToolVisible(cfg, tool) {
+		t.Fatalf("expected write tools to be listed under PREY_DRY_RUN")
+	}
+	cfg.DryRun = false
+	if ToolVisible(cfg, tool) {
+		t.Fatalf("expected write tools to be hidden without writes")
+	}
+}
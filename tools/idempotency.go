@@ -0,0 +1,182 @@
+package tools
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+
+	"mcp-prey/approval"
+	"mcp-prey/prey"
+)
+
+const (
+	idempotencyWindowEnvVar = "PREY_IDEMPOTENCY_WINDOW"
+
+	// idempotencyKeyTTL is how long results of calls with an explicit key are kept.
+	idempotencyKeyTTL = 24 * time.Hour
+)
+
+// idempotencyArgs are arguments that do not change what a write does.
+var idempotencyArgs = map[string]bool{"idempotency_key": true, "dry_run": true}
+
+// Idempotency suppresses repeated write calls. A call with an idempotency_key returns
+// the first outcome for that key, and reusing the key with other arguments is an error;
+// without one, an identical call by the same caller within the window does. Only
+// successful outcomes are kept, so failed calls can be retried.
+type Idempotency struct {
+	window time.Duration
+	now    func() time.Time
+
+	mu      sync.Mutex
+	entries map[string]*idempotencyEntry
+}
+
+type idempotencyEntry struct {
+	// args is the hash of the arguments of the call that created the entry.
+	args    string
+	done    chan struct{}
+	result  *mcp.CallToolResult
+	expires time.Time
+}
+
+// NewIdempotency returns a layer deduplicating calls without a key within window.
+// A zero window only deduplicates calls that pass an idempotency_key.
+func NewIdempotency(window time.Duration) *Idempotency {
+	return &Idempotency{window: window, now: time.Now, entries: map[string]*idempotencyEntry{}}
+}
+
+// NewIdempotencyFromEnv reads the window from PREY_IDEMPOTENCY_WINDOW (a Go duration).
+// It defaults to 0, so only calls with an idempotency_key are deduplicated: repeating
+// an identical write on purpose, such as sounding an alarm twice, must not be dropped.
+func NewIdempotencyFromEnv() (*Idempotency, error) {
+	var window time.Duration
+	if val := strings.TrimSpace(os.Getenv(idempotencyWindowEnvVar)); val != "" {
+		d, err := time.ParseDuration(val)
+		if err != nil || d < 0 {
+			return nil, fmt.Errorf("invalid %s: %q", idempotencyWindowEnvVar, val)
+		}
+		window = d
+	}
+	return NewIdempotency(window), nil
+}
+
+// Middleware returns the cached outcome for duplicate write calls.
+func (i *Idempotency) Middleware(next server.ToolHandlerFunc) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		if prey.IsDryRun(ctx) || !callIsWrite(ctx, request.Params.Name) {
+			return next(ctx, request)
+		}
+		if _, approved := ctx.Value(approvedKey{}).(approval.Request); approved {
+			return next(ctx, request)
+		}
+		key, args, ttl := i.key(ctx, request)
+		if key == "" {
+			return next(ctx, request)
+		}
+
+		i.mu.Lock()
+		i.pruneLocked()
+		if entry, ok := i.entries[key]; ok {
+			i.mu.Unlock()
+			if entry.args != args {
+				return mcp.NewToolResultError(fmt.Sprintf(
+					"idempotency_key %q was already used for %s with different arguments; use a new key for a different call",
+					request.GetArguments()["idempotency_key"], request.Params.Name,
+				)), nil
+			}
+			select {
+			case <-entry.done:
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			}
+			if entry.result != nil {
+				return replayed(entry.result), nil
+			}
+			// The first call failed and was forgotten; run this one.
+			return next(ctx, request)
+		}
+		entry := &idempotencyEntry{args: args, done: make(chan struct{}), expires: i.now().Add(ttl)}
+		i.entries[key] = entry
+		i.mu.Unlock()
+
+		result, err := next(ctx, request)
+		i.mu.Lock()
+		if err == nil && result != nil && !result.IsError {
+			entry.result = result
+		} else {
+			delete(i.entries, key)
+		}
+		i.mu.Unlock()
+		close(entry.done)
+		return result, err
+	}
+}
+
+// key derives the cache key, a hash of the arguments that matter and how long to keep
+// the outcome. Keys are scoped to the caller and the Prey account so callers never see
+// each other's results.
+func (i *Idempotency) key(ctx context.Context, request mcp.CallToolRequest) (key, args string, ttl time.Duration) {
+	filtered := make(map[string]any, len(request.GetArguments()))
+	for k, v := range request.GetArguments() {
+		if !idempotencyArgs[k] {
+			filtered[k] = v
+		}
+	}
+	b, err := json.Marshal(filtered)
+	if err != nil {
+		return "", "", 0
+	}
+	args = hashHex(b)
+
+	cfg := prey.ConfigFromContext(ctx)
+	scope := []any{callerName(ctx), cfg.URL, cfg.APIKey, request.Params.Name}
+	if callerName(ctx) == "" {
+		if session := server.ClientSessionFromContext(ctx); session != nil {
+			scope = append(scope, session.SessionID())
+		}
+	}
+	ttl = idempotencyKeyTTL
+	if explicit, _ := request.GetArguments()["idempotency_key"].(string); explicit != "" {
+		scope = append(scope, "key", explicit)
+	} else {
+		if i.window == 0 {
+			return "", "", 0
+		}
+		scope = append(scope, "args", args)
+		ttl = i.window
+	}
+	if b, err = json.Marshal(scope); err != nil {
+		return "", "", 0
+	}
+	return hashHex(b), args, ttl
+}
+
+func hashHex(b []byte) string {
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:])
+}
+
+func (i *Idempotency) pruneLocked() {
+	now := i.now()
+	for key, entry := range i.entries {
+		if entry.result != nil && now.After(entry.expires) {
+			delete(i.entries, key)
+		}
+	}
+}
+
+// replayed marks a cached result so clients can tell it was not executed again.
+func replayed(result *mcp.CallToolResult) *mcp.CallToolResult {
+	copied := *result
+	copied.Meta = mcp.NewMetaFromMap(map[string]any{"idempotentReplay": true})
+	return &copied
+}
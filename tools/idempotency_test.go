@@ -0,0 +1,137 @@
+package tools
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+
+	"mcp-prey/auth"
+)
+
+func TestIdempotencyMiddleware(t *testing.T) {
+	idem := NewIdempotency(time.Minute)
+	now := time.Now()
+	idem.now = func() time.Time { return now }
+	calls := 0
+	fail := false
+	handler := idem.Middleware(func(context.Context, mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		calls++
+		if fail {
+			return mcp.NewToolResultError("upstream error"), nil
+		}
+		return mcp.NewToolResultText("created"), nil
+	})
+	call := func(subject string, args map[string]any) *mcp.CallToolResult {
+		ctx := auth.WithIdentity(context.Background(), auth.Identity{Subject: subject, Method: auth.MethodBearer})
+		req := mcp.CallToolRequest{}
+		req.Params.Name = "prey.labels.create"
+		req.Params.Arguments = args
+		res, err := handler(ctx, req)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		return res
+	}
+
+	call("alice", map[string]any{"name": "EMEA"})
+	res := call("alice", map[string]any{"name": "EMEA"})
+	if calls != 1 || res.Meta == nil {
+		t.Fatalf("expected duplicate call to be replayed, got %d calls", calls)
+	}
+	call("bob", map[string]any{"name": "EMEA"})
+	if calls != 2 {
+		t.Fatalf("expected another caller not to share results, got %d calls", calls)
+	}
+
+	now = now.Add(2 * time.Minute)
+	call("alice", map[string]any{"name": "EMEA"})
+	if calls != 3 {
+		t.Fatalf("expected call after the window to run, got %d calls", calls)
+	}
+
+	call("alice", map[string]any{"name": "APAC", "idempotency_key": "k1"})
+	now = now.Add(time.Hour)
+	call("alice", map[string]any{"name": "APAC", "idempotency_key": "k1"})
+	if calls != 4 {
+		t.Fatalf("expected explicit key to outlive the window, got %d calls", calls)
+	}
+
+	fail = true
+	call("alice", map[string]any{"name": "LATAM"})
+	call("alice", map[string]any{"name": "LATAM"})
+	if calls != 6 {
+		t.Fatalf("expected failed calls to be retried, got %d calls", calls)
+	}
+}
+
+func TestIdempotencyKeyReusedWithOtherArguments(t *testing.T) {
+	idem := NewIdempotency(0)
+	calls := 0
+	handler := idem.Middleware(func(context.Context, mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		calls++
+		return mcp.NewToolResultText("triggered"), nil
+	})
+	ctx := auth.WithIdentity(context.Background(), auth.Identity{Subject: "alice", Method: auth.MethodBearer})
+	call := func(args map[string]any) *mcp.CallToolResult {
+		req := mcp.CallToolRequest{}
+		req.Params.Name = "prey.devices.action.trigger"
+		req.Params.Arguments = args
+		res, err := handler(ctx, req)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		return res
+	}
+
+	call(map[string]any{"deviceId": "1", "action_name": "alarm", "idempotency_key": "k1"})
+	if res := call(map[string]any{"deviceId": "1", "action_name": "alarm", "idempotency_key": "k1", "dry_run": false}); res.IsError || res.Meta == nil {
+		t.Fatalf("expected the same call to be replayed, got %+v", res)
+	}
+	for _, args := range []map[string]any{
+		{"deviceId": "2", "action_name": "alarm", "idempotency_key": "k1"},
+		{"deviceId": "1", "action_name": "lock", "idempotency_key": "k1"},
+	} {
+		if res := call(args); !res.IsError || res.Meta != nil {
+			t.Fatalf("expected reusing the key with %v to be refused, got %+v", args, res)
+		}
+	}
+	if calls != 1 {
+		t.Fatalf("expected only the first call to run, got %d calls", calls)
+	}
+}
+
+func TestIdempotencyFromEnv(t *testing.T) {
+	t.Setenv(idempotencyWindowEnvVar, "")
+	idem, err := NewIdempotencyFromEnv()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	calls := 0
+	handler := idem.Middleware(func(context.Context, mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		calls++
+		return mcp.NewToolResultText("triggered"), nil
+	})
+	ctx := auth.WithIdentity(context.Background(), auth.Identity{Subject: "alice", Method: auth.MethodBearer})
+	req := mcp.CallToolRequest{}
+	req.Params.Name = "prey.devices.action.trigger"
+	req.Params.Arguments = map[string]any{"deviceId": "42", "action_name": "alarm"}
+	for range 2 {
+		if _, err := handler(ctx, req); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if calls != 2 {
+		t.Fatalf("expected identical calls without a key to run by default, got %d runs", calls)
+	}
+
+	t.Setenv(idempotencyWindowEnvVar, "2m")
+	if idem, err := NewIdempotencyFromEnv(); err != nil || idem.window != 2*time.Minute {
+		t.Fatalf("expected a 2m window, got %v (%v)", idem, err)
+	}
+	t.Setenv(idempotencyWindowEnvVar, "-1s")
+	if _, err := NewIdempotencyFromEnv(); err == nil {
+		t.Fatalf("expected a negative window to be rejected")
+	}
+}
@@ -2,6 +2,7 @@ package tools
 
 import (
 	"context"
+	"fmt"
 	"net/http"
 	"net/url"
 
@@ -13,6 +14,23 @@ import (
 	"mcp-prey/prey"
 )
 
+// Label is a Prey device label. Fields that are not modeled explicitly are kept in Extra.
+type Label struct {
+	ID    internal.FlexString `json:"id" jsonschema:"description=ID of the label"`
+	Name  string              `json:"name,omitempty" jsonschema:"description=Label name"`
+	Extra map[string]any      `json:"-"`
+}
+
+func (l *Label) UnmarshalJSON(b []byte) error {
+	type label Label
+	return internal.UnmarshalWithExtra(b, (*label)(l), &l.Extra)
+}
+
+func (l Label) MarshalJSON() ([]byte, error) {
+	type label Label
+	return internal.MarshalWithExtra(label(l), l.Extra)
+}
+
 type LabelsListParams struct {
 	Page     int `json:"page,omitempty" jsonschema:"default=1"`
 	PageSize int `json:"page_size,omitempty" jsonschema:"default=20,minimum=1,maximum=100"`
@@ -25,9 +43,10 @@ type LabelsGetParams struct {
 type LabelsCreateParams struct {
 	Name    string   `json:"name" jsonschema:"description=Label name"`
 	Devices []string `json:"devices,omitempty" jsonschema:"description=Device IDs to assign"`
+	WriteOptions
 }
 
-func labelsList(ctx context.Context, args LabelsListParams) (any, error) {
+func labelsList(ctx context.Context, args LabelsListParams) (*internal.Envelope[[]Label], error) {
 	if err := ensureToolAllowed(ctx, "prey.labels.list", false); err != nil {
 		return nil, err
 	}
@@ -47,14 +66,18 @@ func labelsList(ctx context.Context, args LabelsListParams) (any, error) {
 	if err := client.DoJSON(req.WithContext(ctx), &payload); err != nil {
 		return nil, err
 	}
-	meta, err := internal.Meta(args.Page, args.PageSize)
+	meta, err := internal.NewPageMeta(args.Page, args.PageSize)
 	if err != nil {
 		return nil, err
 	}
-	return internal.Wrap(internal.MaskSensitive(payload), meta), nil
+	var labels []Label
+	if err := internal.DecodeList(internal.MaskSensitive(payload), &labels); err != nil {
+		return nil, err
+	}
+	return internal.NewEnvelope(labels, meta), nil
 }
 
-func labelsGet(ctx context.Context, args LabelsGetParams) (any, error) {
+func labelsGet(ctx context.Context, args LabelsGetParams) (*internal.Envelope[Label], error) {
 	if err := ensureToolAllowed(ctx, "prey.labels.get", false); err != nil {
 		return nil, err
 	}
@@ -73,10 +96,14 @@ func labelsGet(ctx context.Context, args LabelsGetParams) (any, error) {
 	if err := client.DoJSON(req.WithContext(ctx), &payload); err != nil {
 		return nil, err
 	}
-	return internal.Wrap(internal.MaskSensitive(payload), nil), nil
+	var label Label
+	if err := internal.Decode(internal.MaskSensitive(payload), &label); err != nil {
+		return nil, err
+	}
+	return internal.NewEnvelope(label, nil), nil
 }
 
-func labelsCreate(ctx context.Context, args LabelsCreateParams) (any, error) {
+func labelsCreate(ctx context.Context, args LabelsCreateParams) (*internal.Envelope[Label], error) {
 	if err := ensureToolAllowed(ctx, "prey.labels.create", true); err != nil {
 		return nil, err
 	}
@@ -87,6 +114,11 @@ func labelsCreate(ctx context.Context, args LabelsCreateParams) (any, error) {
 	if client == nil {
 		return nil, &mcprey.HardError{Err: prey.ErrMissingClient}
 	}
+	if err := confirmWrite(ctx, "prey.labels.create", false, func() string {
+		return fmt.Sprintf("Create label %q with %d device(s).", args.Name, len(args.Devices))
+	}); err != nil {
+		return nil, err
+	}
 	body := map[string]any{"name": args.Name}
 	if len(args.Devices) > 0 {
 		body["devices"] = args.Devices
@@ -99,7 +131,11 @@ func labelsCreate(ctx context.Context, args LabelsCreateParams) (any, error) {
 	if err := client.DoJSON(req.WithContext(ctx), &payload); err != nil {
 		return nil, err
 	}
-	return internal.Wrap(internal.MaskSensitive(payload), nil), nil
+	var label Label
+	if err := internal.Decode(internal.MaskSensitive(payload), &label); err != nil {
+		return nil, err
+	}
+	return internal.NewEnvelope(label, nil), nil
 }
 
 var LabelsList = mcprey.MustTool(
@@ -0,0 +1,90 @@
+package tools
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+
+	"mcp-prey/auth"
+	"mcp-prey/internal"
+	"mcp-prey/prey"
+)
+
+// locationPrecision returns the precision policy that applies to the caller, or
+// false when coordinates may be returned exactly.
+func locationPrecision(ctx context.Context) (internal.LocationPrecision, bool) {
+	p := prey.ConfigFromContext(ctx).LocationPrecision
+	if !p.Enabled() || auth.HasPermission(ctx, auth.PermissionLocationPrecise) {
+		return p, false
+	}
+	return p, true
+}
+
+// maskDevices masks a device or list of devices: MaskSensitive, then the location
+// precision policy for each device that is not marked missing.
+func maskDevices(ctx context.Context, payload any) any {
+	masked := internal.MaskSensitive(payload)
+	p, ok := locationPrecision(ctx)
+	if !ok {
+		return masked
+	}
+	if list, isList := masked.([]any); isList {
+		out := make([]any, len(list))
+		for i, item := range list {
+			out[i] = reduceDevice(p, item)
+		}
+		return out
+	}
+	return reduceDevice(p, masked)
+}
+
+func reduceDevice(p internal.LocationPrecision, device any) any {
+	if m, ok := device.(map[string]any); ok && isMissing(m["missing"]) {
+		return device
+	}
+	return p.Apply(device)
+}
+
+// maskDeviceData masks reports or location history of one device: MaskSensitive,
+// then the location precision policy unless the device is marked missing.
+func maskDeviceData(ctx context.Context, client *prey.Client, deviceID string, payload any) any {
+	masked := internal.MaskSensitive(payload)
+	p, ok := locationPrecision(ctx)
+	if !ok || deviceMissing(ctx, client, deviceID) {
+		return masked
+	}
+	return p.Apply(masked)
+}
+
+// maskDeviceCSV applies the location precision policy to a CSV export of one device.
+func maskDeviceCSV(ctx context.Context, client *prey.Client, deviceID string, b []byte) ([]byte, error) {
+	p, ok := locationPrecision(ctx)
+	if !ok || deviceMissing(ctx, client, deviceID) {
+		return b, nil
+	}
+	return p.ApplyCSV(b)
+}
+
+// deviceMissing reports whether Prey marks the device as missing. Lookup failures
+// count as not missing, so coordinates stay coarse.
+func deviceMissing(ctx context.Context, client *prey.Client, deviceID string) bool {
+	req, err := client.NewRequest(http.MethodGet, "/devices/"+deviceID, url.Values{}, nil)
+	if err != nil {
+		return false
+	}
+	var payload map[string]any
+	if err := client.DoJSON(req.WithContext(ctx), &payload); err != nil {
+		return false
+	}
+	return isMissing(payload["missing"])
+}
+
+func isMissing(v any) bool {
+	switch t := v.(type) {
+	case bool:
+		return t
+	case string:
+		return t == "true"
+	}
+	return false
+}
@@ -0,0 +1,89 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"mcp-prey/auth"
+	"mcp-prey/internal"
+	"mcp-prey/prey"
+)
+
+func TestMaskDeviceLocations(t *testing.T) {
+	missing := false
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if missing {
+			_, _ = w.Write([]byte(`{"id":"1","missing":true}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"id":"1","missing":false}`))
+	}))
+	defer srv.Close()
+
+	cfg := prey.Config{
+		URL: srv.URL, APIKey: "key", Timeout: time.Second, DisableRateLimit: true,
+		LocationPrecision: internal.LocationPrecision{Mode: internal.LocationDecimals, Digits: 1},
+	}
+	client := prey.NewClient(cfg)
+	ctx := prey.WithConfig(context.Background(), cfg)
+	report := map[string]any{"lat": 1.2345, "lng": 5.4321}
+
+	if got := maskDeviceData(ctx, client, "1", report).(map[string]any); got["lat"] != 1.2 {
+		t.Fatalf("expected coarse coordinates, got %v", got)
+	}
+	precise := auth.WithPermissions(ctx, []string{auth.PermissionLocationPrecise})
+	if got := maskDeviceData(precise, client, "1", report).(map[string]any); got["lat"] != 1.2345 {
+		t.Fatalf("expected exact coordinates with location:precise, got %v", got)
+	}
+	missing = true
+	if got := maskDeviceData(ctx, client, "1", report).(map[string]any); got["lat"] != 1.2345 {
+		t.Fatalf("expected exact coordinates for a missing device, got %v", got)
+	}
+
+	devices := maskDevices(ctx, []any{
+		map[string]any{"id": "1", "missing": true, "lat": 1.2345, "lng": 5.4321},
+		map[string]any{"id": "2", "missing": false, "lat": 1.2345, "lng": 5.4321},
+	}).([]any)
+	if devices[0].(map[string]any)["lat"] != 1.2345 || devices[1].(map[string]any)["lat"] != 1.2 {
+		t.Fatalf("unexpected device locations: %v", devices)
+	}
+}
+
+func TestWriteResponsesReduceLocation(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"id":"1","missing":false,"lat":1.2345,"lng":5.4321}`))
+	}))
+	defer srv.Close()
+
+	cfg := prey.Config{
+		URL: srv.URL, APIKey: "key", AllowWrite: true, Timeout: time.Second, DisableRateLimit: true,
+		Confirm:           prey.ConfirmConfig{Default: prey.ConfirmNever},
+		LocationPrecision: internal.LocationPrecision{Mode: internal.LocationDecimals, Digits: 1},
+	}
+	ctx := prey.WithClient(prey.WithConfig(context.Background(), cfg), prey.NewClient(cfg))
+	results := map[string]func() (any, error){
+		"delete": func() (any, error) { return devicesDelete(ctx, DevicesDeleteParams{DeviceID: "1"}) },
+		"action": func() (any, error) {
+			return deviceActionTrigger(ctx, DeviceActionTriggerParams{DeviceID: "1", Command: "start", ActionName: "alarm"})
+		},
+		"status": func() (any, error) { return deviceStatusSet(ctx, DeviceStatusSetParams{DeviceID: "1"}) },
+	}
+	for name, run := range results {
+		res, err := run()
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", name, err)
+		}
+		b, err := json.Marshal(res)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", name, err)
+		}
+		if strings.Contains(string(b), "1.2345") || !strings.Contains(string(b), `"lat":1.2`) {
+			t.Fatalf("%s: expected coarse coordinates, got %s", name, b)
+		}
+	}
+}
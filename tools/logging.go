@@ -0,0 +1,82 @@
+package tools
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+
+	mcprey "mcp-prey"
+	"mcp-prey/prey"
+)
+
+// Logged call outcomes. A tool error is a result with isError set, an error is a
+// failure of the call itself.
+const (
+	outcomeOK        = "ok"
+	outcomeToolError = "tool_error"
+	outcomeError     = "error"
+)
+
+// LoggingMiddleware logs one line per tool call and one per upstream Prey request it
+// makes, all carrying the session, JSON-RPC request ID and correlation ID. A call
+// without a correlation ID from the caller's X-Request-ID header gets a new one,
+// which is also sent to Prey. Arguments are never logged.
+func LoggingMiddleware(next server.ToolHandlerFunc) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		correlationID := prey.CorrelationIDFromContext(ctx)
+		if correlationID == "" {
+			correlationID = prey.NewCorrelationID()
+			ctx = prey.WithCorrelationID(ctx, correlationID)
+		}
+		attrs := []any{
+			slog.String("session_id", sessionID(ctx)),
+			slog.String("correlation_id", correlationID),
+			slog.String("tool", request.Params.Name),
+		}
+		if id, ok := mcprey.RequestIDFromContext(ctx); ok {
+			attrs = append(attrs, slog.Any("request_id", id))
+		}
+		logger := slog.Default().With(attrs...)
+
+		ctx = prey.WithRequestObserver(ctx, func(info prey.RequestInfo) {
+			args := []any{
+				slog.String("method", info.Request.Method),
+				slog.String("route", info.Route),
+				slog.Int("status", info.Status),
+				slog.Int64("duration_ms", info.Elapsed.Milliseconds()),
+				slog.Int64("ratelimit_wait_ms", info.RateLimitWait.Milliseconds()),
+			}
+			switch {
+			case info.Err != nil:
+				logger.WarnContext(ctx, "upstream request", append(args, slog.String("outcome", outcomeError), slog.String("error", info.Err.Error()))...)
+			case info.Status >= 400:
+				logger.WarnContext(ctx, "upstream request", append(args, slog.String("outcome", outcomeError))...)
+			default:
+				logger.InfoContext(ctx, "upstream request", append(args, slog.String("outcome", outcomeOK))...)
+			}
+		})
+
+		start := time.Now()
+		result, err := next(ctx, request)
+		args := []any{slog.Int64("duration_ms", time.Since(start).Milliseconds())}
+		switch {
+		case err != nil:
+			logger.WarnContext(ctx, "tool call", append(args, slog.String("outcome", outcomeError), slog.String("error", err.Error()))...)
+		case result != nil && result.IsError:
+			logger.InfoContext(ctx, "tool call", append(args, slog.String("outcome", outcomeToolError))...)
+		default:
+			logger.InfoContext(ctx, "tool call", append(args, slog.String("outcome", outcomeOK))...)
+		}
+		return result, err
+	}
+}
+
+func sessionID(ctx context.Context) string {
+	if session := server.ClientSessionFromContext(ctx); session != nil {
+		return session.SessionID()
+	}
+	return ""
+}
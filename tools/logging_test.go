@@ -0,0 +1,89 @@
+package tools
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+
+	"mcp-prey/prey"
+)
+
+func TestLoggingMiddleware(t *testing.T) {
+	var upstreamID string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		upstreamID = r.Header.Get("X-Request-ID")
+		_, _ = w.Write([]byte(`{}`))
+	}))
+	defer srv.Close()
+
+	var buf bytes.Buffer
+	prev := slog.Default()
+	slog.SetDefault(slog.New(slog.NewJSONHandler(&buf, nil)))
+	defer slog.SetDefault(prev)
+
+	client := prey.NewClient(prey.Config{URL: srv.URL, APIKey: "key", Timeout: time.Second, DisableRateLimit: true})
+	req := mcp.CallToolRequest{}
+	req.Params.Name = "prey.account.get"
+	req.Params.Arguments = map[string]any{"api_key": "secret"}
+	handler := LoggingMiddleware(func(ctx context.Context, _ mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		r, _ := client.NewRequest(http.MethodGet, "/account", nil, nil)
+		if err := client.DoJSON(r.WithContext(ctx), nil); err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+		return mcp.NewToolResultText("ok"), nil
+	})
+	ctx := prey.WithCorrelationID(context.Background(), "caller-123")
+	if _, err := handler(ctx, req); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if upstreamID != "caller-123" {
+		t.Fatalf("expected correlation ID upstream, got %q", upstreamID)
+	}
+	if strings.Contains(buf.String(), "secret") {
+		t.Fatalf("arguments must not be logged: %s", buf.String())
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expected 2 log lines, got %d: %s", len(lines), buf.String())
+	}
+	var upstream, call map[string]any
+	if err := json.Unmarshal([]byte(lines[0]), &upstream); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := json.Unmarshal([]byte(lines[1]), &call); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if upstream["msg"] != "upstream request" || upstream["route"] != "/account" || upstream["status"] != float64(200) {
+		t.Fatalf("unexpected upstream line: %v", upstream)
+	}
+	if call["msg"] != "tool call" || call["tool"] != "prey.account.get" || call["outcome"] != "ok" || call["correlation_id"] != "caller-123" {
+		t.Fatalf("unexpected tool call line: %v", call)
+	}
+}
+
+func TestLoggingMiddlewareGeneratesCorrelationID(t *testing.T) {
+	prev := slog.Default()
+	slog.SetDefault(slog.New(slog.NewTextHandler(&bytes.Buffer{}, nil)))
+	defer slog.SetDefault(prev)
+
+	var got string
+	handler := LoggingMiddleware(func(ctx context.Context, _ mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		got = prey.CorrelationIDFromContext(ctx)
+		return mcp.NewToolResultText("ok"), nil
+	})
+	if _, err := handler(context.Background(), mcp.CallToolRequest{}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(got) != 32 {
+		t.Fatalf("expected a generated correlation ID, got %q", got)
+	}
+}
@@ -14,8 +14,9 @@ import (
 )
 
 type MassActionsListParams struct {
-	Page     int `json:"page,omitempty" jsonschema:"default=1"`
-	PageSize int `json:"page_size,omitempty" jsonschema:"default=20,minimum=1,maximum=100"`
+	Page     int  `json:"page,omitempty" jsonschema:"default=1"`
+	PageSize int  `json:"page_size,omitempty" jsonschema:"default=20,minimum=1,maximum=100"`
+	AllPages bool `json:"all_pages,omitempty" jsonschema:"default=false,description=Fetch every page (up to 100) instead of a single page; reports progress"`
 }
 
 type MassActionsGetParams struct {
@@ -30,6 +31,13 @@ func massActionsList(ctx context.Context, args MassActionsListParams) (any, erro
 	if client == nil {
 		return nil, &mcprey.HardError{Err: prey.ErrMissingClient}
 	}
+	if args.AllPages {
+		items, meta, err := listAllPages(ctx, client, "/mass_actions", args.PageSize)
+		if err != nil {
+			return nil, err
+		}
+		return internal.Wrap(internal.MaskSensitive(items), meta), nil
+	}
 	q, err := internal.AddPagination(url.Values{}, args.Page, args.PageSize)
 	if err != nil {
 		return nil, err
@@ -0,0 +1,52 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	mcprey "mcp-prey"
+	"mcp-prey/internal"
+	"mcp-prey/prey"
+)
+
+// maxAllPages caps auto-pagination so a single call cannot exhaust the hourly rate limit.
+const maxAllPages = 100
+
+// listAllPages fetches every page of a list endpoint until a short page is returned.
+// It reports progress after each page and stops as soon as ctx is cancelled.
+func listAllPages(ctx context.Context, client *prey.Client, path string, pageSize int) ([]any, *internal.PageMeta, error) {
+	pageSize, err := internal.NormalizePageSize(pageSize)
+	if err != nil {
+		return nil, nil, err
+	}
+	items := []any{}
+	for page := 1; page <= maxAllPages; page++ {
+		if err := ctx.Err(); err != nil {
+			return nil, nil, err
+		}
+		q, err := internal.AddPagination(url.Values{}, page, pageSize)
+		if err != nil {
+			return nil, nil, err
+		}
+		req, err := client.NewRequest(http.MethodGet, path, q, nil)
+		if err != nil {
+			return nil, nil, err
+		}
+		var payload any
+		if err := client.DoJSON(req.WithContext(ctx), &payload); err != nil {
+			return nil, nil, err
+		}
+		records, err := internal.ListItems(payload)
+		if err != nil {
+			return nil, nil, err
+		}
+		items = append(items, records...)
+		mcprey.ReportProgress(ctx, float64(page), 0, fmt.Sprintf("fetched page %d (%d records)", page, len(items)))
+		if len(records) < pageSize {
+			return items, &internal.PageMeta{Page: 1, PageSize: pageSize, Pages: page}, nil
+		}
+	}
+	return items, &internal.PageMeta{Page: 1, PageSize: pageSize, Pages: maxAllPages}, nil
+}
@@ -0,0 +1,73 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"mcp-prey/prey"
+)
+
+func TestListAllPages(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		items := []any{map[string]any{"id": 1}, map[string]any{"id": 2}}
+		if r.URL.Query().Get("page") == "3" {
+			items = items[:1]
+		}
+		_ = json.NewEncoder(w).Encode(items)
+	}))
+	defer srv.Close()
+
+	client := prey.NewClient(prey.Config{URL: srv.URL, APIKey: "key", Timeout: time.Second, DisableRateLimit: true})
+	items, meta, err := listAllPages(context.Background(), client, "/devices", 2)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(items) != 5 {
+		t.Fatalf("expected 5 items, got %d", len(items))
+	}
+	if meta.Pages != 3 || meta.PageSize != 2 {
+		t.Fatalf("unexpected meta: %+v", meta)
+	}
+}
+
+func TestListAllPagesCancelled(t *testing.T) {
+	client := prey.NewClient(prey.Config{URL: "http://127.0.0.1:0", APIKey: "key", Timeout: time.Second, DisableRateLimit: true})
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, _, err := listAllPages(ctx, client, "/devices", 20); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+}
+
+func TestMassActionsListAllPages(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		items := []any{map[string]any{"id": 1}, map[string]any{"id": 2}}
+		if r.URL.Query().Get("page") == "2" {
+			items = items[:1]
+		}
+		_ = json.NewEncoder(w).Encode(items)
+	}))
+	defer srv.Close()
+
+	cfg := prey.Config{URL: srv.URL, APIKey: "key", Timeout: time.Second, DisableRateLimit: true}
+	ctx := prey.WithClient(prey.WithConfig(context.Background(), cfg), prey.NewClient(cfg))
+	res, err := massActionsList(ctx, MassActionsListParams{PageSize: 2, AllPages: true})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	out := res.(map[string]any)
+	if items, _ := out["data"].([]any); len(items) != 3 {
+		t.Fatalf("expected every page, got %+v", out)
+	}
+
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	if _, err := massActionsList(cancelled, MassActionsListParams{AllPages: true}); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+}
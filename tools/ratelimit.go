@@ -0,0 +1,61 @@
+package tools
+
+import (
+	"context"
+
+	"github.com/mark3labs/mcp-go/mcp"
+
+	mcprey "mcp-prey"
+	"mcp-prey/internal"
+	"mcp-prey/prey"
+)
+
+type RateLimitStatusParams struct{}
+
+// RateLimitTierStatus is the remaining budget of one rate limit tier.
+type RateLimitTierStatus struct {
+	Tier              string `json:"tier" jsonschema:"description=Tier as LIMIT/PERIOD:BURST"`
+	Remaining         int    `json:"remaining" jsonschema:"description=Requests any call can make now"`
+	ReservedRemaining int    `json:"reserved_remaining" jsonschema:"description=Additional requests only write calls can make now"`
+}
+
+// RateLimitStatus is returned by prey.ratelimit.status.
+type RateLimitStatus struct {
+	Enabled      bool                  `json:"enabled" jsonschema:"description=False when client-side rate limiting is disabled"`
+	Profile      string                `json:"profile,omitempty" jsonschema:"description=Config file profile the limits come from"`
+	WriteReserve float64               `json:"write_reserve" jsonschema:"description=Share of every tier reserved for write calls"`
+	Tiers        []RateLimitTierStatus `json:"tiers" jsonschema:"description=Remaining budget per tier"`
+}
+
+func rateLimitStatus(ctx context.Context, _ RateLimitStatusParams) (*internal.Envelope[RateLimitStatus], error) {
+	if err := ensureToolAllowed(ctx, "prey.ratelimit.status", false); err != nil {
+		return nil, err
+	}
+	client := prey.ClientFromContext(ctx)
+	if client == nil {
+		return nil, &mcprey.HardError{Err: prey.ErrMissingClient}
+	}
+	cfg := prey.ConfigFromContext(ctx)
+	status := RateLimitStatus{Profile: cfg.Profile, Tiers: []RateLimitTierStatus{}}
+	if client.Limiter != nil {
+		status.Enabled = true
+		status.WriteReserve = cfg.RateLimitWriteReserve
+		for _, t := range client.Limiter.Status() {
+			status.Tiers = append(status.Tiers, RateLimitTierStatus{
+				Tier:              t.Tier.String(),
+				Remaining:         t.Remaining,
+				ReservedRemaining: t.ReservedRemaining,
+			})
+		}
+	}
+	return internal.NewEnvelope(status, nil), nil
+}
+
+var RateLimitStatusTool = mcprey.MustTool(
+	"prey.ratelimit.status",
+	"Show the remaining client-side Prey API budget per rate limit tier, including the share reserved for write calls. Does not call Prey.",
+	rateLimitStatus,
+	mcp.WithTitleAnnotation("Rate limit status"),
+	mcp.WithIdempotentHintAnnotation(true),
+	mcp.WithReadOnlyHintAnnotation(true),
+)
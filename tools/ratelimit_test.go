@@ -0,0 +1,37 @@
+package tools
+
+import (
+	"context"
+	"testing"
+
+	"mcp-prey/internal"
+	"mcp-prey/prey"
+)
+
+func TestRateLimitStatus(t *testing.T) {
+	cfg := prey.Config{URL: "https://api.example.com", APIKey: "ratelimit-status", RateLimitTiers: internal.DefaultPreyTiers, RateLimitWriteReserve: 0.2}
+	client := prey.NewClient(cfg)
+	ctx := prey.WithClient(prey.WithConfig(context.Background(), cfg), client)
+	if err := client.Limiter.Wait(ctx); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	res, err := rateLimitStatus(ctx, RateLimitStatusParams{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	status := res.Data
+	if !status.Enabled || status.WriteReserve != 0.2 || len(status.Tiers) != 3 {
+		t.Fatalf("unexpected status: %+v", status)
+	}
+	if minute := status.Tiers[1]; minute.Tier != "60/1m:60" || minute.Remaining != 47 || minute.ReservedRemaining != 12 {
+		t.Fatalf("unexpected minute tier: %+v", minute)
+	}
+
+	cfg.DisableRateLimit = true
+	ctx = prey.WithClient(prey.WithConfig(context.Background(), cfg), prey.NewClient(cfg))
+	res, err = rateLimitStatus(ctx, RateLimitStatusParams{})
+	if err != nil || res.Data.Enabled || len(res.Data.Tiers) != 0 {
+		t.Fatalf("expected disabled status, got %+v, %v", res, err)
+	}
+}
@@ -0,0 +1,320 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+
+	"mcp-prey/auth"
+	"mcp-prey/internal"
+	"mcp-prey/prey"
+)
+
+// deviceArgumentKeys are the tool arguments that name devices a call acts on.
+var deviceArgumentKeys = []string{"deviceId", "devices", "add_devices", "remove_devices"}
+
+// objectArgumentKeys are the tool arguments that name an existing zone or label a call
+// reads or modifies.
+var objectArgumentKeys = []string{"zoneId", "labelId"}
+
+// scopeFilteredTools filter their results to the caller's device scope, so scoped
+// roles may call them without naming devices.
+var scopeFilteredTools = map[string]bool{"prey.devices.list": true}
+
+// localTools do not read Prey data, so device scopes do not apply to them.
+var localTools = map[string]bool{"prey.toolsets.list": true, "prey.toolsets.enable": true, "prey.ratelimit.status": true}
+
+// RBAC enforces a role policy before every tool handler runs.
+type RBAC struct {
+	policy *auth.Policy
+}
+
+func NewRBAC(policy *auth.Policy) *RBAC {
+	return &RBAC{policy: policy}
+}
+
+// Middleware rejects tool calls the caller's roles do not permit.
+func (r *RBAC) Middleware(next server.ToolHandlerFunc) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		scope, err := r.authorize(ctx, request)
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+		if scope != nil {
+			ctx = context.WithValue(ctx, deviceScopeKey{}, scope)
+		}
+		var perms []string
+		for _, role := range r.policy.RolesFor(r.policy.Subject(ctx)) {
+			perms = append(perms, role.Permissions...)
+		}
+		return next(auth.WithPermissions(ctx, perms), request)
+	}
+}
+
+// Filter is a server.ToolFilterFunc hiding tools none of the caller's roles may call.
+func (r *RBAC) Filter(ctx context.Context, tools []mcp.Tool) []mcp.Tool {
+	roles := r.policy.RolesFor(r.policy.Subject(ctx))
+	visible := make([]mcp.Tool, 0, len(tools))
+	for _, tool := range tools {
+		for _, role := range roles {
+			if role.AllowsTool(tool.Name, isWriteTool(tool)) {
+				visible = append(visible, tool)
+				break
+			}
+		}
+	}
+	return visible
+}
+
+// authorize checks the call against the caller's roles. Scoped roles may only call
+// tools whose devices, zone and label are all in their scope, local tools, and
+// scopeFilteredTools; for the latter the returned roles limit the results. A nil
+// scope leaves results unfiltered.
+func (r *RBAC) authorize(ctx context.Context, request mcp.CallToolRequest) ([]auth.Role, error) {
+	subject := r.policy.Subject(ctx)
+	roles := r.policy.RolesFor(subject)
+	if len(roles) == 0 {
+		return nil, fmt.Errorf("access denied: no role for caller %q", subject)
+	}
+	toolName := request.Params.Name
+	write := callIsWrite(ctx, toolName)
+	args := request.GetArguments()
+	targeted := len(targetDevices(args)) > 0 || len(argumentIDs(args, objectArgumentKeys)) > 0
+	lookups := newScopeLookups()
+	var scope []auth.Role
+	for _, role := range roles {
+		if !role.AllowsTool(toolName, write) {
+			continue
+		}
+		switch {
+		case !role.Scoped() || localTools[toolName]:
+			return nil, nil
+		case targeted:
+			if roleAllowsTargets(ctx, role, args, lookups) {
+				return nil, nil
+			}
+		case scopeFilteredTools[toolName]:
+			scope = append(scope, role)
+		}
+	}
+	if scope != nil {
+		return scope, nil
+	}
+	return nil, fmt.Errorf("access denied: %q may not call %s on these targets", subject, toolName)
+}
+
+type deviceScopeKey struct{}
+
+// filterDeviceScope drops the devices outside the device scope RBAC stored in ctx.
+// Without a scope every device is kept.
+func filterDeviceScope(ctx context.Context, devices []Device) []Device {
+	roles, ok := ctx.Value(deviceScopeKey{}).([]auth.Role)
+	if !ok {
+		return devices
+	}
+	kept := make([]Device, 0, len(devices))
+	for _, device := range devices {
+		labels := deviceLabels(device)
+		for _, role := range roles {
+			if role.AllowsDevice(string(device.ID), labels) {
+				kept = append(kept, device)
+				break
+			}
+		}
+	}
+	return kept
+}
+
+// roleAllowsTargets reports whether every device, zone and label named in args is in
+// the role's scope. A zone is in scope when all of its devices are, so a scoped role
+// cannot change a zone shared with devices outside its scope; a label is in scope
+// when the role's labels name it.
+func roleAllowsTargets(ctx context.Context, role auth.Role, args map[string]any, lookups *scopeLookups) bool {
+	if !role.Scoped() {
+		return true
+	}
+	if !roleAllowsDevices(ctx, role, targetDevices(args), lookups) {
+		return false
+	}
+	if zoneID, _ := args["zoneId"].(string); zoneID != "" {
+		devices, ok := lookups.zoneDevices(ctx, zoneID)
+		if !ok || !roleAllowsDevices(ctx, role, devices, lookups) {
+			return false
+		}
+	}
+	if labelID, _ := args["labelId"].(string); labelID != "" {
+		return roleAllowsLabel(role, lookups.labelNames(ctx, labelID))
+	}
+	return true
+}
+
+func roleAllowsDevices(ctx context.Context, role auth.Role, devices []string, lookups *scopeLookups) bool {
+	for _, id := range devices {
+		// Match on the device ID first, so labels are only fetched when needed.
+		if role.AllowsDevice(id, nil) {
+			continue
+		}
+		if len(role.Labels) == 0 || !role.AllowsDevice(id, lookups.deviceLabels(ctx, id)) {
+			return false
+		}
+	}
+	return true
+}
+
+func roleAllowsLabel(role auth.Role, names []string) bool {
+	for _, want := range role.Labels {
+		for _, have := range names {
+			if strings.EqualFold(want, have) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// targetDevices collects the device IDs named in a call's arguments.
+func targetDevices(args map[string]any) []string {
+	return argumentIDs(args, deviceArgumentKeys)
+}
+
+// argumentIDs collects the string and string-list values of keys in args.
+func argumentIDs(args map[string]any, keys []string) []string {
+	var ids []string
+	for _, key := range keys {
+		switch v := args[key].(type) {
+		case string:
+			if v != "" {
+				ids = append(ids, v)
+			}
+		case []any:
+			for _, item := range v {
+				if s, ok := item.(string); ok && s != "" {
+					ids = append(ids, s)
+				}
+			}
+		}
+	}
+	return ids
+}
+
+// scopeLookups caches the upstream lookups of one call's scope check, so each device,
+// zone and label is fetched at most once however many roles are checked.
+type scopeLookups struct {
+	labels map[string][]string
+	zones  map[string][]string
+	names  map[string][]string
+}
+
+func newScopeLookups() *scopeLookups {
+	return &scopeLookups{labels: map[string][]string{}, zones: map[string][]string{}, names: map[string][]string{}}
+}
+
+func (l *scopeLookups) deviceLabels(ctx context.Context, deviceID string) []string {
+	if labels, ok := l.labels[deviceID]; ok {
+		return labels
+	}
+	labels := fetchDeviceLabels(ctx, deviceID)
+	l.labels[deviceID] = labels
+	return labels
+}
+
+// zoneDevices returns the IDs of the devices in a zone; ok is false when the zone
+// cannot be fetched, which denies scoped access.
+func (l *scopeLookups) zoneDevices(ctx context.Context, zoneID string) ([]string, bool) {
+	devices, ok := l.zones[zoneID]
+	if !ok {
+		var zone Zone
+		if fetchScopeObject(ctx, "/zones/"+zoneID, &zone) {
+			devices = append([]string{}, objectIDs(zone.Extra["devices"])...)
+		}
+		l.zones[zoneID] = devices
+	}
+	return devices, devices != nil
+}
+
+// labelNames returns the ID and name of a label, or just the ID when the label cannot
+// be fetched.
+func (l *scopeLookups) labelNames(ctx context.Context, labelID string) []string {
+	if names, ok := l.names[labelID]; ok {
+		return names
+	}
+	names := []string{labelID}
+	var label Label
+	if fetchScopeObject(ctx, "/labels/"+labelID, &label) && label.Name != "" {
+		names = append(names, label.Name)
+	}
+	l.names[labelID] = names
+	return names
+}
+
+// fetchScopeObject decodes the object at path into v, reporting whether it could.
+func fetchScopeObject(ctx context.Context, path string, v any) bool {
+	client := prey.ClientFromContext(ctx)
+	if client == nil {
+		return false
+	}
+	req, err := client.NewRequest(http.MethodGet, path, url.Values{}, nil)
+	if err != nil {
+		return false
+	}
+	var payload any
+	if err := client.DoJSON(req.WithContext(ctx), &payload); err != nil {
+		return false
+	}
+	return internal.Decode(payload, v) == nil
+}
+
+// objectIDs returns the IDs in a list of IDs or of objects with an "id".
+func objectIDs(raw any) []string {
+	items, _ := raw.([]any)
+	var ids []string
+	for _, item := range items {
+		if m, ok := item.(map[string]any); ok {
+			item = m["id"]
+		}
+		switch v := item.(type) {
+		case string:
+			if v != "" {
+				ids = append(ids, v)
+			}
+		case float64:
+			ids = append(ids, strconv.FormatFloat(v, 'f', -1, 64))
+		}
+	}
+	return ids
+}
+
+// fetchDeviceLabels returns the names and IDs of a device's labels, or nil when the
+// device cannot be fetched, which denies label-scoped access.
+func fetchDeviceLabels(ctx context.Context, deviceID string) []string {
+	var device Device
+	if !fetchScopeObject(ctx, "/devices/"+deviceID, &device) {
+		return nil
+	}
+	return deviceLabels(device)
+}
+
+// deviceLabels returns the names and IDs of the labels in a device record.
+func deviceLabels(device Device) []string {
+	raw, _ := device.Extra["labels"].([]any)
+	var labels []string
+	for _, item := range raw {
+		switch v := item.(type) {
+		case string:
+			labels = append(labels, v)
+		case map[string]any:
+			for _, key := range []string{"name", "id"} {
+				if s := fmt.Sprint(v[key]); v[key] != nil && s != "" {
+					labels = append(labels, s)
+				}
+			}
+		}
+	}
+	return labels
+}
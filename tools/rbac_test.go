@@ -0,0 +1,192 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+
+	"mcp-prey/auth"
+	"mcp-prey/prey"
+)
+
+func TestRBACLabelScope(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		labels := []any{map[string]any{"id": 7, "name": "APAC"}}
+		if r.URL.Path == "/devices/1" {
+			labels = []any{map[string]any{"id": 3, "name": "EMEA"}}
+		}
+		_ = json.NewEncoder(w).Encode(map[string]any{"id": 1, "labels": labels})
+	}))
+	defer srv.Close()
+
+	policy, err := auth.ParsePolicy([]byte("roles:\n  helpdesk:\n    write: true\n    labels: [EMEA]\nidentities:\n  bob: [helpdesk]\n"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	rbac := NewRBAC(policy)
+	client := prey.NewClient(prey.Config{URL: srv.URL, APIKey: "key", Timeout: time.Second, DisableRateLimit: true})
+	ctx := prey.WithClient(context.Background(), client)
+	ctx = auth.WithIdentity(ctx, auth.Identity{Subject: "bob", Method: auth.MethodBearer})
+
+	call := func(ctx context.Context, deviceID string) *mcp.CallToolResult {
+		req := mcp.CallToolRequest{}
+		req.Params.Name = "prey.devices.action.trigger"
+		req.Params.Arguments = map[string]any{"deviceId": deviceID, "action_name": "alarm"}
+		res, err := rbac.Middleware(func(context.Context, mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+			return mcp.NewToolResultText("ok"), nil
+		})(ctx, req)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		return res
+	}
+	if res := call(ctx, "1"); res.IsError {
+		t.Fatalf("expected EMEA device to be allowed")
+	}
+	if res := call(ctx, "2"); !res.IsError {
+		t.Fatalf("expected APAC device to be denied")
+	}
+	anon := auth.WithIdentity(ctx, auth.Identity{Subject: "mallory", Method: auth.MethodBearer})
+	if res := call(anon, "1"); !res.IsError {
+		t.Fatalf("expected caller without role to be denied")
+	}
+}
+
+func TestTargetDevices(t *testing.T) {
+	ids := targetDevices(map[string]any{"deviceId": "1", "add_devices": []any{"2", "3"}, "name": "x"})
+	if len(ids) != 3 || ids[0] != "1" || ids[2] != "3" {
+		t.Fatalf("unexpected targets: %v", ids)
+	}
+}
+
+func TestRBACScopedRolesOnFleetTools(t *testing.T) {
+	policy, err := auth.ParsePolicy([]byte("roles:\n  helpdesk:\n    write: true\n    labels: [EMEA]\n  admin:\n    write: true\nidentities:\n  bob: [helpdesk]\n  alice: [helpdesk, admin]\n"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	rbac := NewRBAC(policy)
+	devices := []Device{
+		{ID: "1", Extra: map[string]any{"labels": []any{map[string]any{"id": 3, "name": "EMEA"}}}},
+		{ID: "2", Extra: map[string]any{"labels": []any{"APAC"}}},
+		{ID: "3"},
+	}
+	call := func(subject, tool string) ([]Device, bool) {
+		ctx := auth.WithIdentity(context.Background(), auth.Identity{Subject: subject, Method: auth.MethodBearer})
+		req := mcp.CallToolRequest{}
+		req.Params.Name = tool
+		var seen []Device
+		res, err := rbac.Middleware(func(ctx context.Context, _ mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+			seen = filterDeviceScope(ctx, devices)
+			return mcp.NewToolResultText("ok"), nil
+		})(ctx, req)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		return seen, !res.IsError
+	}
+
+	if _, ok := call("bob", "prey.zones.list"); ok {
+		t.Fatalf("expected scoped role to be denied a fleet-wide tool")
+	}
+	if _, ok := call("bob", "prey.toolsets.list"); !ok {
+		t.Fatalf("expected scoped role to be allowed a local tool")
+	}
+	seen, ok := call("bob", "prey.devices.list")
+	if !ok || len(seen) != 1 || seen[0].ID != "1" {
+		t.Fatalf("expected scoped list to keep only the EMEA device, got %v (allowed %v)", seen, ok)
+	}
+	seen, ok = call("alice", "prey.devices.list")
+	if !ok || len(seen) != 3 {
+		t.Fatalf("expected unscoped role to see every device, got %v", seen)
+	}
+	if _, ok := call("alice", "prey.zones.list"); !ok {
+		t.Fatalf("expected unscoped role to be allowed a fleet-wide tool")
+	}
+}
+
+func TestRBACScopedZoneAndLabelTargets(t *testing.T) {
+	var mu sync.Mutex
+	fetches := map[string]int{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		fetches[r.URL.Path]++
+		mu.Unlock()
+		var body any
+		switch r.URL.Path {
+		case "/devices/1":
+			body = map[string]any{"id": 1, "labels": []any{map[string]any{"id": 3, "name": "EMEA"}}}
+		case "/devices/2":
+			body = map[string]any{"id": 2, "labels": []any{map[string]any{"id": 7, "name": "APAC"}}}
+		case "/zones/5":
+			body = map[string]any{"id": 5, "devices": []any{map[string]any{"id": 1}, map[string]any{"id": 2}}}
+		case "/zones/6":
+			body = map[string]any{"id": 6, "devices": []any{1}}
+		case "/labels/3":
+			body = map[string]any{"id": 3, "name": "EMEA"}
+		case "/labels/7":
+			body = map[string]any{"id": 7, "name": "APAC"}
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(body)
+	}))
+	defer srv.Close()
+
+	policy, err := auth.ParsePolicy([]byte("roles:\n  helpdesk:\n    write: true\n    labels: [EMEA]\n  owner:\n    write: true\n    devices: [\"1\"]\nidentities:\n  bob: [helpdesk]\n  carol: [owner]\n"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	rbac := NewRBAC(policy)
+	client := prey.NewClient(prey.Config{URL: srv.URL, APIKey: "key", Timeout: time.Second, DisableRateLimit: true})
+	call := func(subject, tool string, args map[string]any) bool {
+		ctx := auth.WithIdentity(prey.WithClient(context.Background(), client), auth.Identity{Subject: subject, Method: auth.MethodBearer})
+		req := mcp.CallToolRequest{}
+		req.Params.Name = tool
+		req.Params.Arguments = args
+		res, err := rbac.Middleware(func(context.Context, mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+			return mcp.NewToolResultText("ok"), nil
+		})(ctx, req)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		return !res.IsError
+	}
+
+	// Zone 5 also holds an APAC device, so the EMEA role may not change it, even by
+	// naming only its own devices.
+	if call("bob", "prey.zones.update", map[string]any{"zoneId": "5", "add_devices": []any{"1"}}) {
+		t.Fatalf("expected a zone shared outside the scope to be denied")
+	}
+	if call("bob", "prey.zones.update", map[string]any{"zoneId": "5", "name": "renamed"}) {
+		t.Fatalf("expected a zone update without device arguments to be checked too")
+	}
+	if !call("bob", "prey.zones.update", map[string]any{"zoneId": "6", "remove_devices": []any{"1"}}) {
+		t.Fatalf("expected a zone within the scope to be allowed")
+	}
+	if !call("bob", "prey.labels.get", map[string]any{"labelId": "3"}) || call("bob", "prey.labels.get", map[string]any{"labelId": "7"}) {
+		t.Fatalf("expected only the role's own label to be in scope")
+	}
+	if call("bob", "prey.zones.update", map[string]any{"zoneId": "404"}) {
+		t.Fatalf("expected a zone that cannot be fetched to be denied")
+	}
+
+	// A device-ID scope needs no label lookups.
+	mu.Lock()
+	clear(fetches)
+	mu.Unlock()
+	if !call("carol", "prey.zones.update", map[string]any{"zoneId": "6", "add_devices": []any{"1"}}) {
+		t.Fatalf("expected the owner's zone to be allowed")
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if fetches["/devices/1"] != 0 || fetches["/zones/6"] != 1 {
+		t.Fatalf("expected only the zone to be fetched, got %v", fetches)
+	}
+}
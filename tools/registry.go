@@ -1,6 +1,10 @@
 package tools
 
-import "github.com/mark3labs/mcp-go/server"
+import (
+	"github.com/mark3labs/mcp-go/server"
+
+	mcprey "mcp-prey"
+)
 
 func AddAllTools(m *server.MCPServer) {
 	AddAccountTools(m)
@@ -12,3 +16,18 @@ func AddAllTools(m *server.MCPServer) {
 	AddMassActionTools(m)
 	AddActionTools(m)
 }
+
+// KnownTools returns every tool the server can register, mapped to whether it writes.
+func KnownTools() map[string]bool {
+	known := make(map[string]bool)
+	add := func(t mcprey.Tool) { known[t.Tool.Name] = isWriteTool(t.Tool) }
+	for _, ts := range Toolsets {
+		for _, t := range ts.Tools {
+			add(t)
+		}
+	}
+	for _, t := range []mcprey.Tool{ApprovalsListTool(nil), ApprovalsApproveTool(nil), ToolsetsListTool(nil), ToolsetsEnableTool(nil)} {
+		add(t)
+	}
+	return known
+}
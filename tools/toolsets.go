@@ -0,0 +1,270 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+
+	mcprey "mcp-prey"
+	"mcp-prey/internal"
+)
+
+// Toolset is a named group of tools that can be enabled together.
+type Toolset struct {
+	Name        string
+	Description string
+	Tools       []mcprey.Tool
+}
+
+var Toolsets = []Toolset{
+	{Name: "account", Description: "Account summary, users and rate limit budget", Tools: []mcprey.Tool{AccountGet, UsersList, UsersGet, RateLimitStatusTool}},
+	{Name: "devices", Description: "List, inspect and delete devices", Tools: []mcprey.Tool{DevicesList, DevicesGet, DevicesDelete}},
+	{Name: "reports", Description: "Device reports", Tools: []mcprey.Tool{DevicesReportsList, DevicesReportsGet}},
+	{Name: "location", Description: "Device location history", Tools: []mcprey.Tool{DevicesLocationHistory}},
+	{Name: "labels", Description: "Device labels", Tools: []mcprey.Tool{LabelsList, LabelsGet, LabelsCreate}},
+	{Name: "zones", Description: "Geofence zones", Tools: []mcprey.Tool{ZonesList, ZonesGet, ZonesCreate, ZonesUpdate}},
+	{Name: "automations", Description: "Automations", Tools: []mcprey.Tool{AutomationsList, AutomationsGet}},
+	{Name: "mass_actions", Description: "Mass actions", Tools: []mcprey.Tool{MassActionsList, MassActionsGet}},
+	{Name: "actions", Description: "Device actions (alarm, alert, lock) and missing status", Tools: []mcprey.Tool{DeviceActionTrigger, DeviceStatusSet}},
+}
+
+const allToolsets = "all"
+
+// ParseToolsets validates a comma-separated toolset list. "all" selects every toolset.
+func ParseToolsets(val string) ([]string, error) {
+	var names []string
+	for _, name := range strings.Split(val, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		if name == allToolsets {
+			names = names[:0]
+			for _, ts := range Toolsets {
+				names = append(names, ts.Name)
+			}
+			return names, nil
+		}
+		if findToolset(name) == nil {
+			return nil, fmt.Errorf("unknown toolset: %s", name)
+		}
+		names = append(names, name)
+	}
+	return names, nil
+}
+
+func findToolset(name string) *Toolset {
+	for i := range Toolsets {
+		if Toolsets[i].Name == name {
+			return &Toolsets[i]
+		}
+	}
+	return nil
+}
+
+// ToolsetManager registers the selected toolsets. In dynamic mode every tool is
+// registered but only enabled toolsets are advertised; the model can enable more
+// per session with prey.toolsets.enable.
+type ToolsetManager struct {
+	mu       sync.Mutex
+	enabled  map[string]bool
+	dynamic  bool
+	sessions map[string]map[string]bool
+	toolsets map[string]string
+}
+
+func NewToolsetManager(enabled []string, dynamic bool) *ToolsetManager {
+	m := &ToolsetManager{
+		enabled:  make(map[string]bool, len(enabled)),
+		dynamic:  dynamic,
+		sessions: make(map[string]map[string]bool),
+		toolsets: make(map[string]string),
+	}
+	for _, name := range enabled {
+		m.enabled[name] = true
+	}
+	for _, ts := range Toolsets {
+		for _, t := range ts.Tools {
+			m.toolsets[t.Tool.Name] = ts.Name
+		}
+	}
+	return m
+}
+
+// Register adds the tools of the selected toolsets, or every tool plus the meta tools in dynamic mode.
+func (m *ToolsetManager) Register(s *server.MCPServer) {
+	for _, ts := range Toolsets {
+		if !m.dynamic && !m.enabled[ts.Name] {
+			continue
+		}
+		for _, t := range ts.Tools {
+			t.Register(s)
+		}
+	}
+	if m.dynamic {
+		list, enable := ToolsetsListTool(m), ToolsetsEnableTool(m)
+		list.Register(s)
+		enable.Register(s)
+	}
+}
+
+// AddHooks forgets per-session toolsets when a session ends.
+func (m *ToolsetManager) AddHooks(hooks *server.Hooks) {
+	hooks.AddOnUnregisterSession(func(_ context.Context, session server.ClientSession) {
+		m.mu.Lock()
+		delete(m.sessions, session.SessionID())
+		m.mu.Unlock()
+	})
+}
+
+func (m *ToolsetManager) isEnabled(ctx context.Context, toolset string) bool {
+	if m.enabled[toolset] {
+		return true
+	}
+	session := server.ClientSessionFromContext(ctx)
+	if session == nil {
+		return false
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.sessions[session.SessionID()][toolset]
+}
+
+// toolEnabled reports whether a tool belongs to an enabled toolset. Tools outside any toolset are always enabled.
+func (m *ToolsetManager) toolEnabled(ctx context.Context, toolName string) bool {
+	toolset, ok := m.toolsets[toolName]
+	return !ok || m.isEnabled(ctx, toolset)
+}
+
+// Filter is a server.ToolFilterFunc hiding tools of toolsets that are not enabled for the session.
+func (m *ToolsetManager) Filter(ctx context.Context, tools []mcp.Tool) []mcp.Tool {
+	if !m.dynamic {
+		return tools
+	}
+	visible := make([]mcp.Tool, 0, len(tools))
+	for _, tool := range tools {
+		if m.toolEnabled(ctx, tool.Name) {
+			visible = append(visible, tool)
+		}
+	}
+	return visible
+}
+
+// Middleware rejects calls to tools whose toolset has not been enabled for the session.
+func (m *ToolsetManager) Middleware(next server.ToolHandlerFunc) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		if m.dynamic && !m.toolEnabled(ctx, request.Params.Name) {
+			return mcp.NewToolResultError(fmt.Sprintf(
+				"tool %s belongs to toolset %q, which is not enabled; call prey.toolsets.enable first",
+				request.Params.Name, m.toolsets[request.Params.Name],
+			)), nil
+		}
+		return next(ctx, request)
+	}
+}
+
+func (m *ToolsetManager) enable(ctx context.Context, toolset string) (bool, error) {
+	if m.isEnabled(ctx, toolset) {
+		return false, nil
+	}
+	session := server.ClientSessionFromContext(ctx)
+	if session == nil {
+		return false, fmt.Errorf("toolsets can only be enabled within a session")
+	}
+	m.mu.Lock()
+	if m.sessions[session.SessionID()] == nil {
+		m.sessions[session.SessionID()] = make(map[string]bool)
+	}
+	m.sessions[session.SessionID()][toolset] = true
+	m.mu.Unlock()
+	if srv := server.ServerFromContext(ctx); srv != nil {
+		_ = srv.SendNotificationToSpecificClient(session.SessionID(), mcp.MethodNotificationToolsListChanged, nil)
+	}
+	return true, nil
+}
+
+type ToolsetsListParams struct{}
+
+type ToolsetsEnableParams struct {
+	Toolset string `json:"toolset" jsonschema:"description=Name of the toolset to enable"`
+}
+
+// ToolsetInfo describes a toolset and whether it is enabled for the session.
+type ToolsetInfo struct {
+	Name        string   `json:"name" jsonschema:"description=Toolset name"`
+	Description string   `json:"description" jsonschema:"description=What the toolset covers"`
+	Enabled     bool     `json:"enabled" jsonschema:"description=Whether the toolset is enabled for this session"`
+	Tools       []string `json:"tools" jsonschema:"description=Tools in the toolset"`
+}
+
+// ToolsetsEnableResult is returned by prey.toolsets.enable.
+type ToolsetsEnableResult struct {
+	Toolset string   `json:"toolset" jsonschema:"description=Toolset name"`
+	Changed bool     `json:"changed" jsonschema:"description=False when the toolset was already enabled"`
+	Tools   []string `json:"tools" jsonschema:"description=Tools now available"`
+}
+
+func toolsetToolNames(ts *Toolset) []string {
+	names := make([]string, 0, len(ts.Tools))
+	for _, t := range ts.Tools {
+		names = append(names, t.Tool.Name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func ToolsetsListTool(m *ToolsetManager) mcprey.Tool {
+	return mcprey.MustTool(
+		"prey.toolsets.list",
+		"List the available toolsets and whether they are enabled for this session.",
+		func(ctx context.Context, _ ToolsetsListParams) (*internal.Envelope[[]ToolsetInfo], error) {
+			infos := make([]ToolsetInfo, 0, len(Toolsets))
+			for i := range Toolsets {
+				ts := &Toolsets[i]
+				infos = append(infos, ToolsetInfo{
+					Name:        ts.Name,
+					Description: ts.Description,
+					Enabled:     m.isEnabled(ctx, ts.Name),
+					Tools:       toolsetToolNames(ts),
+				})
+			}
+			return internal.NewEnvelope(infos, nil), nil
+		},
+		mcp.WithTitleAnnotation("List toolsets"),
+		mcp.WithIdempotentHintAnnotation(true),
+		mcp.WithReadOnlyHintAnnotation(true),
+	)
+}
+
+func ToolsetsEnableTool(m *ToolsetManager) mcprey.Tool {
+	return mcprey.MustTool(
+		"prey.toolsets.enable",
+		"Enable a toolset for this session so its tools become available. Use prey.toolsets.list to discover toolsets.",
+		func(ctx context.Context, args ToolsetsEnableParams) (*internal.Envelope[ToolsetsEnableResult], error) {
+			if err := internal.RequireID(args.Toolset, "toolset"); err != nil {
+				return nil, err
+			}
+			ts := findToolset(args.Toolset)
+			if ts == nil {
+				return nil, fmt.Errorf("unknown toolset: %s", args.Toolset)
+			}
+			changed, err := m.enable(ctx, ts.Name)
+			if err != nil {
+				return nil, err
+			}
+			return internal.NewEnvelope(ToolsetsEnableResult{
+				Toolset: ts.Name,
+				Changed: changed,
+				Tools:   toolsetToolNames(ts),
+			}, nil), nil
+		},
+		mcp.WithTitleAnnotation("Enable toolset"),
+		mcp.WithIdempotentHintAnnotation(true),
+		mcp.WithReadOnlyHintAnnotation(true),
+	)
+}
@@ -0,0 +1,48 @@
+package tools
+
+import (
+	"context"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+func TestParseToolsets(t *testing.T) {
+	names, err := ParseToolsets("all")
+	if err != nil || len(names) != len(Toolsets) {
+		t.Fatalf("expected every toolset, got %v err=%v", names, err)
+	}
+	names, err = ParseToolsets("devices, zones")
+	if err != nil || len(names) != 2 {
+		t.Fatalf("expected two toolsets, got %v err=%v", names, err)
+	}
+	if _, err := ParseToolsets("devices,bogus"); err == nil {
+		t.Fatalf("expected error for unknown toolset")
+	}
+}
+
+func TestToolsetsCoverAllTools(t *testing.T) {
+	s := server.NewMCPServer("test", "0.0.0")
+	AddAllTools(s)
+	m := NewToolsetManager(nil, true)
+	for name := range s.ListTools() {
+		if _, ok := m.toolsets[name]; !ok {
+			t.Fatalf("tool %s is not part of any toolset", name)
+		}
+	}
+}
+
+func TestToolsetManagerFilter(t *testing.T) {
+	m := NewToolsetManager([]string{"devices"}, true)
+	tools := []mcp.Tool{DevicesList.Tool, ZonesList.Tool, ToolsetsEnableTool(m).Tool}
+	names := toolNames(m.Filter(context.Background(), tools))
+	if !names["prey.devices.list"] || names["prey.zones.list"] || !names["prey.toolsets.enable"] {
+		t.Fatalf("unexpected visible tools: %v", names)
+	}
+
+	static := NewToolsetManager([]string{"devices"}, false)
+	if got := static.Filter(context.Background(), tools); len(got) != len(tools) {
+		t.Fatalf("static mode should not filter registered tools")
+	}
+}
@@ -0,0 +1,104 @@
+package tools
+
+import (
+	"context"
+	"sync"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+
+	"mcp-prey/prey"
+)
+
+// isWriteTool reports whether a tool modifies Prey state. Read tools carry a read-only hint.
+func isWriteTool(tool mcp.Tool) bool {
+	return tool.Annotations.ReadOnlyHint == nil || !*tool.Annotations.ReadOnlyHint
+}
+
+// ToolVisible reports whether a tool should be advertised for the given configuration.
+// With PREY_DRY_RUN every call is a dry run, so write tools are shown without write
+// permission.
+func ToolVisible(cfg prey.Config, tool mcp.Tool) bool {
+	if !prey.IsToolAllowed(cfg, tool.Name) {
+		return false
+	}
+	return !isWriteTool(tool) || cfg.DryRun || prey.IsWriteAllowed(cfg, tool.Name)
+}
+
+// ToolVisibility filters tools/list per session and sends notifications/tools/list_changed
+// when a session's configuration changes the set of tools it can use.
+type ToolVisibility struct {
+	// contextFunc, when set, refreshes the configuration in the context before use.
+	contextFunc func(context.Context) context.Context
+
+	mu         sync.Mutex
+	advertised map[string]string
+}
+
+func NewToolVisibility() *ToolVisibility {
+	return &ToolVisibility{advertised: make(map[string]string)}
+}
+
+// SetContextFunc makes the filter resolve the configuration with fn on every request,
+// such as prey.ExtractInfoFromEnv for the stdio transport, whose context is built once.
+func (v *ToolVisibility) SetContextFunc(fn func(context.Context) context.Context) {
+	v.contextFunc = fn
+}
+
+func (v *ToolVisibility) config(ctx context.Context) prey.Config {
+	if v.contextFunc != nil {
+		ctx = v.contextFunc(ctx)
+	}
+	return prey.ConfigFromContext(ctx)
+}
+
+// Filter is a server.ToolFilterFunc hiding tools the session's configuration cannot call.
+func (v *ToolVisibility) Filter(ctx context.Context, tools []mcp.Tool) []mcp.Tool {
+	cfg := v.config(ctx)
+	visible := make([]mcp.Tool, 0, len(tools))
+	for _, tool := range tools {
+		if ToolVisible(cfg, tool) && ensureScope(ctx, tool.Name, isWriteTool(tool)) == nil {
+			visible = append(visible, tool)
+		}
+	}
+	if session := server.ClientSessionFromContext(ctx); session != nil {
+		v.mu.Lock()
+		v.advertised[session.SessionID()] = prey.ToolAccessKey(cfg)
+		v.mu.Unlock()
+	}
+	return visible
+}
+
+// AddHooks registers the hooks that detect configuration changes between requests.
+func (v *ToolVisibility) AddHooks(hooks *server.Hooks) {
+	hooks.AddBeforeAny(func(ctx context.Context, _ any, method mcp.MCPMethod, _ any) {
+		if method == mcp.MethodToolsList || method == mcp.MethodInitialize {
+			return
+		}
+		v.checkChanged(ctx)
+	})
+	hooks.AddOnUnregisterSession(func(_ context.Context, session server.ClientSession) {
+		v.mu.Lock()
+		delete(v.advertised, session.SessionID())
+		v.mu.Unlock()
+	})
+}
+
+func (v *ToolVisibility) checkChanged(ctx context.Context) {
+	session := server.ClientSessionFromContext(ctx)
+	srv := server.ServerFromContext(ctx)
+	if session == nil || srv == nil {
+		return
+	}
+	key := prey.ToolAccessKey(v.config(ctx))
+	v.mu.Lock()
+	previous, ok := v.advertised[session.SessionID()]
+	changed := ok && previous != key
+	if changed {
+		v.advertised[session.SessionID()] = key
+	}
+	v.mu.Unlock()
+	if changed {
+		_ = srv.SendNotificationToSpecificClient(session.SessionID(), mcp.MethodNotificationToolsListChanged, nil)
+	}
+}
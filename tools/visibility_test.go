@@ -0,0 +1,46 @@
+package tools
+
+import (
+	"context"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+
+	"mcp-prey/prey"
+)
+
+func toolNames(tools []mcp.Tool) map[string]bool {
+	names := make(map[string]bool, len(tools))
+	for _, t := range tools {
+		names[t.Name] = true
+	}
+	return names
+}
+
+func TestToolVisibilityFilter(t *testing.T) {
+	all := []mcp.Tool{DevicesList.Tool, DevicesGet.Tool, DevicesDelete.Tool, LabelsCreate.Tool}
+	v := NewToolVisibility()
+
+	ctx := prey.WithConfig(context.Background(), prey.Config{})
+	names := toolNames(v.Filter(ctx, all))
+	if !names["prey.devices.list"] || names["prey.devices.delete"] || names["prey.labels.create"] {
+		t.Fatalf("expected only read tools without write access, got %v", names)
+	}
+
+	ctx = prey.WithConfig(context.Background(), prey.Config{
+		AllowWrite:   true,
+		AllowedTools: map[string]struct{}{"prey.devices.list": {}, "prey.labels.create": {}},
+	})
+	names = toolNames(v.Filter(ctx, all))
+	if len(names) != 2 || !names["prey.devices.list"] || !names["prey.labels.create"] {
+		t.Fatalf("expected allowlisted tools only, got %v", names)
+	}
+
+	// A context func replaces the configuration the context was built with.
+	v.SetContextFunc(func(ctx context.Context) context.Context {
+		return prey.WithConfig(ctx, prey.Config{AllowWrite: true})
+	})
+	if names := toolNames(v.Filter(ctx, all)); len(names) != len(all) {
+		t.Fatalf("expected the refreshed configuration to be used, got %v", names)
+	}
+}
@@ -2,6 +2,7 @@ package tools
 
 import (
 	"context"
+	"fmt"
 	"net/http"
 	"net/url"
 
@@ -13,6 +14,27 @@ import (
 	"mcp-prey/prey"
 )
 
+// Zone is a Prey geofence zone. Fields that are not modeled explicitly are kept in Extra.
+type Zone struct {
+	ID     internal.FlexString `json:"id" jsonschema:"description=ID of the zone"`
+	Name   string              `json:"name,omitempty" jsonschema:"description=Zone name"`
+	Lat    *float64            `json:"lat,omitempty" jsonschema:"description=Latitude"`
+	Lng    *float64            `json:"lng,omitempty" jsonschema:"description=Longitude"`
+	Radius *float64            `json:"radius,omitempty" jsonschema:"description=Radius in meters"`
+	Color  string              `json:"color,omitempty" jsonschema:"description=Hex color"`
+	Extra  map[string]any      `json:"-"`
+}
+
+func (z *Zone) UnmarshalJSON(b []byte) error {
+	type zone Zone
+	return internal.UnmarshalWithExtra(b, (*zone)(z), &z.Extra)
+}
+
+func (z Zone) MarshalJSON() ([]byte, error) {
+	type zone Zone
+	return internal.MarshalWithExtra(zone(z), z.Extra)
+}
+
 type ZoneNotificationParams struct {
 	WhenIn  string `json:"when_in,omitempty" jsonschema:"description=on|off"`
 	WhenOut string `json:"when_out,omitempty" jsonschema:"description=on|off"`
@@ -42,6 +64,7 @@ type ZonesCreateParams struct {
 	Devices       []string                `json:"devices,omitempty" jsonschema:"description=Device IDs to assign"`
 	Actions       []ZoneTriggerParams     `json:"actions,omitempty" jsonschema:"description=Zone triggers"`
 	Notifications *ZoneNotificationParams `json:"notifications,omitempty" jsonschema:"description=Notification settings"`
+	WriteOptions
 }
 
 type ZonesUpdateParams struct {
@@ -56,6 +79,7 @@ type ZonesUpdateParams struct {
 	Actions       []ZoneTriggerParams     `json:"actions,omitempty" jsonschema:"description=Zone triggers"`
 	RemoveActions []string                `json:"remove_actions,omitempty" jsonschema:"description=when_in|when_out"`
 	Notifications *ZoneNotificationParams `json:"notifications,omitempty" jsonschema:"description=Notification settings"`
+	WriteOptions
 }
 
 func validateZoneTrigger(t ZoneTriggerParams) error {
@@ -85,7 +109,7 @@ func validateZoneNotifications(n *ZoneNotificationParams) error {
 	return nil
 }
 
-func zonesList(ctx context.Context, args ZonesListParams) (any, error) {
+func zonesList(ctx context.Context, args ZonesListParams) (*internal.Envelope[[]Zone], error) {
 	if err := ensureToolAllowed(ctx, "prey.zones.list", false); err != nil {
 		return nil, err
 	}
@@ -105,14 +129,18 @@ func zonesList(ctx context.Context, args ZonesListParams) (any, error) {
 	if err := client.DoJSON(req.WithContext(ctx), &payload); err != nil {
 		return nil, err
 	}
-	meta, err := internal.Meta(args.Page, args.PageSize)
+	meta, err := internal.NewPageMeta(args.Page, args.PageSize)
 	if err != nil {
 		return nil, err
 	}
-	return internal.Wrap(internal.MaskSensitive(payload), meta), nil
+	var zones []Zone
+	if err := internal.DecodeList(internal.MaskSensitive(payload), &zones); err != nil {
+		return nil, err
+	}
+	return internal.NewEnvelope(zones, meta), nil
 }
 
-func zonesGet(ctx context.Context, args ZonesGetParams) (any, error) {
+func zonesGet(ctx context.Context, args ZonesGetParams) (*internal.Envelope[Zone], error) {
 	if err := ensureToolAllowed(ctx, "prey.zones.get", false); err != nil {
 		return nil, err
 	}
@@ -131,10 +159,14 @@ func zonesGet(ctx context.Context, args ZonesGetParams) (any, error) {
 	if err := client.DoJSON(req.WithContext(ctx), &payload); err != nil {
 		return nil, err
 	}
-	return internal.Wrap(internal.MaskSensitive(payload), nil), nil
+	var zone Zone
+	if err := internal.Decode(internal.MaskSensitive(payload), &zone); err != nil {
+		return nil, err
+	}
+	return internal.NewEnvelope(zone, nil), nil
 }
 
-func zonesCreate(ctx context.Context, args ZonesCreateParams) (any, error) {
+func zonesCreate(ctx context.Context, args ZonesCreateParams) (*internal.Envelope[Zone], error) {
 	if err := ensureToolAllowed(ctx, "prey.zones.create", true); err != nil {
 		return nil, err
 	}
@@ -153,6 +185,11 @@ func zonesCreate(ctx context.Context, args ZonesCreateParams) (any, error) {
 	if client == nil {
 		return nil, &mcprey.HardError{Err: prey.ErrMissingClient}
 	}
+	if err := confirmWrite(ctx, "prey.zones.create", false, func() string {
+		return fmt.Sprintf("Create zone %q with %d device(s).", args.Name, len(args.Devices))
+	}); err != nil {
+		return nil, err
+	}
 	body := map[string]any{
 		"name": args.Name,
 	}
@@ -185,10 +222,14 @@ func zonesCreate(ctx context.Context, args ZonesCreateParams) (any, error) {
 	if err := client.DoJSON(req.WithContext(ctx), &payload); err != nil {
 		return nil, err
 	}
-	return internal.Wrap(internal.MaskSensitive(payload), nil), nil
+	var zone Zone
+	if err := internal.Decode(internal.MaskSensitive(payload), &zone); err != nil {
+		return nil, err
+	}
+	return internal.NewEnvelope(zone, nil), nil
 }
 
-func zonesUpdate(ctx context.Context, args ZonesUpdateParams) (any, error) {
+func zonesUpdate(ctx context.Context, args ZonesUpdateParams) (*internal.Envelope[Zone], error) {
 	if err := ensureToolAllowed(ctx, "prey.zones.update", true); err != nil {
 		return nil, err
 	}
@@ -212,6 +253,11 @@ func zonesUpdate(ctx context.Context, args ZonesUpdateParams) (any, error) {
 	if client == nil {
 		return nil, &mcprey.HardError{Err: prey.ErrMissingClient}
 	}
+	if err := confirmWrite(ctx, "prey.zones.update", false, func() string {
+		return fmt.Sprintf("Update zone %s.", args.ZoneID)
+	}); err != nil {
+		return nil, err
+	}
 	body := map[string]any{}
 	if args.Name != "" {
 		body["name"] = args.Name
@@ -251,7 +297,11 @@ func zonesUpdate(ctx context.Context, args ZonesUpdateParams) (any, error) {
 	if err := client.DoJSON(req.WithContext(ctx), &payload); err != nil {
 		return nil, err
 	}
-	return internal.Wrap(internal.MaskSensitive(payload), nil), nil
+	var zone Zone
+	if err := internal.Decode(internal.MaskSensitive(payload), &zone); err != nil {
+		return nil, err
+	}
+	return internal.NewEnvelope(zone, nil), nil
 }
 
 var ZonesList = mcprey.MustTool(
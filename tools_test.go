@@ -0,0 +1,82 @@
+package mcprey
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+type testToolParams struct {
+	Name string `json:"name" jsonschema:"required,description=Name to greet"`
+}
+
+type testToolResult struct {
+	Greeting string `json:"greeting" jsonschema:"required,description=The greeting"`
+	Count    int    `json:"count,omitempty"`
+}
+
+func callTool(t *testing.T, handler func(context.Context, mcp.CallToolRequest) (*mcp.CallToolResult, error), args map[string]any) *mcp.CallToolResult {
+	t.Helper()
+	var request mcp.CallToolRequest
+	request.Params.Arguments = args
+	result, err := handler(context.Background(), request)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return result
+}
+
+func TestConvertToolStructuredOutput(t *testing.T) {
+	tool, handler, err := ConvertTool("test.greet", "Greet someone.", func(_ context.Context, p testToolParams) (*testToolResult, error) {
+		return &testToolResult{Greeting: "hello " + p.Name, Count: 1}, nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var schema struct {
+		Type       string                    `json:"type"`
+		Properties map[string]map[string]any `json:"properties"`
+		Required   []string                  `json:"required"`
+	}
+	if err := json.Unmarshal(tool.RawOutputSchema, &schema); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if schema.Type != "object" || schema.Properties["greeting"]["type"] != "string" || schema.Properties["count"]["type"] != "integer" {
+		t.Fatalf("unexpected output schema: %s", tool.RawOutputSchema)
+	}
+	if len(schema.Required) != 1 || schema.Required[0] != "greeting" {
+		t.Fatalf("expected greeting to be required, got %v", schema.Required)
+	}
+
+	result := callTool(t, handler, map[string]any{"name": "ada"})
+	structured, ok := result.StructuredContent.(*testToolResult)
+	if !ok || structured.Greeting != "hello ada" {
+		t.Fatalf("expected structured content, got %#v", result.StructuredContent)
+	}
+	text, ok := result.Content[0].(mcp.TextContent)
+	if !ok || text.Text != `{"greeting":"hello ada","count":1}` {
+		t.Fatalf("expected the JSON text alongside structured content, got %#v", result.Content)
+	}
+}
+
+func TestConvertToolUntypedOutput(t *testing.T) {
+	tool, handler, err := ConvertTool("test.raw", "Return a map.", func(_ context.Context, _ testToolParams) (any, error) {
+		return map[string]any{"ok": true}, nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if tool.RawOutputSchema != nil {
+		t.Fatalf("expected no output schema for an untyped result, got %s", tool.RawOutputSchema)
+	}
+	result := callTool(t, handler, map[string]any{"name": "ada"})
+	if result.StructuredContent != nil {
+		t.Fatalf("expected no structured content, got %#v", result.StructuredContent)
+	}
+	if text, ok := result.Content[0].(mcp.TextContent); !ok || text.Text != `{"ok":true}` {
+		t.Fatalf("unexpected content: %#v", result.Content)
+	}
+}